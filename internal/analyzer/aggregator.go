@@ -1,6 +1,7 @@
 package analyzer
 
 import (
+	"strings"
 	"time"
 
 	"github.com/kgrsutos/cw-railspathmetrics/internal/config"
@@ -9,7 +10,12 @@ import (
 
 // Aggregator handles aggregation of log entries into metrics
 type Aggregator struct {
-	pathExcluder *config.PathExcluder
+	pathExcluder      *config.PathExcluder
+	groupOptions      GroupOptions
+	diagnosticSamples int  // Example log lines kept per diagnostic
+	collapseThreshold int  // Distinct one-off values at a path position above which they are collapsed, 0 disables collapsing
+	collapseMaxCount  int  // Requests up to which a value is one-off, 0 counts every value as one-off
+	queryParams       bool // Count the query parameter names of the requests to each path
 }
 
 // NewAggregator creates a new Aggregator instance with default path exclusions
@@ -38,111 +44,187 @@ func NewAggregatorWithPathExcluder(pathExcluder *config.PathExcluder) *Aggregato
 	}
 }
 
-// MatchRequestPairs matches Started and Completed log entries by their SessionID
+// matchKey returns the key that pairs a Started entry with its Completed entry, and the strategy it represents
+// Entries tagged with a request ID are matched by it. Untagged entries fall back to log order: a Completed entry
+// completes the oldest pending Started entry of the same log group, log stream, process and thread
+func matchKey(entry *models.LogEntry) (string, string) {
+	if entry.SessionID != "" {
+		return "id:" + entry.SessionID, models.MatchByRequestID
+	}
+	return strings.Join([]string{"order", entry.LogGroup, entry.LogStream, entry.ProcessID, entry.ThreadID}, "\x00"), models.MatchByLogOrder
+}
+
+// attachAction copies the controller action of a Processing entry onto its Started entry
+func attachAction(started, processing *models.LogEntry) {
+	started.Controller = processing.Controller
+	started.Action = processing.Action
+	started.Format = processing.Format
+}
+
+// MatchRequestPairs matches Started and Completed log entries the way the stream aggregator does
+// Request entries of structured logs are complete pairs on their own
 func (a *Aggregator) MatchRequestPairs(entries []*models.LogEntry) []*models.RequestPair {
 	pairs := make([]*models.RequestPair, 0)
-	startedLogs := make(map[string]*models.LogEntry)
-
+	stream := NewStreamAggregator(a, NewNormalizer(), 0)
+	stream.handlePair = func(pair *models.RequestPair) {
+		pairs = append(pairs, pair)
+	}
 	for _, entry := range entries {
-		if entry.Type == "Started" {
-			// Store Started logs by SessionID
-			if entry.SessionID != "" {
-				startedLogs[entry.SessionID] = entry
-			}
-		} else if entry.Type == "Completed" && entry.SessionID != "" {
-			// Match with Started log with the same SessionID
-			if started, exists := startedLogs[entry.SessionID]; exists {
-				pairs = append(pairs, &models.RequestPair{
-					Started:   started,
-					Completed: entry,
-				})
-				// Remove matched Started log to avoid duplicate matches
-				delete(startedLogs, entry.SessionID)
-			}
-		}
+		stream.Add(entry, time.Time{})
 	}
-
 	return pairs
 }
 
 // AggregateMetrics aggregates request pairs into path metrics
 func (a *Aggregator) AggregateMetrics(pairs []*models.RequestPair, normalizer *Normalizer) map[string]*models.PathMetrics {
 	pathMetrics := make(map[string]*models.PathMetrics)
-
 	for _, pair := range pairs {
-		// Check if the path should be excluded
-		if a.pathExcluder.ShouldExclude(pair.Started.Path) {
-			continue
-		}
+		a.addPair(pathMetrics, pair, normalizer)
+	}
+	finalizeMetrics(pathMetrics)
+	return pathMetrics
+}
 
-		// Normalize the path
-		normalizedPath := normalizer.NormalizePath(pair.Started.Path)
-
-		// Get or create path metrics
-		metrics, exists := pathMetrics[normalizedPath]
-		if !exists {
-			metrics = &models.PathMetrics{
-				Path:        normalizedPath,
-				Count:       0,
-				AverageTime: 0,
-				MinTime:     0,
-				MaxTime:     0,
-				StatusCodes: make(map[int]int),
-				Methods:     make(map[string]int),
-			}
-			pathMetrics[normalizedPath] = metrics
-		}
+// addPair adds a single request pair to the path metrics
+func (a *Aggregator) addPair(pathMetrics map[string]*models.PathMetrics, pair *models.RequestPair, normalizer *Normalizer) {
+	// Check if the path should be excluded
+	if a.pathExcluder.ShouldExclude(pair.Started.Path) {
+		return
+	}
 
-		// Update metrics
-		metrics.Count++
+	// Normalize the path, or use the controller action when aggregating by action
+	normalizedPath := a.aggregationPath(pair.Started, normalizer)
 
-		// Update timing metrics
-		duration := pair.Completed.Duration
-		if metrics.Count == 1 {
+	// Split the path by method and log stream when requested
+	var method, logStream string
+	if a.groupOptions.Method {
+		method = pair.Started.Method
+	}
+	if a.groupOptions.LogStream {
+		logStream = pair.Started.LogStream
+	}
+	key := a.groupOptions.metricsKey(method, normalizedPath, logStream)
+
+	// Get or create path metrics
+	metrics, exists := pathMetrics[key]
+	if !exists {
+		metrics = &models.PathMetrics{
+			Method:      method,
+			Path:        normalizedPath,
+			LogStream:   logStream,
+			Count:       0,
+			AverageTime: 0,
+			MinTime:     0,
+			MaxTime:     0,
+			StatusCodes: make(map[int]int),
+			Methods:     make(map[string]int),
+		}
+		pathMetrics[key] = metrics
+	}
+
+	// Update metrics
+	metrics.Count++
+
+	// Update timing metrics
+	duration := pair.Completed.Duration
+	if metrics.Count == 1 {
+		metrics.MinTime = duration
+		metrics.MaxTime = duration
+		metrics.AverageTime = float64(duration)
+	} else {
+		if duration < metrics.MinTime {
 			metrics.MinTime = duration
+		}
+		if duration > metrics.MaxTime {
 			metrics.MaxTime = duration
-			metrics.AverageTime = float64(duration)
-		} else {
-			if duration < metrics.MinTime {
-				metrics.MinTime = duration
-			}
-			if duration > metrics.MaxTime {
-				metrics.MaxTime = duration
-			}
-			// Calculate new average: (old_avg * (count-1) + new_value) / count
-			metrics.AverageTime = (metrics.AverageTime*float64(metrics.Count-1) + float64(duration)) / float64(metrics.Count)
 		}
+		// Calculate new average: (old_avg * (count-1) + new_value) / count
+		metrics.AverageTime = (metrics.AverageTime*float64(metrics.Count-1) + float64(duration)) / float64(metrics.Count)
+	}
 
-		// Update status codes
-		metrics.StatusCodes[pair.Completed.StatusCode]++
+	// Count the duration in the histogram for percentile calculation; memory does not grow with the request count
+	addDuration(metrics, duration)
 
-		// Update methods
-		metrics.Methods[pair.Started.Method]++
+	// Update status codes
+	metrics.StatusCodes[pair.Completed.StatusCode]++
 
-		// Update view and DB durations if present
-		if pair.Completed.ViewDuration > 0 {
-			metrics.TotalViewDuration += pair.Completed.ViewDuration
+	// Update methods
+	metrics.Methods[pair.Started.Method]++
+
+	// Attribute the request to its log group when it was read from CloudWatch
+	if pair.Started.LogGroup != "" {
+		if metrics.LogGroups == nil {
+			metrics.LogGroups = make(map[string]int)
 		}
-		if pair.Completed.DBDuration > 0 {
-			metrics.TotalDBDuration += pair.Completed.DBDuration
+		metrics.LogGroups[pair.Started.LogGroup]++
+	}
+
+	// Count the query parameter names for the query parameter report
+	if a.queryParams {
+		addQueryParams(metrics, pair.Started.Path)
+	}
+
+	// Update view and DB durations if present
+	if pair.Completed.ViewDuration > 0 {
+		metrics.TotalViewDuration += pair.Completed.ViewDuration
+	}
+	if pair.Completed.DBDuration > 0 {
+		metrics.TotalDBDuration += pair.Completed.DBDuration
+	}
+}
+
+// addIncomplete adds a Started entry that never completed to the incomplete metrics of its path
+func (a *Aggregator) addIncomplete(incomplete map[string]*models.IncompleteMetrics, entry *models.LogEntry, normalizer *Normalizer) {
+	// Check if the path should be excluded
+	if a.pathExcluder.ShouldExclude(entry.Path) {
+		return
+	}
+
+	normalizedPath := a.aggregationPath(entry, normalizer)
+
+	// Split the path by method and log stream when requested
+	var method, logStream string
+	if a.groupOptions.Method {
+		method = entry.Method
+	}
+	if a.groupOptions.LogStream {
+		logStream = entry.LogStream
+	}
+	key := a.groupOptions.metricsKey(method, normalizedPath, logStream)
+
+	metrics, exists := incomplete[key]
+	if !exists {
+		metrics = &models.IncompleteMetrics{
+			Method:    method,
+			Path:      normalizedPath,
+			LogStream: logStream,
 		}
+		incomplete[key] = metrics
 	}
 
-	return pathMetrics
+	metrics.Count++
+	metrics.Requests = append(metrics.Requests, &models.IncompleteRequest{
+		Method:    entry.Method,
+		StartedAt: entry.Timestamp.UTC(),
+		RequestID: entry.SessionID,
+	})
+}
+
+// finalizeMetrics calculates values that depend on all aggregated requests
+func finalizeMetrics(pathMetrics map[string]*models.PathMetrics) {
+	// Calculate percentiles from the collected durations
+	for _, metrics := range pathMetrics {
+		updatePercentiles(metrics)
+	}
 }
 
 // AnalyzeLogs performs complete analysis of log entries
+// Entries are matched like a stream without eviction, so unmatched entries are reported in the diagnostics
 func (a *Aggregator) AnalyzeLogs(entries []*models.LogEntry, normalizer *Normalizer, startTime, endTime time.Time) *models.AnalysisResult {
-	// Match Started and Completed logs
-	pairs := a.MatchRequestPairs(entries)
-
-	// Aggregate metrics
-	pathMetrics := a.AggregateMetrics(pairs, normalizer)
-
-	return &models.AnalysisResult{
-		StartTime:   startTime,
-		EndTime:     endTime,
-		TotalLogs:   len(entries),
-		PathMetrics: pathMetrics,
+	stream := NewStreamAggregator(a, normalizer, 0)
+	for _, entry := range entries {
+		stream.Add(entry, time.Time{})
 	}
+
+	return stream.Result(startTime, endTime)
 }
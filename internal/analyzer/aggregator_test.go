@@ -52,6 +52,7 @@ func TestAggregator_MatchRequestPairs(t *testing.T) {
 						Duration:   150,
 						SessionID:  "abc123",
 					},
+					MatchedBy: models.MatchByRequestID,
 				},
 			},
 		},
@@ -103,6 +104,7 @@ func TestAggregator_MatchRequestPairs(t *testing.T) {
 						Duration:   150,
 						SessionID:  "abc123",
 					},
+					MatchedBy: models.MatchByRequestID,
 				},
 				{
 					Started: &models.LogEntry{
@@ -119,6 +121,7 @@ func TestAggregator_MatchRequestPairs(t *testing.T) {
 						Duration:   250,
 						SessionID:  "def456",
 					},
+					MatchedBy: models.MatchByRequestID,
 				},
 			},
 		},
@@ -169,25 +172,93 @@ func TestAggregator_MatchRequestPairs(t *testing.T) {
 			expected: []*models.RequestPair{},
 		},
 		{
-			name: "ignore logs without session ID",
+			name: "match logs without session ID by log order per process",
 			entries: []*models.LogEntry{
+				{Type: "Started", Method: "GET", Path: "/users/1", ProcessID: "100"},
+				{Type: "Started", Method: "GET", Path: "/posts", ProcessID: "200"},
+				{Type: "Completed", StatusCode: 200, Duration: 50, ProcessID: "200"},
+				{Type: "Completed", StatusCode: 200, Duration: 150, ProcessID: "100"},
+			},
+			expected: []*models.RequestPair{
 				{
-					Type:      "Started",
-					Method:    "GET",
-					Path:      "/users/123",
-					Timestamp: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
-					SessionID: "",
+					Started:   &models.LogEntry{Type: "Started", Method: "GET", Path: "/posts", ProcessID: "200"},
+					Completed: &models.LogEntry{Type: "Completed", StatusCode: 200, Duration: 50, ProcessID: "200"},
+					MatchedBy: models.MatchByLogOrder,
 				},
 				{
-					Type:       "Completed",
-					StatusCode: 200,
-					StatusText: "OK",
-					Duration:   150,
-					SessionID:  "",
+					Started:   &models.LogEntry{Type: "Started", Method: "GET", Path: "/users/1", ProcessID: "100"},
+					Completed: &models.LogEntry{Type: "Completed", StatusCode: 200, Duration: 150, ProcessID: "100"},
+					MatchedBy: models.MatchByLogOrder,
+				},
+			},
+		},
+		{
+			name: "match interleaved untagged logs of a process first in first out as ambiguous",
+			entries: []*models.LogEntry{
+				{Type: "Started", Method: "GET", Path: "/users/1", ProcessID: "100"},
+				{Type: "Started", Method: "GET", Path: "/posts", ProcessID: "100"},
+				{Type: "Completed", StatusCode: 200, Duration: 150, ProcessID: "100"},
+				{Type: "Completed", StatusCode: 200, Duration: 50, ProcessID: "100"},
+				// A Started entry whose Completed entry was lost stays pending instead of being replaced
+				{Type: "Started", Method: "GET", Path: "/lost", ProcessID: "100"},
+			},
+			expected: []*models.RequestPair{
+				{
+					Started:   &models.LogEntry{Type: "Started", Method: "GET", Path: "/users/1", ProcessID: "100"},
+					Completed: &models.LogEntry{Type: "Completed", StatusCode: 200, Duration: 150, ProcessID: "100"},
+					MatchedBy: models.MatchByLogOrderAmbiguous,
+				},
+				{
+					Started:   &models.LogEntry{Type: "Started", Method: "GET", Path: "/posts", ProcessID: "100"},
+					Completed: &models.LogEntry{Type: "Completed", StatusCode: 200, Duration: 50, ProcessID: "100"},
+					MatchedBy: models.MatchByLogOrder,
+				},
+			},
+		},
+		{
+			name: "match untagged logs of different threads of a process separately",
+			entries: []*models.LogEntry{
+				{Type: "Started", Method: "GET", Path: "/users/1", ProcessID: "100", ThreadID: "puma 001"},
+				{Type: "Started", Method: "GET", Path: "/posts", ProcessID: "100", ThreadID: "puma 002"},
+				{Type: "Completed", StatusCode: 200, Duration: 50, ProcessID: "100", ThreadID: "puma 002"},
+				{Type: "Completed", StatusCode: 200, Duration: 150, ProcessID: "100", ThreadID: "puma 001"},
+			},
+			expected: []*models.RequestPair{
+				{
+					Started:   &models.LogEntry{Type: "Started", Method: "GET", Path: "/posts", ProcessID: "100", ThreadID: "puma 002"},
+					Completed: &models.LogEntry{Type: "Completed", StatusCode: 200, Duration: 50, ProcessID: "100", ThreadID: "puma 002"},
+					MatchedBy: models.MatchByLogOrder,
+				},
+				{
+					Started:   &models.LogEntry{Type: "Started", Method: "GET", Path: "/users/1", ProcessID: "100", ThreadID: "puma 001"},
+					Completed: &models.LogEntry{Type: "Completed", StatusCode: 200, Duration: 150, ProcessID: "100", ThreadID: "puma 001"},
+					MatchedBy: models.MatchByLogOrder,
 				},
 			},
+		},
+		{
+			name: "untagged logs of different log streams are not matched",
+			entries: []*models.LogEntry{
+				{Type: "Started", Method: "GET", Path: "/users/1", LogStream: "task-1"},
+				{Type: "Completed", StatusCode: 200, Duration: 150, LogStream: "task-2"},
+			},
 			expected: []*models.RequestPair{},
 		},
+		{
+			name: "attach controller action from Processing logs",
+			entries: []*models.LogEntry{
+				{Type: "Started", Method: "GET", Path: "/users/1", SessionID: "a"},
+				{Type: "Processing", Controller: "UsersController", Action: "show", Format: "JSON", SessionID: "a"},
+				{Type: "Completed", StatusCode: 200, Duration: 150, SessionID: "a"},
+			},
+			expected: []*models.RequestPair{
+				{
+					Started:   &models.LogEntry{Type: "Started", Method: "GET", Path: "/users/1", Controller: "UsersController", Action: "show", Format: "JSON", SessionID: "a"},
+					Completed: &models.LogEntry{Type: "Completed", StatusCode: 200, Duration: 150, SessionID: "a"},
+					MatchedBy: models.MatchByRequestID,
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -237,6 +308,11 @@ func TestAggregator_AggregateMetrics(t *testing.T) {
 					Methods:           map[string]int{"GET": 1},
 					TotalViewDuration: 100.0,
 					TotalDBDuration:   50.0,
+					P50Time:           150,
+					P90Time:           150,
+					P95Time:           150,
+					P99Time:           150,
+					Durations:         map[int]int{150: 1},
 				},
 			},
 		},
@@ -285,6 +361,11 @@ func TestAggregator_AggregateMetrics(t *testing.T) {
 					Methods:           map[string]int{"GET": 2},
 					TotalViewDuration: 300.0,
 					TotalDBDuration:   100.0,
+					P50Time:           150,
+					P90Time:           250,
+					P95Time:           250,
+					P99Time:           250,
+					Durations:         map[int]int{150: 1, 250: 1},
 				},
 			},
 		},
@@ -327,6 +408,11 @@ func TestAggregator_AggregateMetrics(t *testing.T) {
 					MaxTime:     150,
 					StatusCodes: map[int]int{200: 1},
 					Methods:     map[string]int{"GET": 1},
+					P50Time:     150,
+					P90Time:     150,
+					P95Time:     150,
+					P99Time:     150,
+					Durations:   map[int]int{150: 1},
 				},
 				"/posts": {
 					Path:        "/posts",
@@ -336,6 +422,11 @@ func TestAggregator_AggregateMetrics(t *testing.T) {
 					MaxTime:     250,
 					StatusCodes: map[int]int{201: 1},
 					Methods:     map[string]int{"POST": 1},
+					P50Time:     250,
+					P90Time:     250,
+					P95Time:     250,
+					P99Time:     250,
+					Durations:   map[int]int{250: 1},
 				},
 			},
 		},
@@ -378,6 +469,11 @@ func TestAggregator_AggregateMetrics(t *testing.T) {
 					MaxTime:     150,
 					StatusCodes: map[int]int{200: 1, 404: 1},
 					Methods:     map[string]int{"GET": 1, "POST": 1},
+					P50Time:     100,
+					P90Time:     150,
+					P95Time:     150,
+					P99Time:     150,
+					Durations:   map[int]int{150: 1, 100: 1},
 				},
 			},
 		},
@@ -414,6 +510,11 @@ func TestAggregator_AggregateMetrics(t *testing.T) {
 					Methods:           map[string]int{"GET": 1},
 					TotalViewDuration: 0,
 					TotalDBDuration:   0,
+					P50Time:           0,
+					P90Time:           0,
+					P95Time:           0,
+					P99Time:           0,
+					Durations:         map[int]int{0: 1},
 				},
 			},
 		},
@@ -467,10 +568,49 @@ func TestAggregator_AggregateMetrics(t *testing.T) {
 					MaxTime:     150,
 					StatusCodes: map[int]int{200: 1},
 					Methods:     map[string]int{"GET": 1},
+					P50Time:     150,
+					P90Time:     150,
+					P95Time:     150,
+					P99Time:     150,
+					Durations:   map[int]int{150: 1},
 				},
 				// Note: /rails/active_storage path should be excluded
 			},
 		},
+		{
+			name: "count requests per log group",
+			pairs: []*models.RequestPair{
+				{
+					Started:   &models.LogEntry{Type: "Started", Method: "GET", Path: "/posts", LogGroup: "app-web"},
+					Completed: &models.LogEntry{Type: "Completed", StatusCode: 200, Duration: 100},
+				},
+				{
+					Started:   &models.LogEntry{Type: "Started", Method: "GET", Path: "/posts", LogGroup: "app-api"},
+					Completed: &models.LogEntry{Type: "Completed", StatusCode: 200, Duration: 300},
+				},
+				{
+					Started:   &models.LogEntry{Type: "Started", Method: "GET", Path: "/posts", LogGroup: "app-web"},
+					Completed: &models.LogEntry{Type: "Completed", StatusCode: 200, Duration: 200},
+				},
+			},
+			expected: map[string]*models.PathMetrics{
+				"/posts": {
+					Path:        "/posts",
+					Count:       3,
+					AverageTime: 200.0,
+					MinTime:     100,
+					MaxTime:     300,
+					StatusCodes: map[int]int{200: 3},
+					Methods:     map[string]int{"GET": 3},
+					P50Time:     200,
+					P90Time:     300,
+					P95Time:     300,
+					P99Time:     300,
+					LogGroups:   map[string]int{"app-web": 2, "app-api": 1},
+					Durations:   map[int]int{100: 1, durationBucket(300): 1, 200: 1},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -530,9 +670,12 @@ func TestAggregator_AnalyzeLogs(t *testing.T) {
 				},
 			},
 			expected: &models.AnalysisResult{
-				StartTime: startTime,
-				EndTime:   endTime,
-				TotalLogs: 4,
+				StartTime:   startTime,
+				EndTime:     endTime,
+				TotalLogs:   4,
+				MatchedBy:   map[string]int{models.MatchByRequestID: 2},
+				Diagnostics: newEmptyDiagnostics(),
+				Incomplete:  map[string]*models.IncompleteMetrics{},
 				PathMetrics: map[string]*models.PathMetrics{
 					"/users/:id": {
 						Path:        "/users/:id",
@@ -542,6 +685,11 @@ func TestAggregator_AnalyzeLogs(t *testing.T) {
 						MaxTime:     150,
 						StatusCodes: map[int]int{200: 1},
 						Methods:     map[string]int{"GET": 1},
+						P50Time:     150,
+						P90Time:     150,
+						P95Time:     150,
+						P99Time:     150,
+						Durations:   map[int]int{150: 1},
 					},
 					"/posts": {
 						Path:        "/posts",
@@ -551,6 +699,11 @@ func TestAggregator_AnalyzeLogs(t *testing.T) {
 						MaxTime:     250,
 						StatusCodes: map[int]int{201: 1},
 						Methods:     map[string]int{"POST": 1},
+						P50Time:     250,
+						P90Time:     250,
+						P95Time:     250,
+						P99Time:     250,
+						Durations:   map[int]int{250: 1},
 					},
 				},
 			},
@@ -562,6 +715,9 @@ func TestAggregator_AnalyzeLogs(t *testing.T) {
 				StartTime:   startTime,
 				EndTime:     endTime,
 				TotalLogs:   0,
+				MatchedBy:   map[string]int{},
+				Diagnostics: newEmptyDiagnostics(),
+				Incomplete:  map[string]*models.IncompleteMetrics{},
 				PathMetrics: map[string]*models.PathMetrics{},
 			},
 		},
@@ -1,28 +1,36 @@
 package analyzer
 
 import (
-	"encoding/json"
+	"fmt"
 	"io"
-	"sort"
+	"log/slog"
+	"slices"
 	"time"
 
 	"github.com/kgrsutos/cw-railspathmetrics/internal/config"
 	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/output"
 )
 
 // Analyzer coordinates the analysis of Rails log entries
 type Analyzer struct {
-	parser     *Parser
-	normalizer *Normalizer
-	aggregator *Aggregator
+	parser         LineParser
+	customFormats  map[string]LineParser
+	normalizer     *Normalizer
+	aggregator     *Aggregator
+	selectOptions  SelectOptions
+	pendingTimeout time.Duration
+	filterWindow   bool // Drop requests whose Started timestamp is outside the analyzed time range
 }
 
 // NewAnalyzer creates a new Analyzer instance with default configuration
 func NewAnalyzer() *Analyzer {
 	return &Analyzer{
-		parser:     NewParser(),
-		normalizer: NewNormalizer(),
-		aggregator: NewAggregator(),
+		parser:         NewParser(),
+		normalizer:     NewNormalizer(),
+		aggregator:     NewAggregator(),
+		selectOptions:  DefaultSelectOptions(),
+		pendingTimeout: DefaultPendingTimeout,
 	}
 }
 
@@ -47,51 +55,293 @@ func NewAnalyzerWithConfig(configPath string) (*Analyzer, error) {
 	}
 
 	return &Analyzer{
-		parser:     NewParser(),
-		normalizer: NewNormalizer(),
-		aggregator: aggregator,
+		parser:         NewParser(),
+		normalizer:     NewNormalizer(),
+		aggregator:     aggregator,
+		selectOptions:  DefaultSelectOptions(),
+		pendingTimeout: DefaultPendingTimeout,
 	}, nil
 }
 
+// SetSelectOptions sets the sorting, top-N and threshold options applied before output
+func (a *Analyzer) SetSelectOptions(opts SelectOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	a.selectOptions = opts
+	return nil
+}
+
+// SetGroupOptions sets the dimensions besides the path that the metrics are grouped by
+func (a *Analyzer) SetGroupOptions(opts GroupOptions) {
+	a.aggregator.groupOptions = opts
+}
+
+// AddLogFormats registers custom log formats from the config file so SetLogFormat can select them by name
+func (a *Analyzer) AddLogFormats(formats []config.LogFormat) error {
+	for i, format := range formats {
+		if format.Name == "" {
+			return fmt.Errorf("log format at index %d must specify a name", i)
+		}
+		if slices.Contains(LogFormats(), format.Name) {
+			return fmt.Errorf("log format %q conflicts with a built-in log format", format.Name)
+		}
+		if _, exists := a.customFormats[format.Name]; exists {
+			return fmt.Errorf("log format %q is defined more than once", format.Name)
+		}
+
+		parser, err := NewRegexParser(format)
+		if err != nil {
+			return err
+		}
+		if a.customFormats == nil {
+			a.customFormats = make(map[string]LineParser)
+		}
+		a.customFormats[format.Name] = parser
+	}
+	return nil
+}
+
+// SetLogFormat sets the format of the log lines, see LogFormats and AddLogFormats
+func (a *Analyzer) SetLogFormat(format string) error {
+	if parser, exists := a.customFormats[format]; exists {
+		a.parser = parser
+		return nil
+	}
+
+	parser, err := NewLineParser(format)
+	if err != nil {
+		return err
+	}
+	a.parser = parser
+	return nil
+}
+
+// SetNormalization sets the user normalization rules of the config file
+func (a *Analyzer) SetNormalization(cfg config.NormalizationConfig) error {
+	return a.normalizer.SetRules(cfg)
+}
+
+// LoadRoutes normalizes paths with the routes of a `rails routes` dump instead of the heuristics
+// Paths matching no route are still normalized by the heuristics
+func (a *Analyzer) LoadRoutes(path string) error {
+	routes, err := LoadRouteTable(path)
+	if err != nil {
+		return err
+	}
+	a.normalizer.SetRoutes(routes)
+	slog.Info("Loaded routes", "path", path, "count", routes.Len())
+	return nil
+}
+
+// SetPendingTimeout sets how long unmatched Started entries are kept during streaming analysis
+// Zero disables eviction
+func (a *Analyzer) SetPendingTimeout(timeout time.Duration) error {
+	if timeout < 0 {
+		return fmt.Errorf("pending timeout must not be negative: %s", timeout)
+	}
+	a.pendingTimeout = timeout
+	return nil
+}
+
+// SetWindowFilter sets whether requests whose Started timestamp is outside the analyzed time range are dropped
+// This is needed for sources that cannot filter by time themselves, such as log files
+func (a *Analyzer) SetWindowFilter(enabled bool) {
+	a.filterWindow = enabled
+}
+
+// newStream creates a StreamAggregator for the time range, filtering by it when the window filter is enabled
+func (a *Analyzer) newStream(startTime, endTime time.Time) *StreamAggregator {
+	stream := NewStreamAggregator(a.aggregator, a.normalizer, a.pendingTimeout)
+	if a.filterWindow {
+		stream.SetWindow(startTime, endTime)
+	}
+	return stream
+}
+
+// AnalyzeStream analyzes log events as they arrive on the channel until it is closed
+// Events are parsed, matched and aggregated one by one so only in-flight requests are kept in memory
+func (a *Analyzer) AnalyzeStream(logEvents <-chan *models.LogEvent, startTime, endTime time.Time) *models.AnalysisResult {
+	stream := a.newStream(startTime, endTime)
+
+	for logEvent := range logEvents {
+		logEntry, err := a.parser.ParseLogEntry(logEvent.Message)
+		if err != nil {
+			// Skip invalid log entries, counting them in the diagnostics
+			stream.ParseFailure(err, logEvent.Message)
+			continue
+		}
+		logEntry.LogGroup = logEvent.LogGroup
+		logEntry.LogStream = logEvent.LogStream
+		stream.Add(logEntry, logEvent.Timestamp)
+	}
+
+	return a.finishStream(stream, startTime, endTime)
+}
+
+// AnalyzeEntryStream analyzes already parsed log entries as they arrive on the channel until it is closed
+// This is used by sources that extract the Rails fields themselves, such as Logs Insights
+func (a *Analyzer) AnalyzeEntryStream(logEntries <-chan *models.LogEntry, startTime, endTime time.Time) *models.AnalysisResult {
+	stream := a.newStream(startTime, endTime)
+
+	for logEntry := range logEntries {
+		stream.Add(logEntry, logEntry.Timestamp)
+	}
+
+	return a.finishStream(stream, startTime, endTime)
+}
+
+// finishStream reports unmatched entries and returns the result of the stream
+func (a *Analyzer) finishStream(stream *StreamAggregator, startTime, endTime time.Time) *models.AnalysisResult {
+	// Result reports the pending entries as incomplete, so they are counted first
+	pending := stream.Pending()
+	result := stream.Result(startTime, endTime)
+	slog.Info("Matched requests",
+		"byRequestID", result.MatchedBy[models.MatchByRequestID],
+		"byLogOrder", result.MatchedBy[models.MatchByLogOrder],
+		"byLogOrderAmbiguous", result.MatchedBy[models.MatchByLogOrderAmbiguous],
+		"byRecord", result.MatchedBy[models.MatchByRecord],
+	)
+	if stream.OutsideWindow() > 0 {
+		slog.Info("Skipped requests outside the time range", "count", stream.OutsideWindow())
+	}
+	if stream.Evicted() > 0 || pending > 0 {
+		slog.Info("Unmatched Started entries",
+			"evicted", stream.Evicted(),
+			"pending", pending,
+		)
+	}
+	if diagnostics := result.Diagnostics; diagnostics.OrphanedCompleted.Count > 0 || diagnostics.DuplicateRequestIDs.Count > 0 || len(diagnostics.ParseFailures) > 0 {
+		slog.Info("Unmatched or unparseable log lines",
+			"parseFailures", countParseFailures(diagnostics),
+			"orphanedCompleted", diagnostics.OrphanedCompleted.Count,
+			"duplicateRequestIDs", diagnostics.DuplicateRequestIDs.Count,
+		)
+	}
+	for _, collapsed := range result.Collapsed {
+		slog.Info("Collapsed high-cardinality path segment",
+			"path", collapsed.Path,
+			"distinct", collapsed.Distinct,
+			"count", collapsed.Count,
+		)
+	}
+
+	return result
+}
+
 // AnalyzeLogEvents analyzes CloudWatch log events and returns aggregated metrics
+// Unlike AnalyzeStream, unmatched Started entries are never evicted
 func (a *Analyzer) AnalyzeLogEvents(logEvents []*models.LogEvent, startTime, endTime time.Time) *models.AnalysisResult {
-	var logEntries []*models.LogEntry
+	stream := NewStreamAggregator(a.aggregator, a.normalizer, 0)
 
 	// Parse log events into log entries
 	for _, logEvent := range logEvents {
 		logEntry, err := a.parser.ParseLogEntry(logEvent.Message)
 		if err != nil {
-			// Skip invalid log entries
+			// Skip invalid log entries, counting them in the diagnostics
+			stream.ParseFailure(err, logEvent.Message)
 			continue
 		}
-		logEntries = append(logEntries, logEntry)
+		logEntry.LogGroup = logEvent.LogGroup
+		logEntry.LogStream = logEvent.LogStream
+		stream.Add(logEntry, time.Time{})
 	}
 
-	// Analyze log entries
-	return a.aggregator.AnalyzeLogs(logEntries, a.normalizer, startTime, endTime)
+	return stream.Result(startTime, endTime)
 }
 
 // OutputJSON writes the analysis result as JSON to the provided writer
 func (a *Analyzer) OutputJSON(result *models.AnalysisResult, writer io.Writer) error {
-	// Convert to simplified format
-	simplified := make([]*models.SimplifiedPathMetrics, 0, len(result.PathMetrics))
+	return a.Output(result, output.NewJSONFormatter(), writer)
+}
+
+// Output writes the analysis result to the provided writer using the given formatter
+func (a *Analyzer) Output(result *models.AnalysisResult, formatter output.Formatter, writer io.Writer) error {
+	simplified := a.SimplifyMetrics(result)
 
-	for _, metrics := range result.PathMetrics {
+	records := make([]output.Record, 0, len(simplified))
+	for _, metrics := range simplified {
+		records = append(records, a.metricsRecord(metrics, metrics.Method, metrics.LogStream))
+	}
+
+	return formatter.Format(&output.Table{
+		Columns: a.metricsColumns(models.SimplifiedPathMetricsColumns),
+		Records: records,
+	}, writer)
+}
+
+// OutputDetailed writes the analysis result including status codes, methods and view/DB time
+func (a *Analyzer) OutputDetailed(result *models.AnalysisResult, formatter output.Formatter, writer io.Writer) error {
+	detailed := a.DetailMetrics(result)
+
+	records := make([]output.Record, 0, len(detailed))
+	for _, metrics := range detailed {
+		records = append(records, a.metricsRecord(metrics, metrics.Method, metrics.LogStream))
+	}
+
+	return formatter.Format(&output.Table{
+		Columns: a.metricsColumns(models.DetailedPathMetricsColumns),
+		Records: records,
+	}, writer)
+}
+
+// SimplifyMetrics converts the analysis result into simplified metrics selected by the select options
+func (a *Analyzer) SimplifyMetrics(result *models.AnalysisResult) []*models.SimplifiedPathMetrics {
+	sorted := SelectMetrics(result.PathMetrics, a.selectOptions)
+	simplified := make([]*models.SimplifiedPathMetrics, 0, len(sorted))
+
+	for _, metrics := range sorted {
 		simplified = append(simplified, &models.SimplifiedPathMetrics{
+			Method:    metrics.Method,
 			Path:      metrics.Path,
+			LogStream: metrics.LogStream,
 			Count:     metrics.Count,
 			MaxTimeMs: metrics.MaxTime,
 			MinTimeMs: metrics.MinTime,
 			AvgTimeMs: int(metrics.AverageTime),
+			P50TimeMs: metrics.P50Time,
+			P90TimeMs: metrics.P90Time,
+			P95TimeMs: metrics.P95Time,
+			P99TimeMs: metrics.P99Time,
 		})
 	}
 
-	// Sort by count in descending order (highest count first)
-	sort.Slice(simplified, func(i, j int) bool {
-		return simplified[i].Count > simplified[j].Count
-	})
+	return simplified
+}
+
+// DetailMetrics converts the analysis result into detailed metrics with derived averages, selected by the select options
+func (a *Analyzer) DetailMetrics(result *models.AnalysisResult) []*models.DetailedPathMetrics {
+	sorted := SelectMetrics(result.PathMetrics, a.selectOptions)
+	detailed := make([]*models.DetailedPathMetrics, 0, len(sorted))
+
+	for _, metrics := range sorted {
+		entry := &models.DetailedPathMetrics{
+			Method:      metrics.Method,
+			Path:        metrics.Path,
+			LogStream:   metrics.LogStream,
+			Count:       metrics.Count,
+			MaxTimeMs:   metrics.MaxTime,
+			MinTimeMs:   metrics.MinTime,
+			AvgTimeMs:   int(metrics.AverageTime),
+			P50TimeMs:   metrics.P50Time,
+			P90TimeMs:   metrics.P90Time,
+			P95TimeMs:   metrics.P95Time,
+			P99TimeMs:   metrics.P99Time,
+			Count4xx:    countStatusRange(metrics.StatusCodes, 400),
+			Count5xx:    countStatusRange(metrics.StatusCodes, 500),
+			ErrorRate:   serverErrorRate(metrics),
+			StatusCodes: metrics.StatusCodes,
+			Methods:     metrics.Methods,
+			LogGroups:   metrics.LogGroups,
+		}
+
+		if metrics.Count > 0 {
+			entry.AvgViewMs = metrics.TotalViewDuration / float64(metrics.Count)
+			entry.AvgDBMs = metrics.TotalDBDuration / float64(metrics.Count)
+		}
+
+		detailed = append(detailed, entry)
+	}
 
-	encoder := json.NewEncoder(writer)
-	encoder.SetIndent("", "    ")
-	return encoder.Encode(simplified)
+	return detailed
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/output"
 )
 
 func TestAnalyzer_AnalyzeLogEvents(t *testing.T) {
@@ -39,9 +40,12 @@ func TestAnalyzer_AnalyzeLogEvents(t *testing.T) {
 				},
 			},
 			expected: &models.AnalysisResult{
-				StartTime: startTime,
-				EndTime:   endTime,
-				TotalLogs: 2,
+				StartTime:   startTime,
+				EndTime:     endTime,
+				TotalLogs:   2,
+				MatchedBy:   map[string]int{models.MatchByRequestID: 1},
+				Diagnostics: newEmptyDiagnostics(),
+				Incomplete:  map[string]*models.IncompleteMetrics{},
 				PathMetrics: map[string]*models.PathMetrics{
 					"/users/:id": {
 						Path:              "/users/:id",
@@ -53,6 +57,11 @@ func TestAnalyzer_AnalyzeLogEvents(t *testing.T) {
 						Methods:           map[string]int{"GET": 1},
 						TotalViewDuration: 100.0,
 						TotalDBDuration:   50.0,
+						P50Time:           150,
+						P90Time:           150,
+						P95Time:           150,
+						P99Time:           150,
+						Durations:         map[int]int{150: 1},
 					},
 				},
 			},
@@ -64,6 +73,9 @@ func TestAnalyzer_AnalyzeLogEvents(t *testing.T) {
 				StartTime:   startTime,
 				EndTime:     endTime,
 				TotalLogs:   0,
+				MatchedBy:   map[string]int{},
+				Diagnostics: newEmptyDiagnostics(),
+				Incomplete:  map[string]*models.IncompleteMetrics{},
 				PathMetrics: map[string]*models.PathMetrics{},
 			},
 		},
@@ -87,9 +99,12 @@ func TestAnalyzer_AnalyzeLogEvents(t *testing.T) {
 				},
 			},
 			expected: &models.AnalysisResult{
-				StartTime: startTime,
-				EndTime:   endTime,
-				TotalLogs: 2,
+				StartTime:   startTime,
+				EndTime:     endTime,
+				TotalLogs:   2,
+				MatchedBy:   map[string]int{models.MatchByRequestID: 1},
+				Diagnostics: newEmptyDiagnostics(),
+				Incomplete:  map[string]*models.IncompleteMetrics{},
 				PathMetrics: map[string]*models.PathMetrics{
 					"/users/:id": {
 						Path:        "/users/:id",
@@ -99,6 +114,11 @@ func TestAnalyzer_AnalyzeLogEvents(t *testing.T) {
 						MaxTime:     150,
 						StatusCodes: map[int]int{200: 1},
 						Methods:     map[string]int{"GET": 1},
+						P50Time:     150,
+						P90Time:     150,
+						P95Time:     150,
+						P99Time:     150,
+						Durations:   map[int]int{150: 1},
 					},
 				},
 			},
@@ -113,6 +133,76 @@ func TestAnalyzer_AnalyzeLogEvents(t *testing.T) {
 	}
 }
 
+func TestAnalyzer_AnalyzeStream(t *testing.T) {
+	analyzer := NewAnalyzer()
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	endTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+
+	logEvents := []*models.LogEvent{
+		{
+			ID:        "1",
+			Message:   `Started GET "/users/123" for 127.0.0.1 at 2023-01-01 12:00:00 +0900 [abc123]`,
+			Timestamp: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:        "2",
+			Message:   `Invalid log format`,
+			Timestamp: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:        "3",
+			Message:   `Completed 200 OK in 150ms (Views: 100.0ms | ActiveRecord: 50.0ms) [abc123]`,
+			Timestamp: time.Date(2023, 1, 1, 12, 0, 1, 0, time.UTC),
+		},
+	}
+
+	events := make(chan *models.LogEvent, len(logEvents))
+	for _, event := range logEvents {
+		events <- event
+	}
+	close(events)
+
+	result := analyzer.AnalyzeStream(events, startTime, endTime)
+	assert.Equal(t, analyzer.AnalyzeLogEvents(logEvents, startTime, endTime), result)
+}
+
+func TestAnalyzer_AnalyzeEntryStream(t *testing.T) {
+	analyzer := NewAnalyzer()
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	endTime := time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC)
+
+	logEntries := []*models.LogEntry{
+		{Type: "Started", Method: "GET", Path: "/users/123", Timestamp: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC), SessionID: "abc123"},
+		{Type: "Started", Method: "GET", Path: "/posts", Timestamp: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC), SessionID: "def456"},
+		{Type: "Completed", StatusCode: 200, Duration: 150, Timestamp: time.Date(2023, 1, 1, 12, 0, 1, 0, time.UTC), SessionID: "abc123"},
+	}
+
+	entries := make(chan *models.LogEntry, len(logEntries))
+	for _, entry := range logEntries {
+		entries <- entry
+	}
+	close(entries)
+
+	result := analyzer.AnalyzeEntryStream(entries, startTime, endTime)
+	assert.Equal(t, 3, result.TotalLogs)
+	require.Len(t, result.PathMetrics, 1)
+	metrics := result.PathMetrics["/users/:id"]
+	require.NotNil(t, metrics)
+	assert.Equal(t, 1, metrics.Count)
+	assert.Equal(t, 150, metrics.MaxTime)
+	assert.Equal(t, map[string]int{"GET": 1}, metrics.Methods)
+}
+
+func TestAnalyzer_SetPendingTimeout(t *testing.T) {
+	analyzer := NewAnalyzer()
+	assert.Equal(t, DefaultPendingTimeout, analyzer.pendingTimeout)
+
+	assert.NoError(t, analyzer.SetPendingTimeout(0))
+	assert.Equal(t, time.Duration(0), analyzer.pendingTimeout)
+
+	assert.Error(t, analyzer.SetPendingTimeout(-time.Second))
+}
+
 func TestAnalyzer_OutputJSON(t *testing.T) {
 	analyzer := NewAnalyzer()
 
@@ -136,6 +226,10 @@ func TestAnalyzer_OutputJSON(t *testing.T) {
 						MaxTime:     150,
 						StatusCodes: map[int]int{200: 1},
 						Methods:     map[string]int{"GET": 1},
+						P50Time:     150,
+						P90Time:     150,
+						P95Time:     150,
+						P99Time:     150,
 					},
 				},
 			},
@@ -145,7 +239,11 @@ func TestAnalyzer_OutputJSON(t *testing.T) {
         "count": 1,
         "max_time_ms": 150,
         "min_time_ms": 150,
-        "avg_time_ms": 150
+        "avg_time_ms": 150,
+        "p50_time_ms": 150,
+        "p90_time_ms": 150,
+        "p95_time_ms": 150,
+        "p99_time_ms": 150
     }
 ]`,
 		},
@@ -166,6 +264,10 @@ func TestAnalyzer_OutputJSON(t *testing.T) {
 						Methods:           map[string]int{"GET": 2},
 						TotalViewDuration: 180.5,
 						TotalDBDuration:   95.2,
+						P50Time:           150,
+						P90Time:           250,
+						P95Time:           250,
+						P99Time:           250,
 					},
 				},
 			},
@@ -175,7 +277,11 @@ func TestAnalyzer_OutputJSON(t *testing.T) {
         "count": 2,
         "max_time_ms": 250,
         "min_time_ms": 150,
-        "avg_time_ms": 200
+        "avg_time_ms": 200,
+        "p50_time_ms": 150,
+        "p90_time_ms": 250,
+        "p95_time_ms": 250,
+        "p99_time_ms": 250
     }
 ]`,
 		},
@@ -204,6 +310,10 @@ func TestAnalyzer_OutputJSON(t *testing.T) {
 						MaxTime:     2300,
 						StatusCodes: map[int]int{200: 100},
 						Methods:     map[string]int{"GET": 100},
+						P50Time:     900,
+						P90Time:     1800,
+						P95Time:     2000,
+						P99Time:     2250,
 					},
 					"/path1/path3": {
 						Path:        "/path1/path3",
@@ -213,6 +323,10 @@ func TestAnalyzer_OutputJSON(t *testing.T) {
 						MaxTime:     2200,
 						StatusCodes: map[int]int{200: 50},
 						Methods:     map[string]int{"POST": 50},
+						P50Time:     1100,
+						P90Time:     1900,
+						P95Time:     2100,
+						P99Time:     2190,
 					},
 				},
 			},
@@ -222,14 +336,22 @@ func TestAnalyzer_OutputJSON(t *testing.T) {
         "count": 100,
         "max_time_ms": 2300,
         "min_time_ms": 640,
-        "avg_time_ms": 1000
+        "avg_time_ms": 1000,
+        "p50_time_ms": 900,
+        "p90_time_ms": 1800,
+        "p95_time_ms": 2000,
+        "p99_time_ms": 2250
     },
     {
         "path": "/path1/path3",
         "count": 50,
         "max_time_ms": 2200,
         "min_time_ms": 840,
-        "avg_time_ms": 1200
+        "avg_time_ms": 1200,
+        "p50_time_ms": 1100,
+        "p90_time_ms": 1900,
+        "p95_time_ms": 2100,
+        "p99_time_ms": 2190
     }
 ]`,
 		},
@@ -250,6 +372,10 @@ func TestAnalyzer_OutputJSON(t *testing.T) {
 						Methods:           map[string]int{"GET": 5},
 						TotalViewDuration: 0,
 						TotalDBDuration:   0,
+						P50Time:           0,
+						P90Time:           0,
+						P95Time:           0,
+						P99Time:           0,
 					},
 				},
 			},
@@ -259,7 +385,11 @@ func TestAnalyzer_OutputJSON(t *testing.T) {
         "count": 5,
         "max_time_ms": 0,
         "min_time_ms": 0,
-        "avg_time_ms": 0
+        "avg_time_ms": 0,
+        "p50_time_ms": 0,
+        "p90_time_ms": 0,
+        "p95_time_ms": 0,
+        "p99_time_ms": 0
     }
 ]`,
 		},
@@ -283,6 +413,165 @@ func TestAnalyzer_OutputJSON(t *testing.T) {
 	}
 }
 
+func TestAnalyzer_Output(t *testing.T) {
+	analyzer := NewAnalyzer()
+	result := &models.AnalysisResult{
+		PathMetrics: map[string]*models.PathMetrics{
+			"/users/:id": {
+				Path:        "/users/:id",
+				Count:       1,
+				AverageTime: 150.0,
+				MinTime:     150,
+				MaxTime:     150,
+				P50Time:     150,
+				P90Time:     150,
+				P95Time:     150,
+				P99Time:     150,
+			},
+			"/posts": {
+				Path:        "/posts",
+				Count:       3,
+				AverageTime: 80.5,
+				MinTime:     40,
+				MaxTime:     120,
+				P50Time:     80,
+				P90Time:     120,
+				P95Time:     120,
+				P99Time:     120,
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := analyzer.Output(result, output.NewCSVFormatter(), &buf)
+	require.NoError(t, err)
+
+	expected := `path,count,max_time_ms,min_time_ms,avg_time_ms,p50_time_ms,p90_time_ms,p95_time_ms,p99_time_ms
+/posts,3,120,40,80,80,120,120,120
+/users/:id,1,150,150,150,150,150,150,150
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestAnalyzer_DetailMetrics(t *testing.T) {
+	analyzer := NewAnalyzer()
+	result := &models.AnalysisResult{
+		PathMetrics: map[string]*models.PathMetrics{
+			"/users/:id": {
+				Path:              "/users/:id",
+				Count:             4,
+				AverageTime:       200.0,
+				MinTime:           100,
+				MaxTime:           400,
+				StatusCodes:       map[int]int{200: 2, 404: 1, 500: 1},
+				Methods:           map[string]int{"GET": 3, "DELETE": 1},
+				TotalViewDuration: 120.0,
+				TotalDBDuration:   400.0,
+				P50Time:           150,
+				P90Time:           400,
+				P95Time:           400,
+				P99Time:           400,
+				LogGroups:         map[string]int{"app-web": 3, "app-api": 1},
+			},
+			"/health": {
+				Path:        "/health",
+				Count:       4,
+				AverageTime: 1.0,
+				MinTime:     1,
+				MaxTime:     1,
+				StatusCodes: map[int]int{200: 4},
+				Methods:     map[string]int{"GET": 4},
+			},
+		},
+	}
+
+	detailed := analyzer.DetailMetrics(result)
+	require.Len(t, detailed, 2)
+
+	// Equal counts are ordered by path
+	assert.Equal(t, "/health", detailed[0].Path)
+	assert.Equal(t, 0.0, detailed[0].ErrorRate)
+	assert.Equal(t, 0.0, detailed[0].AvgDBMs)
+
+	users := detailed[1]
+	assert.Equal(t, &models.DetailedPathMetrics{
+		Path:        "/users/:id",
+		Count:       4,
+		MaxTimeMs:   400,
+		MinTimeMs:   100,
+		AvgTimeMs:   200,
+		P50TimeMs:   150,
+		P90TimeMs:   400,
+		P95TimeMs:   400,
+		P99TimeMs:   400,
+		AvgViewMs:   30.0,
+		AvgDBMs:     100.0,
+		Count4xx:    1,
+		Count5xx:    1,
+		ErrorRate:   0.25,
+		StatusCodes: map[int]int{200: 2, 404: 1, 500: 1},
+		Methods:     map[string]int{"GET": 3, "DELETE": 1},
+		LogGroups:   map[string]int{"app-web": 3, "app-api": 1},
+	}, users)
+
+	assert.Equal(t, []string{
+		"/users/:id", "4", "400", "100", "200", "150", "400", "400", "400",
+		"30.0", "100.0", "1", "1", "0.2500", "200:2 404:1 500:1", "DELETE:1 GET:3", "app-api:1 app-web:3",
+	}, users.Values())
+}
+
+func TestAnalyzer_OutputDetailed(t *testing.T) {
+	analyzer := NewAnalyzer()
+	result := &models.AnalysisResult{
+		PathMetrics: map[string]*models.PathMetrics{
+			"/posts": {
+				Path:            "/posts",
+				Count:           2,
+				AverageTime:     50.0,
+				MinTime:         40,
+				MaxTime:         60,
+				StatusCodes:     map[int]int{201: 2},
+				Methods:         map[string]int{"POST": 2},
+				TotalDBDuration: 30.0,
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := analyzer.OutputDetailed(result, output.NewJSONFormatter(), &buf)
+	require.NoError(t, err)
+
+	var actual []map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &actual))
+	require.Len(t, actual, 1)
+	assert.Equal(t, 15.0, actual[0]["avg_db_ms"])
+	assert.Equal(t, map[string]interface{}{"201": 2.0}, actual[0]["status_codes"])
+	assert.Equal(t, map[string]interface{}{"POST": 2.0}, actual[0]["methods"])
+	assert.Equal(t, 0.0, actual[0]["error_rate"])
+}
+
+func TestAnalyzer_SetSelectOptions(t *testing.T) {
+	analyzer := NewAnalyzer()
+	result := &models.AnalysisResult{
+		PathMetrics: map[string]*models.PathMetrics{
+			"/users/:id": {Path: "/users/:id", Count: 100, AverageTime: 20.0},
+			"/reports":   {Path: "/reports", Count: 5, AverageTime: 1500.0},
+			"/health":    {Path: "/health", Count: 1000, AverageTime: 1.0},
+		},
+	}
+
+	err := analyzer.SetSelectOptions(SelectOptions{SortBy: SortByTotalTime, Order: OrderDesc, Top: 2})
+	require.NoError(t, err)
+
+	simplified := analyzer.SimplifyMetrics(result)
+	require.Len(t, simplified, 2)
+	assert.Equal(t, "/reports", simplified[0].Path)
+	assert.Equal(t, "/users/:id", simplified[1].Path)
+
+	err = analyzer.SetSelectOptions(SelectOptions{SortBy: "unknown", Order: OrderDesc})
+	assert.Error(t, err)
+}
+
 func TestNewAnalyzer(t *testing.T) {
 	analyzer := NewAnalyzer()
 	assert.NotNil(t, analyzer)
@@ -0,0 +1,296 @@
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/output"
+)
+
+// collapsedSampleCount is the number of collapsed values kept as examples per position
+const collapsedSampleCount = 3
+
+// segmentNode is a node of the tree of normalized path segments
+type segmentNode struct {
+	children map[string]*segmentNode
+	paths    []string // Normalized paths ending at this node
+	count    int      // Requests of the paths in the subtree
+}
+
+// newSegmentNode creates an empty node
+func newSegmentNode() *segmentNode {
+	return &segmentNode{children: make(map[string]*segmentNode)}
+}
+
+// insert adds a path with its request count below the node
+func (n *segmentNode) insert(path string, count int) {
+	node := n
+	node.count += count
+	for _, segment := range splitSegments(path) {
+		child, exists := node.children[segment]
+		if !exists {
+			child = newSegmentNode()
+			node.children[segment] = child
+		}
+		child.count += count
+		node = child
+	}
+	node.paths = append(node.paths, path)
+}
+
+// merge moves the paths and children of other into the node
+func (n *segmentNode) merge(other *segmentNode) {
+	n.paths = append(n.paths, other.paths...)
+	n.count += other.count
+	for segment, child := range other.children {
+		if existing, exists := n.children[segment]; exists {
+			existing.merge(child)
+		} else {
+			n.children[segment] = child
+		}
+	}
+}
+
+// segmentCollapser collapses the positions of the segment tree with more distinct one-off values than a threshold
+// A value is one-off when its paths were requested at most maxCount times, so static names that are requested
+// often keep their own metrics next to a collapsed position. A zero maxCount counts every value as one-off
+type segmentCollapser struct {
+	threshold int
+	maxCount  int
+	collapsed []*models.CollapsedSegment
+	rewrites  map[string]string // Normalized path to collapsed path
+}
+
+// collapse collapses the one-off children of node, then descends into the remaining children
+// Collapsing a position first merges the subtrees below it, so deeper positions are judged on the merged values
+// The leading segment is never collapsed, since it names the resource rather than a value
+// The paths below a collapsed position are rewritten; changed tells whether a position above node was collapsed
+func (c *segmentCollapser) collapse(node *segmentNode, segments []string, changed bool) {
+	literals := make([]string, 0, len(node.children))
+	for segment, child := range node.children {
+		if len(segments) > 0 && !isPlaceholderSegment(segment) && (c.maxCount == 0 || child.count <= c.maxCount) {
+			literals = append(literals, segment)
+		}
+	}
+
+	collapsedHere := len(literals) > c.threshold
+	if collapsedHere {
+		sort.Strings(literals)
+		merged, exists := node.children[models.CollapsedPlaceholder]
+		if !exists {
+			merged = newSegmentNode()
+		}
+		count := 0
+		for _, segment := range literals {
+			count += node.children[segment].count
+			merged.merge(node.children[segment])
+			delete(node.children, segment)
+		}
+		node.children[models.CollapsedPlaceholder] = merged
+
+		c.collapsed = append(c.collapsed, &models.CollapsedSegment{
+			Path:     joinSegments(append(segments, models.CollapsedPlaceholder)),
+			Position: len(segments) + 1,
+			Distinct: len(literals),
+			Count:    count,
+			Samples:  literals[:min(len(literals), collapsedSampleCount)],
+		})
+	}
+
+	for segment, child := range node.children {
+		childSegments := append(segments[:len(segments):len(segments)], segment)
+		childChanged := changed || (collapsedHere && segment == models.CollapsedPlaceholder)
+		if childChanged {
+			collapsedPath := joinSegments(childSegments)
+			for _, path := range child.paths {
+				if path != collapsedPath {
+					c.rewrites[path] = collapsedPath
+				}
+			}
+		}
+		c.collapse(child, childSegments, childChanged)
+	}
+}
+
+// rewrite returns the collapsed path of a normalized path, keeping its query parameters
+func (c *segmentCollapser) rewrite(path string) (string, bool) {
+	pathPart, query := splitQuery(path)
+	collapsedPath, exists := c.rewrites[pathPart]
+	if !exists {
+		return "", false
+	}
+	return collapsedPath + query, true
+}
+
+// collapseHighCardinality merges the metrics of path positions with more distinct one-off values than the threshold
+// under CollapsedPlaceholder and returns the collapsed positions ordered by path
+// Values whose paths were requested more than maxCount times are kept, unless maxCount is zero
+func (a *Aggregator) collapseHighCardinality(pathMetrics map[string]*models.PathMetrics, incomplete map[string]*models.IncompleteMetrics, threshold, maxCount int) []*models.CollapsedSegment {
+	// Controller actions have no path segments to collapse
+	if threshold <= 0 || a.groupOptions.Action {
+		return nil
+	}
+
+	// Query parameters kept by the query rules are not part of the segment tree
+	root := newSegmentNode()
+	counts := make(map[string]int)
+	for _, metrics := range pathMetrics {
+		path, _ := splitQuery(metrics.Path)
+		counts[path] += metrics.Count
+	}
+	for _, metrics := range incomplete {
+		path, _ := splitQuery(metrics.Path)
+		if _, exists := counts[path]; !exists {
+			counts[path] = 0
+		}
+	}
+	for path, count := range counts {
+		root.insert(path, count)
+	}
+
+	collapser := &segmentCollapser{threshold: threshold, maxCount: maxCount, rewrites: make(map[string]string)}
+	collapser.collapse(root, nil, false)
+	if len(collapser.collapsed) == 0 {
+		return nil
+	}
+
+	for key, metrics := range pathMetrics {
+		collapsedPath, exists := collapser.rewrite(metrics.Path)
+		if !exists {
+			continue
+		}
+		delete(pathMetrics, key)
+		metrics.Path = collapsedPath
+		collapsedKey := a.groupOptions.metricsKey(metrics.Method, collapsedPath, metrics.LogStream)
+		if existing, exists := pathMetrics[collapsedKey]; exists {
+			mergeMetrics(existing, metrics)
+		} else {
+			pathMetrics[collapsedKey] = metrics
+		}
+	}
+
+	for key, metrics := range incomplete {
+		collapsedPath, exists := collapser.rewrite(metrics.Path)
+		if !exists {
+			continue
+		}
+		delete(incomplete, key)
+		metrics.Path = collapsedPath
+		collapsedKey := a.groupOptions.metricsKey(metrics.Method, collapsedPath, metrics.LogStream)
+		if existing, exists := incomplete[collapsedKey]; exists {
+			existing.Count += metrics.Count
+			existing.Requests = append(existing.Requests, metrics.Requests...)
+		} else {
+			incomplete[collapsedKey] = metrics
+		}
+	}
+
+	sort.Slice(collapser.collapsed, func(i, j int) bool {
+		return collapser.collapsed[i].Path < collapser.collapsed[j].Path
+	})
+	return collapser.collapsed
+}
+
+// mergeMetrics adds the requests of other to metrics; percentiles are recalculated by finalizeMetrics
+func mergeMetrics(metrics, other *models.PathMetrics) {
+	if other.Count == 0 {
+		return
+	}
+	if metrics.Count == 0 {
+		metrics.MinTime = other.MinTime
+		metrics.MaxTime = other.MaxTime
+	} else {
+		metrics.MinTime = min(metrics.MinTime, other.MinTime)
+		metrics.MaxTime = max(metrics.MaxTime, other.MaxTime)
+	}
+
+	total := metrics.Count + other.Count
+	metrics.AverageTime = (metrics.AverageTime*float64(metrics.Count) + other.AverageTime*float64(other.Count)) / float64(total)
+	metrics.Count = total
+
+	for statusCode, count := range other.StatusCodes {
+		metrics.StatusCodes[statusCode] += count
+	}
+	for method, count := range other.Methods {
+		metrics.Methods[method] += count
+	}
+	for logGroup, count := range other.LogGroups {
+		if metrics.LogGroups == nil {
+			metrics.LogGroups = make(map[string]int)
+		}
+		metrics.LogGroups[logGroup] += count
+	}
+	for name, count := range other.QueryParams {
+		if metrics.QueryParams == nil {
+			metrics.QueryParams = make(map[string]int)
+		}
+		metrics.QueryParams[name] += count
+	}
+	metrics.TotalViewDuration += other.TotalViewDuration
+	metrics.TotalDBDuration += other.TotalDBDuration
+	for bucket, count := range other.Durations {
+		if metrics.Durations == nil {
+			metrics.Durations = make(map[int]int)
+		}
+		metrics.Durations[bucket] += count
+	}
+}
+
+// isPlaceholderSegment checks if a segment is already a placeholder, e.g. :id or a route glob
+func isPlaceholderSegment(segment string) bool {
+	return strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*")
+}
+
+// splitSegments splits a path into its segments without the leading slash
+func splitSegments(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
+
+// splitQuery splits a normalized path into the path and the query parameters kept by the query rules, if any
+func splitQuery(path string) (string, string) {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		return path[:i], path[i:]
+	}
+	return path, ""
+}
+
+// joinSegments joins segments into a path with a leading slash
+func joinSegments(segments []string) string {
+	return "/" + strings.Join(segments, "/")
+}
+
+// SetCollapseThreshold sets the number of distinct one-off values at a path position above which they are collapsed
+// into a single placeholder after aggregation. Zero disables collapsing
+func (a *Analyzer) SetCollapseThreshold(threshold int) error {
+	if threshold < 0 {
+		return fmt.Errorf("collapse threshold must not be negative: %d", threshold)
+	}
+	a.aggregator.collapseThreshold = threshold
+	return nil
+}
+
+// SetCollapseMaxCount sets the number of requests up to which a value at a path position is one-off and can be
+// collapsed. Zero collapses values however often they are requested
+func (a *Analyzer) SetCollapseMaxCount(maxCount int) error {
+	if maxCount < 0 {
+		return fmt.Errorf("collapse max count must not be negative: %d", maxCount)
+	}
+	a.aggregator.collapseMaxCount = maxCount
+	return nil
+}
+
+// OutputCollapsed writes the collapsed path positions using the given formatter
+func (a *Analyzer) OutputCollapsed(result *models.AnalysisResult, formatter output.Formatter, writer io.Writer) error {
+	records := make([]output.Record, 0, len(result.Collapsed))
+	for _, collapsed := range result.Collapsed {
+		records = append(records, collapsed)
+	}
+
+	return formatter.Format(&output.Table{
+		Columns: models.CollapsedSegmentColumns,
+		Records: records,
+	}, writer)
+}
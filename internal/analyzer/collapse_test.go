@@ -0,0 +1,186 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/output"
+)
+
+// requestEvents returns a Started and a Completed event for a request to path
+func requestEvents(id int, method, path string, duration int) []*models.LogEvent {
+	return []*models.LogEvent{
+		{Message: fmt.Sprintf(`Started %s "%s" for 127.0.0.1 at 2023-01-01 00:00:00 +0000 [r%d]`, method, path, id)},
+		{Message: fmt.Sprintf(`Completed 200 OK in %dms [r%d]`, duration, id)},
+	}
+}
+
+func TestAnalyzer_CollapseHighCardinality(t *testing.T) {
+	analyzer := NewAnalyzer()
+	require.NoError(t, analyzer.SetCollapseThreshold(3))
+
+	var events []*models.LogEvent
+	for i, slug := range []string{"alpha", "bravo", "charlie", "delta"} {
+		events = append(events, requestEvents(i, "GET", "/articles/"+slug, (i+1)*10)...)
+		events = append(events, requestEvents(100+i, "GET", "/articles/"+slug+"/comments", 5)...)
+	}
+	events = append(events, requestEvents(200, "GET", "/articles/1", 50)...)
+	for i, page := range []string{"about", "terms", "privacy"} {
+		events = append(events, requestEvents(300+i, "GET", "/pages/"+page, 1)...)
+	}
+	// Never completed, so only counted in the incomplete metrics
+	events = append(events, &models.LogEvent{Message: `Started GET "/articles/echo" for 127.0.0.1 at 2023-01-01 00:00:00 +0000 [x]`})
+
+	result := analyzer.AnalyzeLogEvents(events, time.Time{}, time.Time{})
+
+	assert.Equal(t, []*models.CollapsedSegment{
+		{Path: "/articles/:param", Position: 2, Distinct: 5, Count: 8, Samples: []string{"alpha", "bravo", "charlie"}},
+	}, result.Collapsed)
+
+	collapsed := result.PathMetrics["/articles/:param"]
+	require.NotNil(t, collapsed)
+	assert.Equal(t, 4, collapsed.Count)
+	assert.Equal(t, 10, collapsed.MinTime)
+	assert.Equal(t, 40, collapsed.MaxTime)
+	assert.Equal(t, 25.0, collapsed.AverageTime)
+	assert.Equal(t, 20, collapsed.P50Time)
+	assert.Equal(t, map[int]int{200: 4}, collapsed.StatusCodes)
+	assert.Equal(t, map[string]int{"GET": 4}, collapsed.Methods)
+
+	assert.Equal(t, 4, result.PathMetrics["/articles/:param/comments"].Count)
+	assert.Equal(t, 1, result.PathMetrics["/articles/:id"].Count)
+	assert.Equal(t, 1, result.PathMetrics["/pages/about"].Count)
+	assert.NotContains(t, result.PathMetrics, "/articles/alpha")
+	assert.Len(t, result.PathMetrics, 6)
+
+	require.Contains(t, result.Incomplete, "/articles/:param")
+	assert.Equal(t, 1, result.Incomplete["/articles/:param"].Count)
+
+	var buf bytes.Buffer
+	require.NoError(t, analyzer.OutputCollapsed(result, output.NewCSVFormatter(), &buf))
+	assert.Equal(t, `path,position,distinct,count,samples
+/articles/:param,2,5,8,"alpha, bravo, charlie"
+`, buf.String())
+}
+
+func TestAnalyzer_CollapseNestedPositions(t *testing.T) {
+	analyzer := NewAnalyzer()
+	analyzer.SetGroupOptions(GroupOptions{LogStream: true})
+	require.NoError(t, analyzer.SetCollapseThreshold(2))
+
+	var events []*models.LogEvent
+	id := 0
+	for _, path := range []string{"/repos/ann/api", "/repos/ann/web", "/repos/bob/api", "/repos/bob/web", "/repos/cat/cli"} {
+		for _, event := range requestEvents(id, "GET", path, 10) {
+			event.LogStream = fmt.Sprintf("task-%d", id%2+1)
+			events = append(events, event)
+		}
+		id++
+	}
+
+	result := analyzer.AnalyzeLogEvents(events, time.Time{}, time.Time{})
+
+	// Distinct repositories only exceed the threshold once the users are merged
+	assert.Equal(t, []*models.CollapsedSegment{
+		{Path: "/repos/:param", Position: 2, Distinct: 3, Count: 5, Samples: []string{"ann", "bob", "cat"}},
+		{Path: "/repos/:param/:param", Position: 3, Distinct: 3, Count: 5, Samples: []string{"api", "cli", "web"}},
+	}, result.Collapsed)
+	assert.Equal(t, 3, result.PathMetrics["/repos/:param/:param task-1"].Count)
+	assert.Equal(t, 2, result.PathMetrics["/repos/:param/:param task-2"].Count)
+	assert.Len(t, result.PathMetrics, 2)
+}
+
+func TestAnalyzer_CollapseKeepsStaticSegments(t *testing.T) {
+	analyzer := NewAnalyzer()
+	require.NoError(t, analyzer.SetCollapseThreshold(3))
+	require.NoError(t, analyzer.SetCollapseMaxCount(3))
+
+	var events []*models.LogEvent
+	id := 0
+	// Static names requested more often than the max count are not one-off values
+	for _, name := range []string{"users", "posts", "orders", "comments", "tags"} {
+		for range 4 {
+			events = append(events, requestEvents(id, "GET", "/admin/"+name, 10)...)
+			id++
+		}
+	}
+	for _, token := range []string{"tok-a", "tok-b", "tok-c", "tok-d"} {
+		events = append(events, requestEvents(id, "GET", "/admin/"+token, 10)...)
+		id++
+	}
+	// Leading segments are never collapsed, however rarely they are requested
+	for _, name := range []string{"about", "terms", "privacy", "help"} {
+		events = append(events, requestEvents(id, "GET", "/"+name, 10)...)
+		id++
+	}
+
+	result := analyzer.AnalyzeLogEvents(events, time.Time{}, time.Time{})
+
+	assert.Equal(t, []*models.CollapsedSegment{
+		{Path: "/admin/:param", Position: 2, Distinct: 4, Count: 4, Samples: []string{"tok-a", "tok-b", "tok-c"}},
+	}, result.Collapsed)
+	assert.Equal(t, 4, result.PathMetrics["/admin/:param"].Count)
+	for _, name := range []string{"users", "posts", "orders", "comments", "tags"} {
+		assert.Equal(t, 4, result.PathMetrics["/admin/"+name].Count)
+	}
+	assert.Contains(t, result.PathMetrics, "/about")
+	assert.Len(t, result.PathMetrics, 10)
+}
+
+func TestAnalyzer_CollapseHighTrafficValues(t *testing.T) {
+	// Every slug is requested far more often than there are distinct slugs
+	var events []*models.LogEvent
+	id := 0
+	for _, slug := range []string{"alpha", "bravo", "charlie", "delta"} {
+		for range 10 {
+			events = append(events, requestEvents(id, "GET", "/articles/"+slug, 10)...)
+			id++
+		}
+	}
+
+	analyzer := NewAnalyzer()
+	require.NoError(t, analyzer.SetCollapseThreshold(2))
+	result := analyzer.AnalyzeLogEvents(events, time.Time{}, time.Time{})
+
+	assert.Equal(t, []*models.CollapsedSegment{
+		{Path: "/articles/:param", Position: 2, Distinct: 4, Count: 40, Samples: []string{"alpha", "bravo", "charlie"}},
+	}, result.Collapsed)
+	assert.Equal(t, 40, result.PathMetrics["/articles/:param"].Count)
+	assert.Len(t, result.PathMetrics, 1)
+
+	// With a max count below their traffic the slugs are treated as static names
+	require.NoError(t, analyzer.SetCollapseMaxCount(5))
+	result = analyzer.AnalyzeLogEvents(events, time.Time{}, time.Time{})
+	assert.Nil(t, result.Collapsed)
+	assert.Len(t, result.PathMetrics, 4)
+
+	require.NoError(t, analyzer.SetCollapseMaxCount(10))
+	result = analyzer.AnalyzeLogEvents(events, time.Time{}, time.Time{})
+	assert.Len(t, result.Collapsed, 1)
+}
+
+func TestAnalyzer_CollapseDisabled(t *testing.T) {
+	analyzer := NewAnalyzer()
+	assert.Error(t, analyzer.SetCollapseThreshold(-1))
+	assert.Error(t, analyzer.SetCollapseMaxCount(-1))
+
+	var events []*models.LogEvent
+	for i, slug := range []string{"alpha", "bravo", "charlie"} {
+		events = append(events, requestEvents(i, "GET", "/articles/"+slug, 10)...)
+	}
+	result := analyzer.AnalyzeLogEvents(events, time.Time{}, time.Time{})
+	assert.Nil(t, result.Collapsed)
+	assert.Len(t, result.PathMetrics, 3)
+
+	// Controller actions are never collapsed
+	require.NoError(t, analyzer.SetCollapseThreshold(1))
+	analyzer.SetGroupOptions(GroupOptions{Action: true})
+	result = analyzer.AnalyzeLogEvents(events, time.Time{}, time.Time{})
+	assert.Nil(t, result.Collapsed)
+}
@@ -0,0 +1,218 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/output"
+)
+
+// DefaultRegressionThreshold is the growth in percent of the average or p95 time flagged as a regression
+const DefaultRegressionThreshold = 20.0
+
+// comparisonStatusOrder ranks statuses so regressions are listed first
+var comparisonStatusOrder = map[string]int{
+	models.ComparisonRegression: 0,
+	models.ComparisonNew:        1,
+	models.ComparisonImproved:   2,
+	models.ComparisonRemoved:    3,
+	models.ComparisonUnchanged:  4,
+}
+
+// CompareOptions controls how two analysis results are compared
+type CompareOptions struct {
+	ThresholdPct    float64 // Growth in percent of the average or p95 time flagged as a regression
+	MinCount        int     // Paths with fewer requests in both results are skipped
+	RegressionsOnly bool    // Only report regressed paths
+}
+
+// DefaultCompareOptions returns options that flag a 20% slowdown as a regression
+func DefaultCompareOptions() CompareOptions {
+	return CompareOptions{
+		ThresholdPct: DefaultRegressionThreshold,
+	}
+}
+
+// Validate checks that the options contain sane limits
+func (o CompareOptions) Validate() error {
+	if o.ThresholdPct < 0 {
+		return fmt.Errorf("regression threshold must not be negative: %g", o.ThresholdPct)
+	}
+	if o.MinCount < 0 {
+		return fmt.Errorf("min count must not be negative: %d", o.MinCount)
+	}
+	return nil
+}
+
+// CheckComparable checks that two results were grouped and aggregated alike, since their metrics keys differ otherwise
+func CheckComparable(baseline, target *models.AnalysisResult) error {
+	if baseline.GroupBy != target.GroupBy {
+		return fmt.Errorf("baseline is grouped by %s but target by %s; analyze both with the same --group-by",
+			describeGroupBy(baseline.GroupBy), describeGroupBy(target.GroupBy))
+	}
+	if baseline.AggregateBy != target.AggregateBy {
+		return fmt.Errorf("baseline is aggregated by %s but target by %s; analyze both with the same --aggregate-by",
+			describeAggregateBy(baseline.AggregateBy), describeAggregateBy(target.AggregateBy))
+	}
+	return nil
+}
+
+// describeGroupBy names the grouping dimensions of a result for error messages
+func describeGroupBy(groupBy string) string {
+	if groupBy == "" {
+		return "path only"
+	}
+	return "path and " + groupBy
+}
+
+// describeAggregateBy names the aggregation key of a result for error messages
+func describeAggregateBy(aggregateBy string) string {
+	if aggregateBy == "" {
+		return AggregateByPath
+	}
+	return aggregateBy
+}
+
+// CompareResults compares the metrics of every path in the baseline and target results
+// Regressions are listed first, each status group ordered by the largest average time growth
+func CompareResults(baseline, target *models.AnalysisResult, opts CompareOptions) []*models.PathComparison {
+	paths := make(map[string]bool, len(baseline.PathMetrics)+len(target.PathMetrics))
+	for path := range baseline.PathMetrics {
+		paths[path] = true
+	}
+	for path := range target.PathMetrics {
+		paths[path] = true
+	}
+
+	comparisons := make([]*models.PathComparison, 0, len(paths))
+	for path := range paths {
+		comparison := comparePath(path, baseline.PathMetrics[path], target.PathMetrics[path], opts.ThresholdPct)
+		if comparison.BaselineCount < opts.MinCount && comparison.TargetCount < opts.MinCount {
+			continue
+		}
+		if opts.RegressionsOnly && comparison.Status != models.ComparisonRegression {
+			continue
+		}
+		comparisons = append(comparisons, comparison)
+	}
+
+	sort.Slice(comparisons, func(i, j int) bool {
+		a, b := comparisons[i], comparisons[j]
+		if comparisonStatusOrder[a.Status] != comparisonStatusOrder[b.Status] {
+			return comparisonStatusOrder[a.Status] < comparisonStatusOrder[b.Status]
+		}
+		if a.AvgDeltaPct != b.AvgDeltaPct {
+			return a.AvgDeltaPct > b.AvgDeltaPct
+		}
+		return a.Path < b.Path
+	})
+
+	return comparisons
+}
+
+// comparePath compares a single path; either side may be nil when the path was not requested
+func comparePath(path string, baseline, target *models.PathMetrics, thresholdPct float64) *models.PathComparison {
+	if baseline == nil {
+		baseline = &models.PathMetrics{}
+	}
+	if target == nil {
+		target = &models.PathMetrics{}
+	}
+
+	comparison := &models.PathComparison{
+		Path:          path,
+		BaselineCount: baseline.Count,
+		TargetCount:   target.Count,
+		CountDelta:    target.Count - baseline.Count,
+		BaselineAvgMs: int(baseline.AverageTime),
+		TargetAvgMs:   int(target.AverageTime),
+		AvgDeltaMs:    int(target.AverageTime) - int(baseline.AverageTime),
+		AvgDeltaPct:   percentChange(baseline.AverageTime, target.AverageTime),
+		BaselineMaxMs: baseline.MaxTime,
+		TargetMaxMs:   target.MaxTime,
+		MaxDeltaMs:    target.MaxTime - baseline.MaxTime,
+		BaselineP50Ms: baseline.P50Time,
+		TargetP50Ms:   target.P50Time,
+		P50DeltaMs:    target.P50Time - baseline.P50Time,
+		BaselineP90Ms: baseline.P90Time,
+		TargetP90Ms:   target.P90Time,
+		P90DeltaMs:    target.P90Time - baseline.P90Time,
+		BaselineP95Ms: baseline.P95Time,
+		TargetP95Ms:   target.P95Time,
+		P95DeltaMs:    target.P95Time - baseline.P95Time,
+		P95DeltaPct:   percentChange(float64(baseline.P95Time), float64(target.P95Time)),
+		BaselineP99Ms: baseline.P99Time,
+		TargetP99Ms:   target.P99Time,
+		P99DeltaMs:    target.P99Time - baseline.P99Time,
+	}
+
+	switch {
+	case baseline.Count == 0:
+		comparison.Status = models.ComparisonNew
+	case target.Count == 0:
+		comparison.Status = models.ComparisonRemoved
+	case comparison.AvgDeltaPct > thresholdPct || comparison.P95DeltaPct > thresholdPct:
+		comparison.Status = models.ComparisonRegression
+	case comparison.AvgDeltaPct < -thresholdPct:
+		comparison.Status = models.ComparisonImproved
+	default:
+		comparison.Status = models.ComparisonUnchanged
+	}
+
+	return comparison
+}
+
+// percentChange returns the change from baseline to target in percent, or 0 without a baseline
+func percentChange(baseline, target float64) float64 {
+	if baseline == 0 {
+		return 0
+	}
+	return (target - baseline) / baseline * 100
+}
+
+// OutputComparison writes path comparisons to the provided writer using the given formatter
+func (a *Analyzer) OutputComparison(comparisons []*models.PathComparison, formatter output.Formatter, writer io.Writer) error {
+	records := make([]output.Record, 0, len(comparisons))
+	for _, comparison := range comparisons {
+		records = append(records, comparison)
+	}
+
+	return formatter.Format(&output.Table{
+		Columns: models.PathComparisonColumns,
+		Records: records,
+	}, writer)
+}
+
+// SaveResult writes the full analysis result as JSON so it can be compared later without re-fetching
+func SaveResult(result *models.AnalysisResult, writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(result)
+}
+
+// LoadResult reads an analysis result previously written by SaveResult
+func LoadResult(reader io.Reader) (*models.AnalysisResult, error) {
+	var result models.AnalysisResult
+	if err := json.NewDecoder(reader).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode analysis result: %w", err)
+	}
+	if result.PathMetrics == nil {
+		result.PathMetrics = make(map[string]*models.PathMetrics)
+	}
+	return &result, nil
+}
+
+// LoadResultFile reads an analysis result from a file written by SaveResult
+func LoadResultFile(path string) (*models.AnalysisResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open result file: %w", err)
+	}
+	defer file.Close()
+
+	return LoadResult(file)
+}
@@ -0,0 +1,239 @@
+package analyzer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/output"
+)
+
+func newComparisonResult(metrics ...*models.PathMetrics) *models.AnalysisResult {
+	pathMetrics := make(map[string]*models.PathMetrics, len(metrics))
+	for _, m := range metrics {
+		pathMetrics[m.Path] = m
+	}
+	return &models.AnalysisResult{PathMetrics: pathMetrics}
+}
+
+func TestCompareResults(t *testing.T) {
+	baseline := newComparisonResult(
+		&models.PathMetrics{Path: "/users", Count: 100, AverageTime: 100, MaxTime: 300, P50Time: 90, P90Time: 150, P95Time: 200, P99Time: 280},
+		&models.PathMetrics{Path: "/posts", Count: 50, AverageTime: 200, MaxTime: 400, P95Time: 300},
+		&models.PathMetrics{Path: "/health", Count: 10, AverageTime: 5, MaxTime: 10, P95Time: 8},
+		&models.PathMetrics{Path: "/legacy", Count: 3, AverageTime: 50, MaxTime: 60, P95Time: 60},
+	)
+	target := newComparisonResult(
+		&models.PathMetrics{Path: "/users", Count: 120, AverageTime: 150, MaxTime: 500, P50Time: 120, P90Time: 200, P95Time: 260, P99Time: 450},
+		&models.PathMetrics{Path: "/posts", Count: 40, AverageTime: 100, MaxTime: 200, P95Time: 150},
+		&models.PathMetrics{Path: "/health", Count: 10, AverageTime: 5, MaxTime: 12, P95Time: 8},
+		&models.PathMetrics{Path: "/search", Count: 7, AverageTime: 80, MaxTime: 90, P95Time: 90},
+	)
+
+	comparisons := CompareResults(baseline, target, DefaultCompareOptions())
+	require.Len(t, comparisons, 5)
+
+	var paths, statuses []string
+	for _, comparison := range comparisons {
+		paths = append(paths, comparison.Path)
+		statuses = append(statuses, comparison.Status)
+	}
+	assert.Equal(t, []string{"/users", "/search", "/posts", "/legacy", "/health"}, paths)
+	assert.Equal(t, []string{
+		models.ComparisonRegression,
+		models.ComparisonNew,
+		models.ComparisonImproved,
+		models.ComparisonRemoved,
+		models.ComparisonUnchanged,
+	}, statuses)
+
+	assert.Equal(t, &models.PathComparison{
+		Path:          "/users",
+		Status:        models.ComparisonRegression,
+		BaselineCount: 100,
+		TargetCount:   120,
+		CountDelta:    20,
+		BaselineAvgMs: 100,
+		TargetAvgMs:   150,
+		AvgDeltaMs:    50,
+		AvgDeltaPct:   50,
+		BaselineMaxMs: 300,
+		TargetMaxMs:   500,
+		MaxDeltaMs:    200,
+		BaselineP50Ms: 90,
+		TargetP50Ms:   120,
+		P50DeltaMs:    30,
+		BaselineP90Ms: 150,
+		TargetP90Ms:   200,
+		P90DeltaMs:    50,
+		BaselineP95Ms: 200,
+		TargetP95Ms:   260,
+		P95DeltaMs:    60,
+		P95DeltaPct:   30,
+		BaselineP99Ms: 280,
+		TargetP99Ms:   450,
+		P99DeltaMs:    170,
+	}, comparisons[0])
+}
+
+func TestCompareResults_Options(t *testing.T) {
+	baseline := newComparisonResult(
+		&models.PathMetrics{Path: "/users", Count: 100, AverageTime: 100, P95Time: 200},
+		&models.PathMetrics{Path: "/posts", Count: 2, AverageTime: 100, P95Time: 100},
+	)
+	target := newComparisonResult(
+		&models.PathMetrics{Path: "/users", Count: 100, AverageTime: 125, P95Time: 200},
+		&models.PathMetrics{Path: "/posts", Count: 3, AverageTime: 300, P95Time: 300},
+	)
+
+	tests := []struct {
+		name     string
+		opts     CompareOptions
+		expected map[string]string
+	}{
+		{
+			name:     "default threshold",
+			opts:     DefaultCompareOptions(),
+			expected: map[string]string{"/users": models.ComparisonRegression, "/posts": models.ComparisonRegression},
+		},
+		{
+			name:     "higher threshold",
+			opts:     CompareOptions{ThresholdPct: 30},
+			expected: map[string]string{"/users": models.ComparisonUnchanged, "/posts": models.ComparisonRegression},
+		},
+		{
+			name:     "min count skips low traffic paths",
+			opts:     CompareOptions{ThresholdPct: 30, MinCount: 5},
+			expected: map[string]string{"/users": models.ComparisonUnchanged},
+		},
+		{
+			name:     "regressions only",
+			opts:     CompareOptions{ThresholdPct: 30, RegressionsOnly: true},
+			expected: map[string]string{"/posts": models.ComparisonRegression},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses := make(map[string]string)
+			for _, comparison := range CompareResults(baseline, target, tt.opts) {
+				statuses[comparison.Path] = comparison.Status
+			}
+			assert.Equal(t, tt.expected, statuses)
+		})
+	}
+}
+
+func TestCheckComparable(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseline *models.AnalysisResult
+		target   *models.AnalysisResult
+		errorMsg string
+	}{
+		{
+			name:     "same grouping",
+			baseline: &models.AnalysisResult{GroupBy: GroupByMethod},
+			target:   &models.AnalysisResult{GroupBy: GroupByMethod},
+		},
+		{
+			name:     "grouped by method against path only",
+			baseline: &models.AnalysisResult{GroupBy: GroupByMethod + "," + GroupByStream},
+			target:   &models.AnalysisResult{},
+			errorMsg: "baseline is grouped by path and method,stream but target by path only; analyze both with the same --group-by",
+		},
+		{
+			name:     "aggregated by action against path",
+			baseline: &models.AnalysisResult{},
+			target:   &models.AnalysisResult{AggregateBy: AggregateByAction},
+			errorMsg: "baseline is aggregated by path but target by action; analyze both with the same --aggregate-by",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckComparable(tt.baseline, tt.target)
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestCompareOptions_Validate(t *testing.T) {
+	assert.NoError(t, DefaultCompareOptions().Validate())
+	assert.Error(t, CompareOptions{ThresholdPct: -1}.Validate())
+	assert.Error(t, CompareOptions{MinCount: -1}.Validate())
+}
+
+func TestAnalyzer_OutputComparison(t *testing.T) {
+	analyzer := NewAnalyzer()
+	comparisons := []*models.PathComparison{
+		{Path: "/users", Status: models.ComparisonRegression, BaselineCount: 1, TargetCount: 2, CountDelta: 1, AvgDeltaPct: 12.345},
+	}
+
+	formatter, err := output.NewFormatter(output.FormatCSV)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, analyzer.OutputComparison(comparisons, formatter, &buf))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, strings.Join(models.PathComparisonColumns, ","), lines[0])
+	assert.Equal(t, "/users,regression,1,2,1,0,0,0,12.3,0,0,0,0,0,0,0,0,0,0,0,0,0.0,0,0,0", lines[1])
+}
+
+func TestSaveAndLoadResult(t *testing.T) {
+	result := &models.AnalysisResult{
+		StartTime: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2023, 1, 1, 23, 59, 59, 0, time.UTC),
+		TotalLogs: 4,
+		PathMetrics: map[string]*models.PathMetrics{
+			"/users/:id": {
+				Path:        "/users/:id",
+				Count:       2,
+				AverageTime: 150,
+				MinTime:     100,
+				MaxTime:     200,
+				StatusCodes: map[int]int{200: 2},
+				Methods:     map[string]int{"GET": 2},
+				P50Time:     100,
+				P90Time:     200,
+				P95Time:     200,
+				P99Time:     200,
+			},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "result.json")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, SaveResult(result, file))
+	require.NoError(t, file.Close())
+
+	loaded, err := LoadResultFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, result, loaded)
+}
+
+func TestLoadResult_Errors(t *testing.T) {
+	_, err := LoadResult(strings.NewReader("not json"))
+	assert.ErrorContains(t, err, "failed to decode analysis result")
+
+	_, err = LoadResultFile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorContains(t, err, "failed to open result file")
+
+	loaded, err := LoadResult(strings.NewReader("{}"))
+	require.NoError(t, err)
+	assert.NotNil(t, loaded.PathMetrics)
+}
@@ -0,0 +1,179 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/output"
+)
+
+// Reasons a request line could not be parsed
+const (
+	ParseFailureUnrecognizedFormat = "unrecognized_format" // Mentions Started or Completed but matches neither format
+	ParseFailureInvalidTimestamp   = "invalid_timestamp"
+	ParseFailureInvalidNumber      = "invalid_number" // Status code or duration out of range
+)
+
+// parseFailureReason classifies an error returned by ParseLogEntry
+// Empty lines are not failures and return an empty reason
+func parseFailureReason(err error) string {
+	switch {
+	case errors.Is(err, errEmptyLine):
+		return ""
+	case errors.Is(err, errInvalidTimestamp):
+		return ParseFailureInvalidTimestamp
+	case errors.Is(err, errInvalidNumber):
+		return ParseFailureInvalidNumber
+	default:
+		return ParseFailureUnrecognizedFormat
+	}
+}
+
+// countParseFailures returns the number of unparseable request lines over all reasons
+func countParseFailures(diagnostics *models.Diagnostics) int {
+	total := 0
+	for _, failures := range diagnostics.ParseFailures {
+		total += failures.Count
+	}
+	return total
+}
+
+// isRequestLine reports whether a log line looks like a Started, Completed or lograge line
+// Other lines, such as SQL or rendering logs from local files, are expected not to parse
+func isRequestLine(logLine string) bool {
+	return strings.Contains(logLine, "Started ") || strings.Contains(logLine, "Completed ") || isLogrageLine(logLine)
+}
+
+// diagnosticsCollector counts the log lines and entries that did not contribute to the metrics
+type diagnosticsCollector struct {
+	maxSamples  int
+	diagnostics models.Diagnostics
+}
+
+// newDiagnosticsCollector creates a new diagnosticsCollector keeping up to maxSamples examples per diagnostic
+func newDiagnosticsCollector(maxSamples int) *diagnosticsCollector {
+	return &diagnosticsCollector{
+		maxSamples: maxSamples,
+		diagnostics: models.Diagnostics{
+			ParseFailures: make(map[string]*models.DiagnosticCount),
+		},
+	}
+}
+
+// parseFailure records a request line that could not be parsed
+func (c *diagnosticsCollector) parseFailure(err error, logLine string) {
+	reason := parseFailureReason(err)
+	// Structured lines only fail with other reasons once they have all request fields
+	if reason == "" || (reason == ParseFailureUnrecognizedFormat && !isRequestLine(logLine)) {
+		return
+	}
+
+	failures, exists := c.diagnostics.ParseFailures[reason]
+	if !exists {
+		failures = &models.DiagnosticCount{}
+		c.diagnostics.ParseFailures[reason] = failures
+	}
+	c.add(failures, strings.TrimSpace(logLine))
+}
+
+// orphanedStarted records a Started entry that will never be matched
+func (c *diagnosticsCollector) orphanedStarted(entry *models.LogEntry) {
+	c.add(&c.diagnostics.OrphanedStarted, describeEntry(entry))
+}
+
+// orphanedCompleted records a Completed entry without a pending Started entry
+func (c *diagnosticsCollector) orphanedCompleted(entry *models.LogEntry) {
+	c.add(&c.diagnostics.OrphanedCompleted, describeEntry(entry))
+}
+
+// duplicateRequestID records a Started entry whose request ID was already pending
+func (c *diagnosticsCollector) duplicateRequestID(entry *models.LogEntry) {
+	c.add(&c.diagnostics.DuplicateRequestIDs, describeEntry(entry))
+}
+
+// add increments a diagnostic count and keeps the sample while there is room for it
+func (c *diagnosticsCollector) add(count *models.DiagnosticCount, sample string) {
+	count.Count++
+	if len(count.Samples) < c.maxSamples {
+		count.Samples = append(count.Samples, sample)
+	}
+}
+
+// result returns a copy of the collected diagnostics
+func (c *diagnosticsCollector) result() *models.Diagnostics {
+	diagnostics := c.diagnostics
+	return &diagnostics
+}
+
+// describeEntry summarizes a log entry for a diagnostic sample, including where it was logged
+func describeEntry(entry *models.LogEntry) string {
+	var description string
+	if entry.Type == "Started" {
+		description = fmt.Sprintf("Started %s %q at %s", entry.Method, entry.Path, entry.Timestamp.UTC().Format(time.RFC3339))
+	} else {
+		description = fmt.Sprintf("Completed %d %s in %dms", entry.StatusCode, entry.StatusText, entry.Duration)
+	}
+
+	var tags []string
+	for _, tag := range []string{entry.SessionID, entry.LogGroup, entry.LogStream} {
+		if tag != "" {
+			tags = append(tags, "["+tag+"]")
+		}
+	}
+	if entry.ProcessID != "" {
+		tags = append(tags, "[#"+entry.ProcessID+"]")
+	}
+	if entry.ThreadID != "" {
+		tags = append(tags, "[tid="+entry.ThreadID+"]")
+	}
+	if len(tags) == 0 {
+		return description
+	}
+	return description + " " + strings.Join(tags, " ")
+}
+
+// sortPending returns pending Started entries ordered by their timestamp, so samples are stable
+func sortPending(startedLogs map[string][]*pendingStart) []*models.LogEntry {
+	entries := make([]*models.LogEntry, 0, len(startedLogs))
+	for _, queue := range startedLogs {
+		for _, pending := range queue {
+			entries = append(entries, pending.entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Timestamp.Equal(entries[j].Timestamp) {
+			return entries[i].Timestamp.Before(entries[j].Timestamp)
+		}
+		return describeEntry(entries[i]) < describeEntry(entries[j])
+	})
+	return entries
+}
+
+// SetDiagnosticSamples sets how many example log lines are kept per diagnostic
+func (a *Analyzer) SetDiagnosticSamples(samples int) error {
+	if samples < 0 {
+		return fmt.Errorf("diagnostic samples must not be negative: %d", samples)
+	}
+	a.aggregator.diagnosticSamples = samples
+	return nil
+}
+
+// OutputDiagnostics writes the diagnostics of the analysis result using the given formatter
+func (a *Analyzer) OutputDiagnostics(result *models.AnalysisResult, formatter output.Formatter, writer io.Writer) error {
+	records := make([]output.Record, 0)
+	if result.Diagnostics != nil {
+		for _, record := range result.Diagnostics.Records() {
+			records = append(records, record)
+		}
+	}
+
+	return formatter.Format(&output.Table{
+		Columns: models.DiagnosticRecordColumns,
+		Records: records,
+	}, writer)
+}
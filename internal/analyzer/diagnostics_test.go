@@ -0,0 +1,191 @@
+package analyzer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/output"
+)
+
+// newEmptyDiagnostics returns the diagnostics of an analysis without unmatched or unparseable lines
+func newEmptyDiagnostics() *models.Diagnostics {
+	return &models.Diagnostics{
+		ParseFailures: map[string]*models.DiagnosticCount{},
+	}
+}
+
+func TestParseFailureReason(t *testing.T) {
+	tests := []struct {
+		name     string
+		logLine  string
+		expected string
+	}{
+		{
+			name:     "empty line",
+			logLine:  "   ",
+			expected: "",
+		},
+		{
+			name:     "unrecognized format",
+			logLine:  `Started GET "/users/1" for ::1 at 2023-01-01 00:00:00 +0000`,
+			expected: ParseFailureUnrecognizedFormat,
+		},
+		{
+			name:     "invalid timestamp",
+			logLine:  `Started GET "/users/1" for 127.0.0.1 at 2023-01-01T00:00:00Z`,
+			expected: ParseFailureInvalidTimestamp,
+		},
+		{
+			name:     "invalid number",
+			logLine:  `Completed 200 OK in 99999999999999999999ms`,
+			expected: ParseFailureInvalidNumber,
+		},
+	}
+
+	parser := NewParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parser.ParseLogEntry(tt.logLine)
+			require.Error(t, err)
+			assert.Equal(t, tt.expected, parseFailureReason(err))
+		})
+	}
+}
+
+func TestStreamAggregator_Diagnostics(t *testing.T) {
+	base := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	started := func(path, sessionID, processID string, offset time.Duration) *models.LogEntry {
+		return &models.LogEntry{Type: "Started", Method: "GET", Path: path, Timestamp: base.Add(offset), SessionID: sessionID, ProcessID: processID}
+	}
+	completed := func(sessionID, processID string) *models.LogEntry {
+		return &models.LogEntry{Type: "Completed", StatusCode: 200, StatusText: "OK", Duration: 100, SessionID: sessionID, ProcessID: processID}
+	}
+
+	aggregator := NewAggregator()
+	aggregator.diagnosticSamples = 1
+	stream := NewStreamAggregator(aggregator, NewNormalizer(), 0)
+
+	entries := []*models.LogEntry{
+		started("/users/1", "a", "", 0),
+		completed("a", ""),
+		// Completed without Started
+		completed("b", ""),
+		completed("c", ""),
+		// Request ID logged twice, only the later request is matched
+		started("/users/2", "d", "", time.Second),
+		started("/users/3", "d", "", 2*time.Second),
+		completed("d", ""),
+		// Untagged requests of the same process in flight together, only the older one is matched
+		started("/users/4", "", "100", 3*time.Second),
+		started("/users/5", "", "100", 4*time.Second),
+		completed("", "100"),
+		// Never completed
+		started("/users/6", "e", "", 6*time.Second),
+		started("/users/7", "f", "", 5*time.Second),
+	}
+	for _, entry := range entries {
+		stream.Add(entry, time.Time{})
+	}
+	stream.ParseFailure(errInvalidTimestamp, `Started GET "/" for 127.0.0.1 at yesterday`)
+	stream.ParseFailure(errUnrecognizedFormat, `SELECT 1`)
+	stream.ParseFailure(errEmptyLine, ``)
+
+	result := stream.Result(time.Time{}, time.Time{})
+
+	assert.Equal(t, map[string]int{models.MatchByRequestID: 2, models.MatchByLogOrderAmbiguous: 1}, result.MatchedBy)
+	assert.Equal(t, &models.Diagnostics{
+		ParseFailures: map[string]*models.DiagnosticCount{
+			ParseFailureInvalidTimestamp: {Count: 1, Samples: []string{`Started GET "/" for 127.0.0.1 at yesterday`}},
+		},
+		// The replaced Started entry of the duplicated request ID and the unmatched untagged request are orphaned too
+		OrphanedStarted: models.DiagnosticCount{
+			Count:   4,
+			Samples: []string{`Started GET "/users/2" at 2023-01-01T00:00:01Z [d]`},
+		},
+		OrphanedCompleted: models.DiagnosticCount{
+			Count:   2,
+			Samples: []string{`Completed 200 OK in 100ms [b]`},
+		},
+		DuplicateRequestIDs: models.DiagnosticCount{
+			Count:   1,
+			Samples: []string{`Started GET "/users/3" at 2023-01-01T00:00:02Z [d]`},
+		},
+	}, result.Diagnostics)
+}
+
+func TestStreamAggregator_DiagnosticsReportEvictedAsOrphaned(t *testing.T) {
+	base := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	stream := NewStreamAggregator(NewAggregator(), NewNormalizer(), time.Minute)
+
+	stream.Add(&models.LogEntry{Type: "Started", Method: "GET", Path: "/a", Timestamp: base, SessionID: "a"}, base)
+	stream.Add(&models.LogEntry{Type: "Started", Method: "GET", Path: "/b", Timestamp: base, SessionID: "b"}, base.Add(2*time.Minute))
+	stream.Add(&models.LogEntry{Type: "Started", Method: "GET", Path: "/c", Timestamp: base, SessionID: "c"}, base.Add(4*time.Minute))
+
+	assert.Equal(t, 2, stream.Evicted())
+	assert.Equal(t, 1, stream.Pending())
+
+	result := stream.Result(time.Time{}, time.Time{})
+	assert.Equal(t, 3, result.Diagnostics.OrphanedStarted.Count)
+	assert.Empty(t, result.Diagnostics.OrphanedStarted.Samples)
+}
+
+func TestAnalyzer_AnalyzeLogEventsParseFailures(t *testing.T) {
+	analyzer := NewAnalyzer()
+	require.NoError(t, analyzer.SetDiagnosticSamples(5))
+
+	result := analyzer.AnalyzeLogEvents([]*models.LogEvent{
+		{Message: `Started GET "/users/1" for ::1 at 2023-01-01 00:00:00 +0000 [a]`},
+		{Message: `Completed 200 OK in 10ms [a]`},
+		{Message: `  Rendered users/show.html.erb (Duration: 1.0ms)`},
+		{Message: `Started GET "/users/2" for 127.0.0.1 at 2023-01-01 [b]`},
+		{Message: ``},
+	}, time.Time{}, time.Time{})
+
+	assert.Equal(t, map[string]*models.DiagnosticCount{
+		ParseFailureUnrecognizedFormat: {Count: 1, Samples: []string{`Started GET "/users/1" for ::1 at 2023-01-01 00:00:00 +0000 [a]`}},
+		ParseFailureInvalidTimestamp:   {Count: 1, Samples: []string{`Started GET "/users/2" for 127.0.0.1 at 2023-01-01 [b]`}},
+	}, result.Diagnostics.ParseFailures)
+	assert.Equal(t, 1, result.Diagnostics.OrphanedCompleted.Count)
+	assert.Empty(t, result.PathMetrics)
+}
+
+func TestAnalyzer_SetDiagnosticSamples(t *testing.T) {
+	analyzer := NewAnalyzer()
+
+	require.NoError(t, analyzer.SetDiagnosticSamples(3))
+	assert.Equal(t, 3, analyzer.aggregator.diagnosticSamples)
+
+	assert.ErrorContains(t, analyzer.SetDiagnosticSamples(-1), "must not be negative")
+	assert.Equal(t, 3, analyzer.aggregator.diagnosticSamples)
+}
+
+func TestAnalyzer_OutputDiagnostics(t *testing.T) {
+	result := &models.AnalysisResult{
+		Diagnostics: &models.Diagnostics{
+			ParseFailures: map[string]*models.DiagnosticCount{
+				ParseFailureInvalidTimestamp:   {Count: 1},
+				ParseFailureUnrecognizedFormat: {Count: 2, Samples: []string{"Started x", "Completed y"}},
+			},
+			OrphanedStarted: models.DiagnosticCount{Count: 3},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, NewAnalyzer().OutputDiagnostics(result, output.NewCSVFormatter(), &buf))
+	assert.Equal(t, `diagnostic,reason,count,samples
+parse_failure,invalid_timestamp,1,
+parse_failure,unrecognized_format,2,Started x | Completed y
+orphaned_started,,3,
+orphaned_completed,,0,
+duplicate_request_id,,0,
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, NewAnalyzer().OutputDiagnostics(&models.AnalysisResult{}, output.NewCSVFormatter(), &buf))
+	assert.Equal(t, "diagnostic,reason,count,samples\n", buf.String())
+}
@@ -0,0 +1,160 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+// Log formats supported by NewLineParser
+const (
+	LogFormatRails   = "rails"   // Rails Started, Processing and Completed lines
+	LogFormatLograge = "lograge" // One key=value line per request
+	LogFormatJSON    = "json"    // One JSON object per request, e.g. lograge or rails_semantic_logger JSON
+	LogFormatAuto    = "auto"    // Detects the format of each line
+)
+
+// LineParser parses a single log line into a log entry
+type LineParser interface {
+	ParseLogEntry(logLine string) (*models.LogEntry, error)
+}
+
+// LogFormats returns the names of all supported log formats
+func LogFormats() []string {
+	return []string{LogFormatRails, LogFormatLograge, LogFormatJSON, LogFormatAuto}
+}
+
+// NewLineParser creates the parser of a log format
+func NewLineParser(format string) (LineParser, error) {
+	switch format {
+	case LogFormatRails:
+		return NewParser(), nil
+	case LogFormatLograge:
+		return NewLogrageParser(), nil
+	case LogFormatJSON:
+		return NewJSONParser(), nil
+	case LogFormatAuto:
+		return NewAutoParser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported log format: %s (supported: %s)", format, strings.Join(LogFormats(), ", "))
+	}
+}
+
+// AutoParser detects whether each line is JSON, lograge or Rails text and parses it accordingly
+type AutoParser struct {
+	rails   *Parser
+	lograge *LogrageParser
+	json    *JSONParser
+}
+
+// NewAutoParser creates a new AutoParser instance
+func NewAutoParser() *AutoParser {
+	return &AutoParser{
+		rails:   NewParser(),
+		lograge: NewLogrageParser(),
+		json:    NewJSONParser(),
+	}
+}
+
+// ParseLogEntry parses a log line with the parser of its detected format
+func (p *AutoParser) ParseLogEntry(logLine string) (*models.LogEntry, error) {
+	switch {
+	case isJSONLine(logLine):
+		return p.json.ParseLogEntry(logLine)
+	case isLogrageLine(logLine):
+		return p.lograge.ParseLogEntry(logLine)
+	default:
+		return p.rails.ParseLogEntry(logLine)
+	}
+}
+
+// Keys accepted for each request field of structured logs, in order of preference
+var (
+	methodKeys     = []string{"method", "http_method", "request_method"}
+	pathKeys       = []string{"path", "request_path", "fullpath"}
+	statusKeys     = []string{"status", "status_code", "http_status"}
+	durationKeys   = []string{"duration_ms", "duration", "total_time"}
+	viewKeys       = []string{"view_runtime", "view", "view_ms"}
+	dbKeys         = []string{"db_runtime", "db", "db_ms"}
+	requestIDKeys  = []string{"request_id", "requestId", "uuid"}
+	timestampKeys  = []string{"timestamp", "time", "@timestamp"}
+	controllerKeys = []string{"controller"}
+	actionKeys     = []string{"action"}
+	formatKeys     = []string{"format"}
+)
+
+// fieldLookup returns the value of the first of keys present in a structured log line
+type fieldLookup func(keys []string) (string, bool)
+
+// timestampParser parses the timestamp field of a log line
+type timestampParser func(value string) (time.Time, error)
+
+// newRequestEntry builds a complete request entry from the fields of a structured log line
+// Method, path, status and duration are required; the other fields are optional
+func newRequestEntry(lookup fieldLookup, parseTimestamp timestampParser, logLine string) (*models.LogEntry, error) {
+	method, hasMethod := lookup(methodKeys)
+	path, hasPath := lookup(pathKeys)
+	status, hasStatus := lookup(statusKeys)
+	duration, hasDuration := lookup(durationKeys)
+	if !hasMethod || !hasPath || !hasStatus || !hasDuration {
+		return nil, fmt.Errorf("%w: missing method, path, status or duration: %s", errUnrecognizedFormat, logLine)
+	}
+
+	statusCode, err := strconv.Atoi(status)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid status code: %w", errInvalidNumber, err)
+	}
+	durationMs, err := parseMilliseconds(duration)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid duration: %w", errInvalidNumber, err)
+	}
+
+	entry := &models.LogEntry{
+		Type:       "Request",
+		Method:     strings.ToUpper(method),
+		Path:       path,
+		StatusCode: statusCode,
+		StatusText: http.StatusText(statusCode),
+		Duration:   int(math.Round(durationMs)),
+	}
+
+	// View and DB durations are optional, so a missing or invalid value is left at zero
+	if view, ok := lookup(viewKeys); ok {
+		entry.ViewDuration, _ = parseMilliseconds(view)
+	}
+	if db, ok := lookup(dbKeys); ok {
+		entry.DBDuration, _ = parseMilliseconds(db)
+	}
+
+	if timestamp, ok := lookup(timestampKeys); ok {
+		entry.Timestamp, err = parseTimestamp(timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidTimestamp, err)
+		}
+	}
+
+	entry.SessionID, _ = lookup(requestIDKeys)
+	entry.Controller, _ = lookup(controllerKeys)
+	entry.Action, _ = lookup(actionKeys)
+	entry.Format, _ = lookup(formatKeys)
+
+	return entry, nil
+}
+
+// parseStructuredTimestamp parses an RFC3339 timestamp, or one in the Rails log format
+func parseStructuredTimestamp(value string) (time.Time, error) {
+	if timestamp, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return timestamp, nil
+	}
+	return time.Parse("2006-01-02 15:04:05 -0700", value)
+}
+
+// parseMilliseconds parses a duration in milliseconds, with or without an "ms" suffix
+func parseMilliseconds(value string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSuffix(value, "ms"), 64)
+}
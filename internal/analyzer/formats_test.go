@@ -0,0 +1,67 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+func TestNewLineParser(t *testing.T) {
+	for _, format := range LogFormats() {
+		t.Run(format, func(t *testing.T) {
+			parser, err := NewLineParser(format)
+			require.NoError(t, err)
+			assert.NotNil(t, parser)
+		})
+	}
+
+	_, err := NewLineParser("syslog")
+	assert.ErrorContains(t, err, "unsupported log format: syslog")
+}
+
+func TestAutoParser_ParseLogEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantType string
+	}{
+		{name: "Rails Started", input: `Started GET "/users/1" for 127.0.0.1 at 2023-01-01 12:00:00 +0900`, wantType: "Started"},
+		{name: "Rails Processing", input: `Processing by UsersController#show as HTML`, wantType: "Processing"},
+		{name: "lograge", input: `method=GET path=/users/1 status=200 duration=1.5`, wantType: "Request"},
+		{name: "JSON", input: `{"method":"GET","path":"/users/1","status":200,"duration":1.5}`, wantType: "Request"},
+	}
+
+	parser := NewAutoParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.ParseLogEntry(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantType, got.Type)
+		})
+	}
+}
+
+func TestAnalyzer_StructuredLogFormat(t *testing.T) {
+	analyzer := NewAnalyzer()
+	require.NoError(t, analyzer.SetLogFormat(LogFormatAuto))
+	assert.Error(t, analyzer.SetLogFormat("syslog"))
+
+	result := analyzer.AnalyzeLogEvents([]*models.LogEvent{
+		{Message: `method=GET path=/users/1 status=200 duration=100.0 db=10.0`},
+		{Message: `{"method":"GET","path":"/users/2","status":500,"duration":300}`},
+		{Message: `Started GET "/users/3" for 127.0.0.1 at 2023-01-01 12:00:00 +0900 [a]`},
+		{Message: `Completed 200 OK in 200ms [a]`},
+	}, time.Time{}, time.Time{})
+
+	assert.Equal(t, map[string]int{models.MatchByRecord: 2, models.MatchByRequestID: 1}, result.MatchedBy)
+	metrics := result.PathMetrics["/users/:id"]
+	require.NotNil(t, metrics)
+	assert.Equal(t, 3, metrics.Count)
+	assert.Equal(t, 200.0, metrics.AverageTime)
+	assert.Equal(t, map[int]int{200: 2, 500: 1}, metrics.StatusCodes)
+	assert.Equal(t, 10.0, metrics.TotalDBDuration)
+}
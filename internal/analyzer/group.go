@@ -0,0 +1,166 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/output"
+)
+
+// Dimensions that split the metrics of a path further
+const (
+	GroupByMethod = "method" // One row per HTTP method and path, e.g. GET /users/:id
+	GroupByStream = "stream" // One row per path and CloudWatch log stream
+)
+
+// Keys the metrics of a request are aggregated by
+const (
+	AggregateByPath   = "path"   // Normalized request path
+	AggregateByAction = "action" // Controller#action from the Processing log line
+)
+
+// GroupOptions controls which dimensions besides the path the metrics are grouped by
+type GroupOptions struct {
+	Method    bool // Group by HTTP method, so GET and DELETE requests to a path are not blended
+	LogStream bool // Group by CloudWatch log stream, e.g. to compare containers or tasks
+	Action    bool // Aggregate by Controller#action instead of the normalized path
+}
+
+// GroupByKeys returns the names of all supported grouping dimensions in alphabetical order
+func GroupByKeys() []string {
+	return []string{GroupByMethod, GroupByStream}
+}
+
+// ParseGroupBy parses a comma separated list of grouping dimensions; an empty value groups by path only
+func ParseGroupBy(value string) (GroupOptions, error) {
+	var opts GroupOptions
+	if value == "" {
+		return opts, nil
+	}
+
+	for _, dimension := range strings.Split(value, ",") {
+		switch strings.TrimSpace(dimension) {
+		case GroupByMethod:
+			opts.Method = true
+		case GroupByStream:
+			opts.LogStream = true
+		default:
+			return GroupOptions{}, fmt.Errorf("unsupported group by dimension %q (supported: %s)", dimension, strings.Join(GroupByKeys(), ", "))
+		}
+	}
+
+	return opts, nil
+}
+
+// groupBy returns the grouping dimensions in the form accepted by ParseGroupBy, empty when grouped by path only
+func (o GroupOptions) groupBy() string {
+	var dimensions []string
+	if o.Method {
+		dimensions = append(dimensions, GroupByMethod)
+	}
+	if o.LogStream {
+		dimensions = append(dimensions, GroupByStream)
+	}
+	return strings.Join(dimensions, ",")
+}
+
+// aggregateBy returns the aggregation key in the form accepted by ParseAggregateBy, empty for the path
+func (o GroupOptions) aggregateBy() string {
+	if o.Action {
+		return AggregateByAction
+	}
+	return ""
+}
+
+// AggregateByKeys returns the names of all supported aggregation keys
+func AggregateByKeys() []string {
+	return []string{AggregateByPath, AggregateByAction}
+}
+
+// ParseAggregateBy parses the key metrics are aggregated by and reports whether it is the controller action
+func ParseAggregateBy(value string) (bool, error) {
+	switch value {
+	case "", AggregateByPath:
+		return false, nil
+	case AggregateByAction:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported aggregate by key %q (supported: %s)", value, strings.Join(AggregateByKeys(), ", "))
+	}
+}
+
+// aggregationPath returns the value a request is aggregated by: its controller action when aggregating by action,
+// otherwise its normalized path
+// Requests without a Processing log line, such as routing errors, fall back to the normalized path
+func (a *Aggregator) aggregationPath(entry *models.LogEntry, normalizer *Normalizer) string {
+	if a.groupOptions.Action && entry.Controller != "" {
+		return entry.Controller + "#" + entry.Action
+	}
+	return normalizer.NormalizePath(entry.Method, entry.Path)
+}
+
+// metricsKey returns the key of the metrics a request with the method, normalized path and log stream is aggregated
+// into, e.g. "GET /users/:id task-1"
+// Methods and paths never contain spaces, so the key stays unambiguous
+func (o GroupOptions) metricsKey(method, path, logStream string) string {
+	key := path
+	if o.Method && method != "" {
+		key = method + " " + key
+	}
+	if o.LogStream && logStream != "" {
+		key += " " + logStream
+	}
+	return key
+}
+
+// groupRecord adds the method column before and the log stream column after the path column of a metrics record
+type groupRecord struct {
+	record    output.Record
+	opts      GroupOptions
+	method    string
+	logStream string
+}
+
+// Values returns the values of the wrapped record with the grouping dimensions inserted around the path
+func (r *groupRecord) Values() []string {
+	values := r.record.Values()
+	grouped := make([]string, 0, len(values)+2)
+	if r.opts.Method {
+		grouped = append(grouped, r.method)
+	}
+	grouped = append(grouped, values[0])
+	if r.opts.LogStream {
+		grouped = append(grouped, r.logStream)
+	}
+	return append(grouped, values[1:]...)
+}
+
+// MarshalJSON encodes the wrapped record, which carries the method and log stream fields itself
+func (r *groupRecord) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.record)
+}
+
+// metricsRecord returns the output record of metrics, with method and log stream columns when grouping by them
+func (a *Analyzer) metricsRecord(record output.Record, method, logStream string) output.Record {
+	opts := a.aggregator.groupOptions
+	if !opts.Method && !opts.LogStream {
+		return record
+	}
+	return &groupRecord{record: record, opts: opts, method: method, logStream: logStream}
+}
+
+// metricsColumns returns the output columns of metrics, with method and log stream columns when grouping by them
+func (a *Analyzer) metricsColumns(columns []string) []string {
+	opts := a.aggregator.groupOptions
+	grouped := make([]string, 0, len(columns)+2)
+	if opts.Method {
+		grouped = append(grouped, models.MethodColumn)
+	}
+	grouped = append(grouped, columns[0])
+	if opts.LogStream {
+		grouped = append(grouped, models.LogStreamColumn)
+	}
+	return append(grouped, columns[1:]...)
+}
@@ -0,0 +1,258 @@
+package analyzer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/output"
+)
+
+func TestParseGroupBy(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    GroupOptions
+		expectError bool
+	}{
+		{
+			name:     "path only",
+			value:    "",
+			expected: GroupOptions{},
+		},
+		{
+			name:     "log stream",
+			value:    "stream",
+			expected: GroupOptions{LogStream: true},
+		},
+		{
+			name:     "surrounding spaces",
+			value:    " stream ",
+			expected: GroupOptions{LogStream: true},
+		},
+		{
+			name:     "method",
+			value:    "method",
+			expected: GroupOptions{Method: true},
+		},
+		{
+			name:     "method and log stream",
+			value:    "method,stream",
+			expected: GroupOptions{Method: true, LogStream: true},
+		},
+		{
+			name:        "unsupported dimension",
+			value:       "stream,host",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := ParseGroupBy(tt.value)
+			if tt.expectError {
+				assert.ErrorContains(t, err, "unsupported group by dimension")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, opts)
+		})
+	}
+}
+
+func TestParseAggregateBy(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    bool
+		expectError bool
+	}{
+		{name: "default", value: "", expected: false},
+		{name: "path", value: "path", expected: false},
+		{name: "action", value: "action", expected: true},
+		{name: "unsupported key", value: "controller", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, err := ParseAggregateBy(tt.value)
+			if tt.expectError {
+				assert.ErrorContains(t, err, "unsupported aggregate by key")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, action)
+		})
+	}
+}
+
+func TestAnalyzer_AggregateByAction(t *testing.T) {
+	events := []*models.LogEvent{
+		{Message: `Started GET "/users/1" for 127.0.0.1 at 2023-01-01 00:00:00 +0000 [a]`},
+		{Message: `[a] Processing by UsersController#show as HTML`},
+		{Message: `Completed 200 OK in 100ms [a]`},
+		{Message: `Started GET "/users/me" for 127.0.0.1 at 2023-01-01 00:00:01 +0000 [b]`},
+		{Message: `[b] Processing by UsersController#show as JSON`},
+		{Message: `Completed 200 OK in 300ms [b]`},
+		// Routing errors never reach a controller
+		{Message: `Started GET "/missing" for 127.0.0.1 at 2023-01-01 00:00:02 +0000 [c]`},
+		{Message: `Completed 404 Not Found in 5ms [c]`},
+	}
+
+	analyzer := NewAnalyzer()
+	analyzer.SetGroupOptions(GroupOptions{Action: true})
+	result := analyzer.AnalyzeLogEvents(events, time.Time{}, time.Time{})
+
+	require.Len(t, result.PathMetrics, 2)
+	action := result.PathMetrics["UsersController#show"]
+	require.NotNil(t, action)
+	assert.Equal(t, "UsersController#show", action.Path)
+	assert.Equal(t, 2, action.Count)
+	assert.Equal(t, 200.0, action.AverageTime)
+	require.NotNil(t, result.PathMetrics["/missing"])
+
+	// Without the option the Processing lines are only attached to their requests
+	analyzer = NewAnalyzer()
+	result = analyzer.AnalyzeLogEvents(events, time.Time{}, time.Time{})
+	assert.Len(t, result.PathMetrics, 3)
+	assert.Equal(t, 8, result.TotalLogs)
+}
+
+func newStreamTestEvents() []*models.LogEvent {
+	base := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []*models.LogEvent{
+		{Message: `Started GET "/users/1" for 127.0.0.1 at 2023-01-01 00:00:00 +0000 [a]`, Timestamp: base, LogStream: "web/app/task-1"},
+		{Message: `Completed 200 OK in 100ms (Views: 1.0ms | ActiveRecord: 1.0ms) [a]`, Timestamp: base, LogStream: "web/app/task-1"},
+		{Message: `Started GET "/users/2" for 127.0.0.1 at 2023-01-01 00:00:01 +0000 [b]`, Timestamp: base, LogStream: "web/app/task-2"},
+		{Message: `Completed 200 OK in 900ms (Views: 1.0ms | ActiveRecord: 1.0ms) [b]`, Timestamp: base, LogStream: "web/app/task-2"},
+		{Message: `Started GET "/users/3" for 127.0.0.1 at 2023-01-01 00:00:02 +0000 [c]`, Timestamp: base, LogStream: "web/app/task-1"},
+		{Message: `Completed 200 OK in 200ms (Views: 1.0ms | ActiveRecord: 1.0ms) [c]`, Timestamp: base, LogStream: "web/app/task-1"},
+	}
+}
+
+func TestAnalyzer_GroupByLogStream(t *testing.T) {
+	analyzer := NewAnalyzer()
+	analyzer.SetGroupOptions(GroupOptions{LogStream: true})
+
+	result := analyzer.AnalyzeLogEvents(newStreamTestEvents(), time.Time{}, time.Time{})
+
+	require.Len(t, result.PathMetrics, 2)
+	task1 := result.PathMetrics["/users/:id web/app/task-1"]
+	require.NotNil(t, task1)
+	assert.Equal(t, "/users/:id", task1.Path)
+	assert.Equal(t, "web/app/task-1", task1.LogStream)
+	assert.Equal(t, 2, task1.Count)
+	assert.Equal(t, 150.0, task1.AverageTime)
+
+	task2 := result.PathMetrics["/users/:id web/app/task-2"]
+	require.NotNil(t, task2)
+	assert.Equal(t, 1, task2.Count)
+	assert.Equal(t, 900, task2.MaxTime)
+
+	var buf bytes.Buffer
+	require.NoError(t, analyzer.Output(result, output.NewCSVFormatter(), &buf))
+	assert.Equal(t, `path,log_stream,count,max_time_ms,min_time_ms,avg_time_ms,p50_time_ms,p90_time_ms,p95_time_ms,p99_time_ms
+/users/:id,web/app/task-1,2,200,100,150,100,200,200,200
+/users/:id,web/app/task-2,1,900,900,900,900,900,900,900
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, analyzer.Output(result, output.NewNDJSONFormatter(), &buf))
+	assert.Contains(t, buf.String(), `{"path":"/users/:id","log_stream":"web/app/task-2","count":1,`)
+}
+
+func TestAnalyzer_WithoutGroupByLogStream(t *testing.T) {
+	analyzer := NewAnalyzer()
+
+	result := analyzer.AnalyzeLogEvents(newStreamTestEvents(), time.Time{}, time.Time{})
+
+	require.Len(t, result.PathMetrics, 1)
+	metrics := result.PathMetrics["/users/:id"]
+	require.NotNil(t, metrics)
+	assert.Equal(t, 3, metrics.Count)
+	assert.Empty(t, metrics.LogStream)
+
+	var buf bytes.Buffer
+	require.NoError(t, analyzer.OutputDetailed(result, output.NewCSVFormatter(), &buf))
+	assert.NotContains(t, buf.String(), "log_stream")
+}
+
+// newMethodTestEvents returns GET and DELETE requests to the same user path on two log streams
+func newMethodTestEvents() []*models.LogEvent {
+	var events []*models.LogEvent
+	for i, request := range []struct {
+		method    string
+		path      string
+		duration  int
+		logStream string
+	}{
+		{"GET", "/users/1", 20, "task-1"},
+		{"GET", "/users/2", 40, "task-2"},
+		{"DELETE", "/users/3", 900, "task-1"},
+	} {
+		for _, event := range requestEvents(i, request.method, request.path, request.duration) {
+			event.LogStream = request.logStream
+			events = append(events, event)
+		}
+	}
+	// Never completed, so only counted in the incomplete metrics
+	events = append(events, &models.LogEvent{Message: `Started PATCH "/users/4" for 127.0.0.1 at 2023-01-01 00:00:00 +0000 [x]`})
+	return events
+}
+
+func TestAnalyzer_GroupByMethod(t *testing.T) {
+	analyzer := NewAnalyzer()
+	analyzer.SetGroupOptions(GroupOptions{Method: true})
+
+	result := analyzer.AnalyzeLogEvents(newMethodTestEvents(), time.Time{}, time.Time{})
+
+	require.Len(t, result.PathMetrics, 2)
+	get := result.PathMetrics["GET /users/:id"]
+	require.NotNil(t, get)
+	assert.Equal(t, "GET", get.Method)
+	assert.Equal(t, "/users/:id", get.Path)
+	assert.Equal(t, 2, get.Count)
+	assert.Equal(t, 30.0, get.AverageTime)
+
+	remove := result.PathMetrics["DELETE /users/:id"]
+	require.NotNil(t, remove)
+	assert.Equal(t, 1, remove.Count)
+	assert.Equal(t, 900, remove.MaxTime)
+
+	require.Contains(t, result.Incomplete, "PATCH /users/:id")
+	assert.Equal(t, "PATCH", result.Incomplete["PATCH /users/:id"].Method)
+
+	var buf bytes.Buffer
+	require.NoError(t, analyzer.Output(result, output.NewCSVFormatter(), &buf))
+	assert.Equal(t, `method,path,count,max_time_ms,min_time_ms,avg_time_ms,p50_time_ms,p90_time_ms,p95_time_ms,p99_time_ms
+GET,/users/:id,2,40,20,30,20,40,40,40
+DELETE,/users/:id,1,900,900,900,900,900,900,900
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, analyzer.Output(result, output.NewNDJSONFormatter(), &buf))
+	assert.Contains(t, buf.String(), `{"method":"DELETE","path":"/users/:id","count":1,`)
+}
+
+func TestAnalyzer_GroupByMethodAndLogStream(t *testing.T) {
+	analyzer := NewAnalyzer()
+	analyzer.SetGroupOptions(GroupOptions{Method: true, LogStream: true})
+
+	result := analyzer.AnalyzeLogEvents(newMethodTestEvents(), time.Time{}, time.Time{})
+
+	assert.Len(t, result.PathMetrics, 3)
+	assert.Equal(t, "method,stream", result.GroupBy)
+	assert.Empty(t, result.AggregateBy)
+	assert.Contains(t, result.PathMetrics, "GET /users/:id task-1")
+	assert.Contains(t, result.PathMetrics, "GET /users/:id task-2")
+	assert.Contains(t, result.PathMetrics, "DELETE /users/:id task-1")
+
+	var buf bytes.Buffer
+	require.NoError(t, analyzer.OutputIncomplete(result, output.NewCSVFormatter(), &buf))
+	assert.Contains(t, buf.String(), "method,path,log_stream,count,")
+	assert.Contains(t, buf.String(), "PATCH,/users/:id,,1,")
+}
@@ -0,0 +1,66 @@
+package analyzer
+
+import (
+	"io"
+	"sort"
+	"time"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/output"
+)
+
+// finalizeIncomplete orders the incomplete requests of each path and calculates their age at windowEnd
+func finalizeIncomplete(incomplete map[string]*models.IncompleteMetrics, windowEnd time.Time) {
+	for _, metrics := range incomplete {
+		sort.SliceStable(metrics.Requests, func(i, j int) bool {
+			return metrics.Requests[i].StartedAt.Before(metrics.Requests[j].StartedAt)
+		})
+
+		metrics.MaxAgeMs = 0
+		for _, request := range metrics.Requests {
+			request.AgeMs = max(windowEnd.Sub(request.StartedAt).Milliseconds(), 0)
+			metrics.MaxAgeMs = max(metrics.MaxAgeMs, request.AgeMs)
+		}
+		if len(metrics.Requests) > 0 {
+			metrics.OldestStartedAt = metrics.Requests[0].StartedAt
+		}
+	}
+}
+
+// SortIncomplete returns the incomplete metrics ordered by count descending, then by path, method and log stream
+func SortIncomplete(incomplete map[string]*models.IncompleteMetrics) []*models.IncompleteMetrics {
+	sorted := make([]*models.IncompleteMetrics, 0, len(incomplete))
+	for _, metrics := range incomplete {
+		sorted = append(sorted, metrics)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		if sorted[i].Path != sorted[j].Path {
+			return sorted[i].Path < sorted[j].Path
+		}
+		if sorted[i].Method != sorted[j].Method {
+			return sorted[i].Method < sorted[j].Method
+		}
+		return sorted[i].LogStream < sorted[j].LogStream
+	})
+
+	return sorted
+}
+
+// OutputIncomplete writes the requests that never completed, per path, using the given formatter
+func (a *Analyzer) OutputIncomplete(result *models.AnalysisResult, formatter output.Formatter, writer io.Writer) error {
+	sorted := SortIncomplete(result.Incomplete)
+
+	records := make([]output.Record, 0, len(sorted))
+	for _, metrics := range sorted {
+		records = append(records, a.metricsRecord(metrics, metrics.Method, metrics.LogStream))
+	}
+
+	return formatter.Format(&output.Table{
+		Columns: a.metricsColumns(models.IncompleteMetricsColumns),
+		Records: records,
+	}, writer)
+}
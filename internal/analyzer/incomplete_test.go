@@ -0,0 +1,111 @@
+package analyzer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/output"
+)
+
+func TestAnalyzer_IncompleteRequests(t *testing.T) {
+	analyzer := NewAnalyzer()
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	endTime := time.Date(2023, 1, 1, 0, 10, 0, 0, time.UTC)
+
+	result := analyzer.AnalyzeLogEvents([]*models.LogEvent{
+		{Message: `Started GET "/users/1" for 127.0.0.1 at 2023-01-01 00:05:00 +0000 [a]`},
+		{Message: `Started POST "/users/2" for 127.0.0.1 at 2023-01-01 00:01:00 +0000 [b]`},
+		{Message: `Started GET "/users/3" for 127.0.0.1 at 2023-01-01 00:02:00 +0000 [c]`},
+		{Message: `Completed 200 OK in 100ms [c]`},
+		{Message: `Started GET "/rails/active_storage/blobs/1" for 127.0.0.1 at 2023-01-01 00:03:00 +0000 [d]`},
+		{Message: `Started GET "/orders" for 127.0.0.1 at 2023-01-01 00:09:00 +0000 [e]`},
+	}, startTime, endTime)
+
+	assert.Equal(t, map[string]*models.IncompleteMetrics{
+		"/users/:id": {
+			Path:            "/users/:id",
+			Count:           2,
+			OldestStartedAt: time.Date(2023, 1, 1, 0, 1, 0, 0, time.UTC),
+			MaxAgeMs:        540000,
+			Requests: []*models.IncompleteRequest{
+				{Method: "POST", StartedAt: time.Date(2023, 1, 1, 0, 1, 0, 0, time.UTC), AgeMs: 540000, RequestID: "b"},
+				{Method: "GET", StartedAt: time.Date(2023, 1, 1, 0, 5, 0, 0, time.UTC), AgeMs: 300000, RequestID: "a"},
+			},
+		},
+		"/orders": {
+			Path:            "/orders",
+			Count:           1,
+			OldestStartedAt: time.Date(2023, 1, 1, 0, 9, 0, 0, time.UTC),
+			MaxAgeMs:        60000,
+			Requests: []*models.IncompleteRequest{
+				{Method: "GET", StartedAt: time.Date(2023, 1, 1, 0, 9, 0, 0, time.UTC), AgeMs: 60000, RequestID: "e"},
+			},
+		},
+	}, result.Incomplete)
+	assert.Equal(t, 1, result.PathMetrics["/users/:id"].Count)
+
+	var buf bytes.Buffer
+	require.NoError(t, analyzer.OutputIncomplete(result, output.NewCSVFormatter(), &buf))
+	assert.Equal(t, `path,count,oldest_started_at,max_age_ms,requests
+/users/:id,2,2023-01-01T00:01:00Z,540000,"POST 2023-01-01T00:01:00Z (540000ms), GET 2023-01-01T00:05:00Z (300000ms)"
+/orders,1,2023-01-01T00:09:00Z,60000,GET 2023-01-01T00:09:00Z (60000ms)
+`, buf.String())
+}
+
+func TestAnalyzer_IncompleteRequestsWithoutWindowEnd(t *testing.T) {
+	analyzer := NewAnalyzer()
+	analyzer.SetGroupOptions(GroupOptions{LogStream: true})
+
+	result := analyzer.AnalyzeLogEvents([]*models.LogEvent{
+		{Message: `Started GET "/a" for 127.0.0.1 at 2023-01-01 00:00:00 +0000 [a]`, LogStream: "task-1"},
+		{Message: `Started GET "/b" for 127.0.0.1 at 2023-01-01 00:00:30 +0000 [b]`, LogStream: "task-2"},
+	}, time.Time{}, time.Time{})
+
+	// Ages are measured at the latest Started entry
+	require.Contains(t, result.Incomplete, "/a task-1")
+	assert.Equal(t, "task-1", result.Incomplete["/a task-1"].LogStream)
+	assert.Equal(t, int64(30000), result.Incomplete["/a task-1"].MaxAgeMs)
+	assert.Equal(t, int64(0), result.Incomplete["/b task-2"].MaxAgeMs)
+
+	var buf bytes.Buffer
+	require.NoError(t, analyzer.OutputIncomplete(result, output.NewCSVFormatter(), &buf))
+	assert.Equal(t, `path,log_stream,count,oldest_started_at,max_age_ms,requests
+/a,task-1,1,2023-01-01T00:00:00Z,30000,GET 2023-01-01T00:00:00Z (30000ms)
+/b,task-2,1,2023-01-01T00:00:30Z,0,GET 2023-01-01T00:00:30Z (0ms)
+`, buf.String())
+}
+
+func TestStreamAggregator_EvictedRequestsAreIncomplete(t *testing.T) {
+	base := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	stream := NewStreamAggregator(NewAggregator(), NewNormalizer(), time.Minute)
+
+	stream.Add(&models.LogEntry{Type: "Started", Method: "GET", Path: "/slow", Timestamp: base, SessionID: "a"}, base)
+	stream.Add(&models.LogEntry{Type: "Started", Method: "GET", Path: "/fast", Timestamp: base.Add(2 * time.Minute), SessionID: "b"}, base.Add(2*time.Minute))
+	stream.Add(&models.LogEntry{Type: "Completed", StatusCode: 200, Duration: 10, SessionID: "b"}, base.Add(2*time.Minute))
+
+	result := stream.Result(time.Time{}, base.Add(5*time.Minute))
+
+	assert.Equal(t, 1, stream.Evicted())
+	require.Len(t, result.Incomplete, 1)
+	assert.Equal(t, int64(300000), result.Incomplete["/slow"].MaxAgeMs)
+}
+
+func TestSortIncomplete(t *testing.T) {
+	sorted := SortIncomplete(map[string]*models.IncompleteMetrics{
+		"/b":   {Path: "/b", Count: 1},
+		"/a y": {Path: "/a", LogStream: "y", Count: 1},
+		"/a x": {Path: "/a", LogStream: "x", Count: 1},
+		"/c":   {Path: "/c", Count: 3},
+	})
+
+	paths := make([]string, 0, len(sorted))
+	for _, metrics := range sorted {
+		paths = append(paths, metrics.Path+metrics.LogStream)
+	}
+	assert.Equal(t, []string{"/c", "/ax", "/ay", "/b"}, paths)
+}
@@ -0,0 +1,78 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+// nestedJSONKeys are the objects searched for request fields missing at the top level,
+// e.g. the payload and tags of rails_semantic_logger or Rails structured events
+var nestedJSONKeys = []string{"payload", "named_tags", "tags", "request"}
+
+// JSONParser parses JSON log lines that hold the whole request in one object, such as lograge's JSON formatter:
+// {"method":"GET","path":"/users","status":200,"duration":58.33,"view":40.43,"db":15.26,"request_id":"..."}
+type JSONParser struct{}
+
+// NewJSONParser creates a new JSONParser instance
+func NewJSONParser() *JSONParser {
+	return &JSONParser{}
+}
+
+// ParseLogEntry parses a JSON log line into a complete request entry
+// Text before the object, such as a Ruby Logger prefix, is ignored
+func (p *JSONParser) ParseLogEntry(logLine string) (*models.LogEntry, error) {
+	logLine = strings.TrimSpace(logLine)
+	if logLine == "" {
+		return nil, errEmptyLine
+	}
+
+	start := strings.Index(logLine, "{")
+	if start < 0 {
+		return nil, fmt.Errorf("%w: %s", errUnrecognizedFormat, logLine)
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(logLine[start:]))
+	decoder.UseNumber()
+	var object map[string]any
+	if err := decoder.Decode(&object); err != nil {
+		return nil, fmt.Errorf("%w: invalid JSON: %s", errUnrecognizedFormat, logLine)
+	}
+
+	return newRequestEntry(func(keys []string) (string, bool) {
+		if value, ok := lookupJSON(object, keys); ok {
+			return value, true
+		}
+		for _, nestedKey := range nestedJSONKeys {
+			if nested, ok := object[nestedKey].(map[string]any); ok {
+				if value, ok := lookupJSON(nested, keys); ok {
+					return value, true
+				}
+			}
+		}
+		return "", false
+	}, parseStructuredTimestamp, logLine)
+}
+
+// lookupJSON returns the first of keys with a string or number value in object
+func lookupJSON(object map[string]any, keys []string) (string, bool) {
+	for _, key := range keys {
+		switch value := object[key].(type) {
+		case string:
+			if value != "" {
+				return value, true
+			}
+		case json.Number:
+			return value.String(), true
+		}
+	}
+	return "", false
+}
+
+// isJSONLine checks if the log line is a JSON object, optionally after a Ruby Logger prefix
+func isJSONLine(logLine string) bool {
+	logLine = strings.TrimSpace(logLine)
+	return strings.HasPrefix(logLine, "{") || strings.Contains(logLine, "-- : {")
+}
@@ -0,0 +1,96 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+func TestJSONParser_ParseLogEntry(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		want       *models.LogEntry
+		wantReason string
+	}{
+		{
+			name:  "lograge JSON",
+			input: `{"method":"GET","path":"/users/123","format":"json","controller":"Api::UsersController","action":"show","status":200,"duration":58.33,"view":40.43,"db":15.26,"request_id":"abc-123"}`,
+			want: &models.LogEntry{
+				Type:         "Request",
+				Method:       "GET",
+				Path:         "/users/123",
+				StatusCode:   200,
+				StatusText:   "OK",
+				Duration:     58,
+				ViewDuration: 40.43,
+				DBDuration:   15.26,
+				Controller:   "Api::UsersController",
+				Action:       "show",
+				Format:       "json",
+				SessionID:    "abc-123",
+			},
+		},
+		{
+			name:  "fields nested in payload and tags",
+			input: `{"timestamp":"2023-01-01T12:00:00.5Z","level":"info","duration":"12.3ms","duration_ms":12.3,"payload":{"method":"post","path":"/orders","status":"422","view_runtime":1.5,"db_runtime":2.5},"named_tags":{"request_id":"def-456"}}`,
+			want: &models.LogEntry{
+				Type:         "Request",
+				Method:       "POST",
+				Path:         "/orders",
+				StatusCode:   422,
+				StatusText:   "Unprocessable Entity",
+				Duration:     12,
+				ViewDuration: 1.5,
+				DBDuration:   2.5,
+				SessionID:    "def-456",
+				Timestamp:    time.Date(2023, 1, 1, 12, 0, 0, 500000000, time.UTC),
+			},
+		},
+		{
+			name:  "JSON after a logger prefix",
+			input: `I, [2023-01-01T12:00:00.123456 #4321]  INFO -- : {"method":"GET","path":"/","status":304,"duration":1}`,
+			want: &models.LogEntry{
+				Type:       "Request",
+				Method:     "GET",
+				Path:       "/",
+				StatusCode: 304,
+				StatusText: "Not Modified",
+				Duration:   1,
+			},
+		},
+		{
+			name:       "JSON log line of something else",
+			input:      `{"level":"info","message":"Cache hit"}`,
+			wantReason: ParseFailureUnrecognizedFormat,
+		},
+		{
+			name:       "malformed JSON",
+			input:      `{"method":"GET",`,
+			wantReason: ParseFailureUnrecognizedFormat,
+		},
+		{
+			name:       "empty line",
+			input:      ` `,
+			wantReason: "",
+		},
+	}
+
+	parser := NewJSONParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.ParseLogEntry(tt.input)
+			if tt.want == nil {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantReason, parseFailureReason(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package analyzer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+// logrageFieldRegex matches a key=value pair of a lograge line; values may be double quoted
+var logrageFieldRegex = regexp.MustCompile(`(\w+)=("(?:[^"\\]|\\.)*"|\S*)`)
+
+// LogrageParser parses lograge key=value lines, which hold the whole request on one line:
+// method=GET path=/users format=html controller=UsersController action=index status=200 duration=58.33 view=40.43 db=15.26
+type LogrageParser struct{}
+
+// NewLogrageParser creates a new LogrageParser instance
+func NewLogrageParser() *LogrageParser {
+	return &LogrageParser{}
+}
+
+// ParseLogEntry parses a lograge line into a complete request entry
+func (p *LogrageParser) ParseLogEntry(logLine string) (*models.LogEntry, error) {
+	logLine = strings.TrimSpace(logLine)
+	if logLine == "" {
+		return nil, errEmptyLine
+	}
+	if !isLogrageLine(logLine) {
+		return nil, fmt.Errorf("%w: %s", errUnrecognizedFormat, logLine)
+	}
+
+	fields := make(map[string]string)
+	for _, match := range logrageFieldRegex.FindAllStringSubmatch(logLine, -1) {
+		value := match[2]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		fields[match[1]] = value
+	}
+
+	return newRequestEntry(func(keys []string) (string, bool) {
+		for _, key := range keys {
+			if value, exists := fields[key]; exists && value != "" {
+				return value, true
+			}
+		}
+		return "", false
+	}, parseStructuredTimestamp, logLine)
+}
+
+// isLogrageLine checks if the log line contains the key=value fields every lograge line has
+func isLogrageLine(logLine string) bool {
+	return strings.Contains(logLine, "method=") && strings.Contains(logLine, "path=") && strings.Contains(logLine, "status=")
+}
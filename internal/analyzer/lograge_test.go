@@ -0,0 +1,86 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+func TestLogrageParser_ParseLogEntry(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		want       *models.LogEntry
+		wantReason string
+	}{
+		{
+			name:  "lograge line",
+			input: `method=GET path=/users/123 format=html controller=UsersController action=show status=200 duration=58.33 view=40.43 db=15.26`,
+			want: &models.LogEntry{
+				Type:         "Request",
+				Method:       "GET",
+				Path:         "/users/123",
+				StatusCode:   200,
+				StatusText:   "OK",
+				Duration:     58,
+				ViewDuration: 40.43,
+				DBDuration:   15.26,
+				Controller:   "UsersController",
+				Action:       "show",
+				Format:       "html",
+			},
+		},
+		{
+			name:  "lograge line with logger prefix, quoted values and custom options",
+			input: `I, [2023-01-01T12:00:00.123456 #4321]  INFO -- : method=POST path="/search results" status=201 duration=12.5 request_id=abc-123 time=2023-01-01T12:00:00Z`,
+			want: &models.LogEntry{
+				Type:       "Request",
+				Method:     "POST",
+				Path:       "/search results",
+				StatusCode: 201,
+				StatusText: "Created",
+				Duration:   13,
+				SessionID:  "abc-123",
+				Timestamp:  time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:       "missing duration",
+			input:      `method=GET path=/users status=200`,
+			wantReason: ParseFailureUnrecognizedFormat,
+		},
+		{
+			name:       "invalid status",
+			input:      `method=GET path=/users status=abc duration=1.0`,
+			wantReason: ParseFailureInvalidNumber,
+		},
+		{
+			name:       "invalid time",
+			input:      `method=GET path=/users status=200 duration=1.0 time=yesterday`,
+			wantReason: ParseFailureInvalidTimestamp,
+		},
+		{
+			name:       "Rails text line",
+			input:      `Started GET "/users/123" for 127.0.0.1 at 2023-01-01 12:00:00 +0900`,
+			wantReason: ParseFailureUnrecognizedFormat,
+		},
+	}
+
+	parser := NewLogrageParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.ParseLogEntry(tt.input)
+			if tt.wantReason != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantReason, parseFailureReason(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
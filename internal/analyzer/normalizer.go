@@ -1,9 +1,15 @@
 package analyzer
 
 import (
+	"fmt"
+	"net/url"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"time"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/config"
 )
 
 var (
@@ -14,20 +20,178 @@ var (
 	orderIDRegex = regexp.MustCompile(`^[A-Z]{3,}-[A-Z0-9]+-[0-9]+$|^[A-Z]{3,}-[0-9]+$`)
 )
 
+// Built-in segment detectors, which can be disabled in the normalization config
+const (
+	DetectorNumeric = "numeric"
+	DetectorUUID    = "uuid"
+	DetectorHex     = "hex"
+	DetectorDate    = "date"
+	DetectorOrderID = "order_id"
+)
+
+// Detectors returns the names of all built-in segment detectors
+func Detectors() []string {
+	return []string{DetectorNumeric, DetectorUUID, DetectorHex, DetectorDate, DetectorOrderID}
+}
+
 // Normalizer handles path normalization
-type Normalizer struct{}
+type Normalizer struct {
+	routes   *RouteTable
+	rewrites []rewriteRule
+	rules    segmentRules
+	prefixes []prefixRules
+	queries  []queryRule
+}
+
+// segmentRule replaces a path segment matching its regex with a placeholder
+type segmentRule struct {
+	regex       *regexp.Regexp
+	placeholder string
+}
+
+// rewriteRule rewrites a whole path matching its regex
+type rewriteRule struct {
+	regex       *regexp.Regexp
+	replacement string
+}
+
+// segmentRules holds the user rules tried before the built-in detectors that are not disabled
+type segmentRules struct {
+	rules    []segmentRule
+	disabled map[string]bool
+}
+
+// prefixRules holds the segment rules of the paths starting with a prefix
+type prefixRules struct {
+	prefix string
+	segmentRules
+}
+
+// queryRule keeps query parameters of the paths starting with a prefix in the normalized path
+type queryRule struct {
+	prefix string
+	params []string // Sorted, so the kept parameters are in a stable order
+	values bool
+}
 
 // NewNormalizer creates a new Normalizer instance
 func NewNormalizer() *Normalizer {
 	return &Normalizer{}
 }
 
+// SetRoutes sets the routes whose patterns replace the paths matching them
+// Paths matching no route are normalized by the heuristics
+func (n *Normalizer) SetRoutes(routes *RouteTable) {
+	n.routes = routes
+}
+
+// SetRules sets the user normalization rules of the config file
+func (n *Normalizer) SetRules(cfg config.NormalizationConfig) error {
+	rewrites := make([]rewriteRule, 0, len(cfg.Rewrites))
+	for i, rule := range cfg.Rewrites {
+		if rule.Pattern == "" {
+			return fmt.Errorf("normalization rewrite rule at index %d must specify a pattern", i)
+		}
+		regex, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("failed to compile normalization rewrite pattern '%s': %w", rule.Pattern, err)
+		}
+		rewrites = append(rewrites, rewriteRule{regex: regex, replacement: rule.Replacement})
+	}
+
+	rules, err := compileSegmentRules(cfg.Segments, cfg.Disable)
+	if err != nil {
+		return err
+	}
+
+	prefixes := make([]prefixRules, 0, len(cfg.Prefixes))
+	for i, override := range cfg.Prefixes {
+		if override.Prefix == "" {
+			return fmt.Errorf("normalization prefix override at index %d must specify a prefix", i)
+		}
+		disable := cfg.Disable
+		if override.Disable != nil {
+			disable = override.Disable
+		}
+		overrideRules, err := compileSegmentRules(append(slices.Clone(override.Segments), cfg.Segments...), disable)
+		if err != nil {
+			return err
+		}
+		prefixes = append(prefixes, prefixRules{prefix: override.Prefix, segmentRules: overrideRules})
+	}
+	// The longest matching prefix wins
+	sort.SliceStable(prefixes, func(i, j int) bool {
+		return len(prefixes[i].prefix) > len(prefixes[j].prefix)
+	})
+
+	queries := make([]queryRule, 0, len(cfg.Query))
+	for i, rule := range cfg.Query {
+		if rule.Prefix == "" || len(rule.Params) == 0 {
+			return fmt.Errorf("normalization query rule at index %d must specify a prefix and params", i)
+		}
+		params := slices.Clone(rule.Params)
+		sort.Strings(params)
+		queries = append(queries, queryRule{prefix: rule.Prefix, params: params, values: rule.Values})
+	}
+	// The longest matching prefix wins
+	sort.SliceStable(queries, func(i, j int) bool {
+		return len(queries[i].prefix) > len(queries[j].prefix)
+	})
+
+	n.rewrites = rewrites
+	n.rules = rules
+	n.prefixes = prefixes
+	n.queries = queries
+	return nil
+}
+
+// compileSegmentRules compiles segment rules, which must match the whole segment, and the disabled detectors
+func compileSegmentRules(rules []config.SegmentRule, disable []string) (segmentRules, error) {
+	compiled := segmentRules{disabled: make(map[string]bool, len(disable))}
+	for _, detector := range disable {
+		if !slices.Contains(Detectors(), detector) {
+			return compiled, fmt.Errorf("unknown normalization detector %q (supported: %s)", detector, strings.Join(Detectors(), ", "))
+		}
+		compiled.disabled[detector] = true
+	}
+
+	for i, rule := range rules {
+		if rule.Pattern == "" || rule.Placeholder == "" {
+			return compiled, fmt.Errorf("normalization segment rule at index %d must specify a pattern and a placeholder", i)
+		}
+		regex, err := regexp.Compile("^(?:" + rule.Pattern + ")$")
+		if err != nil {
+			return compiled, fmt.Errorf("failed to compile normalization segment pattern '%s': %w", rule.Pattern, err)
+		}
+		compiled.rules = append(compiled.rules, segmentRule{regex: regex, placeholder: rule.Placeholder})
+	}
+
+	return compiled, nil
+}
+
 // NormalizePath normalizes a request path by replacing dynamic segments with placeholders
-// Query parameters are excluded from the normalized path for aggregation
-func (n *Normalizer) NormalizePath(path string) string {
+// The method selects the routes the path can match; query parameters are excluded from the normalized path for
+// aggregation, unless a query rule keeps them
+func (n *Normalizer) NormalizePath(method, path string) string {
 	// Split path and query string - we'll exclude query parameters
 	parts := strings.SplitN(path, "?", 2)
-	pathPart := parts[0]
+	if len(parts) == 2 {
+		return n.normalizePathPart(method, parts[0]) + n.keptQuery(parts[0], parts[1])
+	}
+	return n.normalizePathPart(method, parts[0])
+}
+
+// normalizePathPart normalizes a request path without query string
+func (n *Normalizer) normalizePathPart(method, path string) string {
+	pathPart := n.rewrite(path)
+
+	if n.routes != nil {
+		if template, ok := n.routes.Match(method, pathPart); ok {
+			return template
+		}
+	}
+
+	rules := n.segmentRulesFor(pathPart)
 
 	// Split path into segments
 	segments := strings.Split(pathPart, "/")
@@ -39,8 +203,8 @@ func (n *Normalizer) NormalizePath(path string) string {
 		}
 
 		// Check if segment should be replaced
-		if n.shouldNormalize(segment) {
-			segments[i] = n.getPlaceholder(segment)
+		if placeholder, ok := n.normalizeSegment(segment, rules); ok {
+			segments[i] = placeholder
 		}
 	}
 
@@ -49,18 +213,81 @@ func (n *Normalizer) NormalizePath(path string) string {
 	return normalizedPath
 }
 
-// shouldNormalize determines if a path segment should be normalized
-func (n *Normalizer) shouldNormalize(segment string) bool {
-	return n.isNumericID(segment) ||
-		n.isUUID(segment) ||
-		n.isHexID(segment) ||
-		n.isDateFormat(segment) ||
-		n.isOrderID(segment)
+// keptQuery returns the query parameters kept by the query rule of the longest prefix matching the path,
+// e.g. "?type" or "?type=users", or an empty string when no parameter is kept
+func (n *Normalizer) keptQuery(path, rawQuery string) string {
+	for _, rule := range n.queries {
+		if !strings.HasPrefix(path, rule.prefix) {
+			continue
+		}
+
+		// Malformed pairs are skipped; the well-formed ones are still returned
+		query, _ := url.ParseQuery(rawQuery)
+		kept := make([]string, 0, len(rule.params))
+		for _, name := range rule.params {
+			values, exists := query[name]
+			if !exists {
+				continue
+			}
+			if rule.values {
+				kept = append(kept, url.QueryEscape(name)+"="+url.QueryEscape(values[0]))
+			} else {
+				kept = append(kept, url.QueryEscape(name))
+			}
+		}
+		if len(kept) == 0 {
+			return ""
+		}
+		return "?" + strings.Join(kept, "&")
+	}
+	return ""
+}
+
+// rewrite applies the first rewrite rule matching the path
+func (n *Normalizer) rewrite(path string) string {
+	for _, rule := range n.rewrites {
+		if rule.regex.MatchString(path) {
+			return rule.regex.ReplaceAllString(path, rule.replacement)
+		}
+	}
+	return path
+}
+
+// segmentRulesFor returns the segment rules of the longest prefix override matching the path
+func (n *Normalizer) segmentRulesFor(path string) *segmentRules {
+	for i := range n.prefixes {
+		if strings.HasPrefix(path, n.prefixes[i].prefix) {
+			return &n.prefixes[i].segmentRules
+		}
+	}
+	return &n.rules
+}
+
+// normalizeSegment returns the placeholder of the first user rule or built-in detector matching a segment
+func (n *Normalizer) normalizeSegment(segment string, rules *segmentRules) (string, bool) {
+	for _, rule := range rules.rules {
+		if rule.regex.MatchString(segment) {
+			return rule.placeholder, true
+		}
+	}
+	if n.shouldNormalize(segment, rules.disabled) {
+		return n.getPlaceholder(segment, rules.disabled), true
+	}
+	return "", false
+}
+
+// shouldNormalize determines if a path segment should be normalized by a detector that is not disabled
+func (n *Normalizer) shouldNormalize(segment string, disabled map[string]bool) bool {
+	return (!disabled[DetectorNumeric] && n.isNumericID(segment)) ||
+		(!disabled[DetectorUUID] && n.isUUID(segment)) ||
+		(!disabled[DetectorHex] && n.isHexID(segment)) ||
+		(!disabled[DetectorDate] && n.isDateFormat(segment)) ||
+		(!disabled[DetectorOrderID] && n.isOrderID(segment))
 }
 
 // getPlaceholder returns the appropriate placeholder for a segment
-func (n *Normalizer) getPlaceholder(segment string) string {
-	if n.isDateFormat(segment) {
+func (n *Normalizer) getPlaceholder(segment string, disabled map[string]bool) string {
+	if !disabled[DetectorDate] && n.isDateFormat(segment) {
 		return ":date"
 	}
 	return ":id"
@@ -4,6 +4,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/config"
 )
 
 func TestNormalizePath(t *testing.T) {
@@ -113,12 +116,162 @@ func TestNormalizePath(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := normalizer.NormalizePath(tt.input)
+			got := normalizer.NormalizePath("GET", tt.input)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
 
+func TestNormalizePath_WithRoutes(t *testing.T) {
+	routes, err := ParseRoutes([]byte(testRoutesText))
+	require.NoError(t, err)
+	normalizer := NewNormalizer()
+	normalizer.SetRoutes(routes)
+
+	tests := []struct {
+		name   string
+		method string
+		input  string
+		want   string
+	}{
+		{name: "Slug matched by route", method: "GET", input: "/articles/my-first-post", want: "/articles/:slug"},
+		{name: "Hex-looking word matched by route", method: "GET", input: "/cafe01?menu=1", want: "/cafe01"},
+		{name: "Unmatched path falls back to heuristics", method: "GET", input: "/users/123/posts/456", want: "/users/:id/posts/:id"},
+		{name: "Path of a route without the method falls back to heuristics", method: "DELETE", input: "/cafe01", want: "/:id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, normalizer.NormalizePath(tt.method, tt.input))
+		})
+	}
+}
+
+func TestNormalizePath_WithRules(t *testing.T) {
+	normalizer := NewNormalizer()
+	require.NoError(t, normalizer.SetRules(config.NormalizationConfig{
+		Disable: []string{DetectorHex},
+		Segments: []config.SegmentRule{
+			{Pattern: `ord_[0-9A-Za-z]+`, Placeholder: ":order_id"},
+		},
+		Rewrites: []config.RewriteRule{
+			{Pattern: `^/blog/\d{4}/\d{2}/[^/]+$`, Replacement: "/blog/:year/:month/:slug"},
+			{Pattern: `^/v1(/.*)$`, Replacement: "$1"},
+		},
+		Prefixes: []config.PrefixOverride{
+			{Prefix: "/api/", Segments: []config.SegmentRule{{Pattern: `[a-z]+-[a-z]+`, Placeholder: ":slug"}}},
+			{Prefix: "/api/legacy/", Disable: []string{DetectorNumeric}},
+			{Prefix: "/shop/", Disable: []string{}},
+		},
+	}))
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "User segment rule", input: "/orders/ord_2Xk9aB3", want: "/orders/:order_id"},
+		{name: "User segment rule matches the whole segment", input: "/orders/ord_2Xk9aB3-draft", want: "/orders/ord_2Xk9aB3-draft"},
+		{name: "Disabled detector", input: "/cafe01/menu", want: "/cafe01/menu"},
+		{name: "Enabled detectors still apply", input: "/users/123", want: "/users/:id"},
+		{name: "Whole-path rewrite", input: "/blog/2024/05/hello-world?ref=top", want: "/blog/:year/:month/:slug"},
+		{name: "Rewrite is followed by segment normalization", input: "/v1/users/123", want: "/users/:id"},
+		{name: "Prefix segment rule", input: "/api/articles/hello-world", want: "/api/articles/:slug"},
+		{name: "Prefix rules include the top-level rules", input: "/api/orders/ord_1", want: "/api/orders/:order_id"},
+		{name: "Longest prefix wins", input: "/api/legacy/123/hello-world", want: "/api/legacy/123/hello-world"},
+		{name: "Prefix with empty disable list enables all detectors", input: "/shop/cafe01", want: "/shop/:id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, normalizer.NormalizePath("GET", tt.input))
+		})
+	}
+}
+
+func TestNormalizePath_WithQueryRules(t *testing.T) {
+	normalizer := NewNormalizer()
+	require.NoError(t, normalizer.SetRules(config.NormalizationConfig{
+		Query: []config.QueryRule{
+			{Prefix: "/search", Params: []string{"type", "page"}},
+			{Prefix: "/api", Params: []string{"op"}, Values: true},
+			{Prefix: "/api/v2", Params: []string{"version"}},
+		},
+	}))
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "Kept parameter name", input: "/search?type=users&q=alice", want: "/search?type"},
+		{name: "Kept parameters in sorted order", input: "/search?type=users&page=2", want: "/search?page&type"},
+		{name: "No kept parameter", input: "/search?q=alice", want: "/search"},
+		{name: "Kept parameter with value", input: "/api?op=createUser&id=1", want: "/api?op=createUser"},
+		{name: "First value of a repeated parameter", input: "/api?op=a&op=b", want: "/api?op=a"},
+		{name: "Value is escaped", input: "/api?op=a%20b", want: "/api?op=a+b"},
+		{name: "Longest prefix wins", input: "/api/v2/users/123?op=list&version=3", want: "/api/v2/users/:id?version"},
+		{name: "Path without rule drops the query", input: "/users/123?type=admin", want: "/users/:id"},
+		{name: "Path without query", input: "/search", want: "/search"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, normalizer.NormalizePath("GET", tt.input))
+		})
+	}
+}
+
+func TestNormalizer_SetRulesErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.NormalizationConfig
+		errorMsg string
+	}{
+		{
+			name:     "unknown detector",
+			cfg:      config.NormalizationConfig{Disable: []string{"slug"}},
+			errorMsg: `unknown normalization detector "slug" (supported: numeric, uuid, hex, date, order_id)`,
+		},
+		{
+			name:     "segment rule without placeholder",
+			cfg:      config.NormalizationConfig{Segments: []config.SegmentRule{{Pattern: "ord_.+"}}},
+			errorMsg: "normalization segment rule at index 0 must specify a pattern and a placeholder",
+		},
+		{
+			name:     "invalid segment pattern",
+			cfg:      config.NormalizationConfig{Segments: []config.SegmentRule{{Pattern: "ord_[", Placeholder: ":id"}}},
+			errorMsg: "failed to compile normalization segment pattern 'ord_['",
+		},
+		{
+			name:     "invalid rewrite pattern",
+			cfg:      config.NormalizationConfig{Rewrites: []config.RewriteRule{{Pattern: "(", Replacement: "/"}}},
+			errorMsg: "failed to compile normalization rewrite pattern '('",
+		},
+		{
+			name:     "prefix override without prefix",
+			cfg:      config.NormalizationConfig{Prefixes: []config.PrefixOverride{{Disable: []string{DetectorHex}}}},
+			errorMsg: "normalization prefix override at index 0 must specify a prefix",
+		},
+		{
+			name:     "unknown detector in prefix override",
+			cfg:      config.NormalizationConfig{Prefixes: []config.PrefixOverride{{Prefix: "/api/", Disable: []string{"slug"}}}},
+			errorMsg: `unknown normalization detector "slug"`,
+		},
+		{
+			name:     "query rule without params",
+			cfg:      config.NormalizationConfig{Query: []config.QueryRule{{Prefix: "/search"}}},
+			errorMsg: "normalization query rule at index 0 must specify a prefix and params",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorContains(t, NewNormalizer().SetRules(tt.cfg), tt.errorMsg)
+		})
+	}
+}
+
 func TestIsNumericID(t *testing.T) {
 	tests := []struct {
 		name     string
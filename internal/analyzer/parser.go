@@ -13,13 +13,28 @@ import (
 
 var (
 	// Regular expressions for parsing Rails logs
-	// Handles both with and without log level prefix
-	startedLogRegex   = regexp.MustCompile(`(?:^|\]\s+)Started\s+(\w+)\s+"([^"]+)"\s+for\s+[\d.]+\s+at\s+(.+)$`)
-	completedLogRegex = regexp.MustCompile(`(?:^|\]\s+)Completed\s+(\d+)\s+([^i]+)\s+in\s+(\d+)ms`)
-	viewDurationRegex = regexp.MustCompile(`Views:\s+([\d.]+)ms`)
-	dbDurationRegex   = regexp.MustCompile(`ActiveRecord:\s+([\d.]+)ms`)
+	// Handles both with and without log level prefix, and the Ruby Logger prefix ending in "-- :"
+	startedLogRegex   = regexp.MustCompile(`(?:^|\]\s+|--\s+:\s+)Started\s+(\w+)\s+"([^"]+)"\s+for\s+[\d.]+\s+at\s+(.+)$`)
+	completedLogRegex = regexp.MustCompile(`(?:^|\]\s+|--\s+:\s+)Completed\s+(\d+)\s+([^i]+)\s+in\s+(\d+)ms`)
+	// Controller may be namespaced (Api::V1::UsersController) and format may be */*
+	processingLogRegex = regexp.MustCompile(`(?:^|\]\s+|--\s+:\s+)Processing\s+by\s+([\w:]+)#(\w+)\s+as\s+(\S+)`)
+	viewDurationRegex  = regexp.MustCompile(`Views:\s+([\d.]+)ms`)
+	dbDurationRegex    = regexp.MustCompile(`ActiveRecord:\s+([\d.]+)ms`)
 	// Session ID can appear after log level prefix: [session-id] or at the end of line
-	sessionIDRegex = regexp.MustCompile(`\[([a-f0-9\-]+)\]\s+(?:Started|Completed)|\[([^\]]+)\]$`)
+	sessionIDRegex = regexp.MustCompile(`\[([a-f0-9\-]+)\]\s+(?:Started|Processing|Completed)|\[([^\]]+)\]$`)
+	// Ruby Logger prefix with the process ID: "I, [2025-07-10T17:28:13.123456 #12345]  INFO -- :",
+	// or a "[12345:puma srv tp 001]" process and thread tag
+	processIDRegex = regexp.MustCompile(`\[\S+ #(\d+)\]|\[(\d+):[^\]]+\]`)
+	// Thread hint of threaded servers: a "[12345:puma srv tp 001]" tag, or a log tag like [tid=70123] or [TID-ovd4g]
+	threadIDRegex = regexp.MustCompile(`\[\d+:([^\]]+)\]|\[(?:tid[=:]\s*|TID-)([\w-]+)\]`)
+)
+
+// Errors returned by ParseLogEntry, used to classify lines that could not be parsed
+var (
+	errEmptyLine          = errors.New("empty log line")
+	errUnrecognizedFormat = errors.New("unrecognized log format")
+	errInvalidTimestamp   = errors.New("failed to parse timestamp")
+	errInvalidNumber      = errors.New("invalid number")
 )
 
 // Parser handles parsing of Rails log entries
@@ -34,7 +49,7 @@ func NewParser() *Parser {
 func (p *Parser) ParseLogEntry(logLine string) (*models.LogEntry, error) {
 	logLine = strings.TrimSpace(logLine)
 	if logLine == "" {
-		return nil, errors.New("empty log line")
+		return nil, errEmptyLine
 	}
 
 	if p.isStartedLog(logLine) {
@@ -45,7 +60,11 @@ func (p *Parser) ParseLogEntry(logLine string) (*models.LogEntry, error) {
 		return p.parseCompletedLog(logLine)
 	}
 
-	return nil, fmt.Errorf("unrecognized log format: %s", logLine)
+	if p.isProcessingLog(logLine) {
+		return p.parseProcessingLog(logLine)
+	}
+
+	return nil, fmt.Errorf("%w: %s", errUnrecognizedFormat, logLine)
 }
 
 // isStartedLog checks if the log line is a Started log
@@ -58,11 +77,16 @@ func (p *Parser) isCompletedLog(logLine string) bool {
 	return completedLogRegex.MatchString(logLine)
 }
 
+// isProcessingLog checks if the log line is a Processing log
+func (p *Parser) isProcessingLog(logLine string) bool {
+	return processingLogRegex.MatchString(logLine)
+}
+
 // parseStartedLog parses a Started log entry
 func (p *Parser) parseStartedLog(logLine string) (*models.LogEntry, error) {
 	matches := startedLogRegex.FindStringSubmatch(logLine)
 	if len(matches) != 4 {
-		return nil, fmt.Errorf("invalid Started log format: %s", logLine)
+		return nil, fmt.Errorf("%w: invalid Started log format: %s", errUnrecognizedFormat, logLine)
 	}
 
 	// Extract session ID first
@@ -78,7 +102,7 @@ func (p *Parser) parseStartedLog(logLine string) (*models.LogEntry, error) {
 	// Parse timestamp
 	timestamp, err := p.parseTimestamp(timestampStr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse timestamp: %w", err)
+		return nil, fmt.Errorf("%w: %w", errInvalidTimestamp, err)
 	}
 
 	return &models.LogEntry{
@@ -87,6 +111,8 @@ func (p *Parser) parseStartedLog(logLine string) (*models.LogEntry, error) {
 		Path:      matches[2],
 		Timestamp: timestamp,
 		SessionID: sessionID,
+		ProcessID: p.extractProcessID(logLine),
+		ThreadID:  p.extractThreadID(logLine),
 	}, nil
 }
 
@@ -94,17 +120,17 @@ func (p *Parser) parseStartedLog(logLine string) (*models.LogEntry, error) {
 func (p *Parser) parseCompletedLog(logLine string) (*models.LogEntry, error) {
 	matches := completedLogRegex.FindStringSubmatch(logLine)
 	if len(matches) != 4 {
-		return nil, fmt.Errorf("invalid Completed log format: %s", logLine)
+		return nil, fmt.Errorf("%w: invalid Completed log format: %s", errUnrecognizedFormat, logLine)
 	}
 
 	statusCode, err := strconv.Atoi(matches[1])
 	if err != nil {
-		return nil, fmt.Errorf("invalid status code: %w", err)
+		return nil, fmt.Errorf("%w: invalid status code: %w", errInvalidNumber, err)
 	}
 
 	duration, err := strconv.Atoi(matches[3])
 	if err != nil {
-		return nil, fmt.Errorf("invalid duration: %w", err)
+		return nil, fmt.Errorf("%w: invalid duration: %w", errInvalidNumber, err)
 	}
 
 	entry := &models.LogEntry{
@@ -113,6 +139,8 @@ func (p *Parser) parseCompletedLog(logLine string) (*models.LogEntry, error) {
 		StatusText: strings.TrimSpace(matches[2]),
 		Duration:   duration,
 		SessionID:  p.extractSessionID(logLine),
+		ProcessID:  p.extractProcessID(logLine),
+		ThreadID:   p.extractThreadID(logLine),
 	}
 
 	// Extract view duration if present
@@ -132,6 +160,24 @@ func (p *Parser) parseCompletedLog(logLine string) (*models.LogEntry, error) {
 	return entry, nil
 }
 
+// parseProcessingLog parses a Processing log entry naming the controller action that handles the request
+func (p *Parser) parseProcessingLog(logLine string) (*models.LogEntry, error) {
+	matches := processingLogRegex.FindStringSubmatch(logLine)
+	if len(matches) != 4 {
+		return nil, fmt.Errorf("%w: invalid Processing log format: %s", errUnrecognizedFormat, logLine)
+	}
+
+	return &models.LogEntry{
+		Type:       "Processing",
+		Controller: matches[1],
+		Action:     matches[2],
+		Format:     matches[3],
+		SessionID:  p.extractSessionID(logLine),
+		ProcessID:  p.extractProcessID(logLine),
+		ThreadID:   p.extractThreadID(logLine),
+	}, nil
+}
+
 // extractSessionID extracts session ID from log line
 func (p *Parser) extractSessionID(logLine string) string {
 	matches := sessionIDRegex.FindStringSubmatch(logLine)
@@ -148,6 +194,27 @@ func (p *Parser) extractSessionID(logLine string) string {
 	return ""
 }
 
+// extractProcessID extracts the process ID from the Ruby Logger prefix or the process and thread tag of a log line
+func (p *Parser) extractProcessID(logLine string) string {
+	return firstSubmatch(processIDRegex, logLine)
+}
+
+// extractThreadID extracts the thread hint of a log line, empty when the line has none
+func (p *Parser) extractThreadID(logLine string) string {
+	return firstSubmatch(threadIDRegex, logLine)
+}
+
+// firstSubmatch returns the first non-empty capture group of the leftmost match of regex
+func firstSubmatch(regex *regexp.Regexp, logLine string) string {
+	matches := regex.FindStringSubmatch(logLine)
+	for i := 1; i < len(matches); i++ {
+		if matches[i] != "" {
+			return matches[i]
+		}
+	}
+	return ""
+}
+
 // parseTimestamp parses timestamp from Rails log format
 func (p *Parser) parseTimestamp(timestampStr string) (time.Time, error) {
 	// Rails log timestamp format: "2023-01-01 12:00:00 +0900"
@@ -121,6 +121,82 @@ func TestParseLogEntry(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:  "Started log with Ruby Logger prefix and no tags",
+			input: `I, [2023-01-01T12:00:00.123456 #4321]  INFO -- : Started GET "/users/123" for 127.0.0.1 at 2023-01-01 12:00:00 +0900`,
+			want: &models.LogEntry{
+				Type:      "Started",
+				Method:    "GET",
+				Path:      "/users/123",
+				Timestamp: mustParseTime("2023-01-01 12:00:00 +0900"),
+				ProcessID: "4321",
+			},
+			wantErr: false,
+		},
+		{
+			name:  "Completed log with Ruby Logger prefix and no tags",
+			input: `I, [2023-01-01T12:00:00.234567 #4321]  INFO -- : Completed 200 OK in 150ms (Views: 100.0ms | ActiveRecord: 50.0ms | Allocations: 1234)`,
+			want: &models.LogEntry{
+				Type:         "Completed",
+				StatusCode:   200,
+				StatusText:   "OK",
+				Duration:     150,
+				ViewDuration: 100.0,
+				DBDuration:   50.0,
+				ProcessID:    "4321",
+			},
+			wantErr: false,
+		},
+		{
+			name:  "Processing log with session ID",
+			input: `I, [2023-01-01T12:00:00.123456 #4321]  INFO -- : [a1b2c3d4] Processing by UsersController#show as JSON`,
+			want: &models.LogEntry{
+				Type:       "Processing",
+				Controller: "UsersController",
+				Action:     "show",
+				Format:     "JSON",
+				SessionID:  "a1b2c3d4",
+				ProcessID:  "4321",
+			},
+			wantErr: false,
+		},
+		{
+			name:  "Started log with process and thread tag",
+			input: `[4321:puma srv tp 001] Started GET "/users/123" for 127.0.0.1 at 2023-01-01 12:00:00 +0900`,
+			want: &models.LogEntry{
+				Type:      "Started",
+				Method:    "GET",
+				Path:      "/users/123",
+				Timestamp: mustParseTime("2023-01-01 12:00:00 +0900"),
+				ProcessID: "4321",
+				ThreadID:  "puma srv tp 001",
+			},
+			wantErr: false,
+		},
+		{
+			name:  "Completed log with Ruby Logger prefix and thread tag",
+			input: `I, [2023-01-01T12:00:00.234567 #4321]  INFO -- : [tid=70123] Completed 200 OK in 150ms`,
+			want: &models.LogEntry{
+				Type:       "Completed",
+				StatusCode: 200,
+				StatusText: "OK",
+				Duration:   150,
+				ProcessID:  "4321",
+				ThreadID:   "70123",
+			},
+			wantErr: false,
+		},
+		{
+			name:  "Processing log with namespaced controller and any format",
+			input: `Processing by Api::V1::OrdersController#create as */*`,
+			want: &models.LogEntry{
+				Type:       "Processing",
+				Controller: "Api::V1::OrdersController",
+				Action:     "create",
+				Format:     "*/*",
+			},
+			wantErr: false,
+		},
 	}
 
 	parser := NewParser()
@@ -177,6 +253,49 @@ func TestExtractSessionID(t *testing.T) {
 	}
 }
 
+func TestExtractThreadID(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "Process and thread tag",
+			input: `[4321:puma srv tp 001] Completed 200 OK in 150ms`,
+			want:  "puma srv tp 001",
+		},
+		{
+			name:  "tid tag",
+			input: `[tid=70123] Completed 200 OK in 150ms`,
+			want:  "70123",
+		},
+		{
+			name:  "TID tag",
+			input: `[TID-ovd4g] Completed 200 OK in 150ms`,
+			want:  "ovd4g",
+		},
+		{
+			name:  "Ruby Logger prefix is not a thread",
+			input: `I, [2023-01-01T12:00:00.234567 #4321]  INFO -- : Completed 200 OK in 150ms`,
+			want:  "",
+		},
+		{
+			name:  "tid in the query string is not a thread",
+			input: `Started GET "/search?tid=5" for 127.0.0.1 at 2023-01-01 12:00:00 +0900`,
+			want:  "",
+		},
+	}
+
+	parser := NewParser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parser.extractThreadID(tt.input)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestIsStartedLog(t *testing.T) {
 	tests := []struct {
 		name  string
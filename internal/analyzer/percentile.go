@@ -0,0 +1,84 @@
+package analyzer
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+// durationSubBucketBits sets the precision of the duration histogram: every power-of-two range of durations is
+// split into 2^durationSubBucketBits buckets, so durations below twice that are counted exactly and larger ones
+// with a relative error below 1/128
+const durationSubBucketBits = 7
+
+// durationSubBuckets is the number of buckets per power-of-two range of durations
+const durationSubBuckets = 1 << durationSubBucketBits
+
+// durationBucket returns the histogram bucket of a duration in milliseconds
+// A histogram holds at most a few thousand buckets, however many requests it counts
+func durationBucket(duration int) int {
+	if duration < 2*durationSubBuckets {
+		return max(duration, 0)
+	}
+	shift := bits.Len(uint(duration)) - 1 - durationSubBucketBits
+	return shift*durationSubBuckets + duration>>shift
+}
+
+// bucketRange returns the smallest and largest duration counted in a histogram bucket
+func bucketRange(bucket int) (int, int) {
+	if bucket < 2*durationSubBuckets {
+		return bucket, bucket
+	}
+	shift := bucket/durationSubBuckets - 1
+	lower := (bucket%durationSubBuckets + durationSubBuckets) << shift
+	return lower, lower + 1<<shift - 1
+}
+
+// addDuration counts a request duration in the duration histogram of metrics
+func addDuration(metrics *models.PathMetrics, duration int) {
+	if metrics.Durations == nil {
+		metrics.Durations = make(map[int]int)
+	}
+	metrics.Durations[durationBucket(duration)]++
+}
+
+// calculatePercentile returns the p-th percentile of a duration histogram using the nearest-rank method
+// The middle of the bucket holding the rank is returned, clamped to the smallest and largest duration
+func calculatePercentile(histogram map[int]int, sortedBuckets []int, minTime, maxTime int, p float64) int {
+	total := 0
+	for _, count := range histogram {
+		total += count
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(total)))
+	rank = min(max(rank, 1), total)
+
+	seen := 0
+	for _, bucket := range sortedBuckets {
+		seen += histogram[bucket]
+		if seen >= rank {
+			lower, upper := bucketRange(bucket)
+			return min(max((lower+upper)/2, minTime), maxTime)
+		}
+	}
+	return maxTime
+}
+
+// updatePercentiles recalculates the percentile fields of metrics from its duration histogram
+func updatePercentiles(metrics *models.PathMetrics) {
+	buckets := make([]int, 0, len(metrics.Durations))
+	for bucket := range metrics.Durations {
+		buckets = append(buckets, bucket)
+	}
+	sort.Ints(buckets)
+
+	metrics.P50Time = calculatePercentile(metrics.Durations, buckets, metrics.MinTime, metrics.MaxTime, 50)
+	metrics.P90Time = calculatePercentile(metrics.Durations, buckets, metrics.MinTime, metrics.MaxTime, 90)
+	metrics.P95Time = calculatePercentile(metrics.Durations, buckets, metrics.MinTime, metrics.MaxTime, 95)
+	metrics.P99Time = calculatePercentile(metrics.Durations, buckets, metrics.MinTime, metrics.MaxTime, 99)
+}
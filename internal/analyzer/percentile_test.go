@@ -0,0 +1,164 @@
+package analyzer
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+// newHistogramMetrics returns path metrics whose duration histogram counts the given durations
+func newHistogramMetrics(durations []int) *models.PathMetrics {
+	metrics := &models.PathMetrics{}
+	for i, duration := range durations {
+		if i == 0 || duration < metrics.MinTime {
+			metrics.MinTime = duration
+		}
+		if duration > metrics.MaxTime {
+			metrics.MaxTime = duration
+		}
+		addDuration(metrics, duration)
+	}
+	return metrics
+}
+
+func TestDurationBucket(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration int
+	}{
+		{name: "zero", duration: 0},
+		{name: "small duration", duration: 120},
+		{name: "largest exact duration", duration: 255},
+		{name: "first inexact duration", duration: 256},
+		{name: "one second", duration: 1000},
+		{name: "one minute", duration: 60000},
+		{name: "one hour", duration: 3600000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lower, upper := bucketRange(durationBucket(tt.duration))
+			assert.LessOrEqual(t, lower, tt.duration)
+			assert.GreaterOrEqual(t, upper, tt.duration)
+			// The bucket width stays below 1/128 of the duration
+			assert.Less(t, float64(upper-lower), float64(tt.duration)/128+1)
+		})
+	}
+
+	t.Run("durations below 256 are exact", func(t *testing.T) {
+		for duration := range 256 {
+			lower, upper := bucketRange(durationBucket(duration))
+			assert.Equal(t, duration, lower)
+			assert.Equal(t, duration, upper)
+		}
+	})
+
+	t.Run("buckets are ordered like durations", func(t *testing.T) {
+		previous := durationBucket(0)
+		for duration := 1; duration < 1000000; duration += 7 {
+			bucket := durationBucket(duration)
+			assert.GreaterOrEqual(t, bucket, previous)
+			previous = bucket
+		}
+	})
+}
+
+func TestCalculatePercentile(t *testing.T) {
+	tests := []struct {
+		name      string
+		durations []int
+		p         float64
+		expected  int
+	}{
+		{
+			name:      "empty durations",
+			durations: []int{},
+			p:         50,
+			expected:  0,
+		},
+		{
+			name:      "single duration",
+			durations: []int{120},
+			p:         99,
+			expected:  120,
+		},
+		{
+			name:      "median of ten values",
+			durations: []int{10, 20, 30, 40, 50, 60, 70, 80, 90, 100},
+			p:         50,
+			expected:  50,
+		},
+		{
+			name:      "p90 of ten values",
+			durations: []int{10, 20, 30, 40, 50, 60, 70, 80, 90, 100},
+			p:         90,
+			expected:  90,
+		},
+		{
+			name:      "p95 rounds up to the next rank",
+			durations: []int{10, 20, 30, 40, 50, 60, 70, 80, 90, 100},
+			p:         95,
+			expected:  100,
+		},
+		{
+			name:      "zero percentile returns minimum",
+			durations: []int{10, 20, 30},
+			p:         0,
+			expected:  10,
+		},
+		{
+			name:      "single large duration is clamped to the exact value",
+			durations: []int{12345},
+			p:         50,
+			expected:  12345,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := newHistogramMetrics(tt.durations)
+			buckets := make([]int, 0, len(metrics.Durations))
+			for bucket := range metrics.Durations {
+				buckets = append(buckets, bucket)
+			}
+			sort.Ints(buckets)
+
+			assert.Equal(t, tt.expected, calculatePercentile(metrics.Durations, buckets, metrics.MinTime, metrics.MaxTime, tt.p))
+		})
+	}
+}
+
+func TestUpdatePercentiles(t *testing.T) {
+	durations := make([]int, 0, 100)
+	for i := 100; i >= 1; i-- {
+		durations = append(durations, i)
+	}
+	metrics := newHistogramMetrics(durations)
+
+	updatePercentiles(metrics)
+
+	assert.Equal(t, 50, metrics.P50Time)
+	assert.Equal(t, 90, metrics.P90Time)
+	assert.Equal(t, 95, metrics.P95Time)
+	assert.Equal(t, 99, metrics.P99Time)
+}
+
+func TestUpdatePercentiles_LargeDurations(t *testing.T) {
+	// 100000 requests from 1ms to 100s keep a bounded histogram and percentiles within 1%
+	durations := make([]int, 0, 100000)
+	for i := 1; i <= 100000; i++ {
+		durations = append(durations, i)
+	}
+	metrics := newHistogramMetrics(durations)
+
+	updatePercentiles(metrics)
+
+	assert.Less(t, len(metrics.Durations), 2000)
+	assert.InEpsilon(t, 50000, metrics.P50Time, 0.01)
+	assert.InEpsilon(t, 90000, metrics.P90Time, 0.01)
+	assert.InEpsilon(t, 95000, metrics.P95Time, 0.01)
+	assert.InEpsilon(t, 99000, metrics.P99Time, 0.01)
+}
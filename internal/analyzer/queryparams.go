@@ -0,0 +1,97 @@
+package analyzer
+
+import (
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/output"
+)
+
+// addQueryParams counts the query parameter names of a request path in its metrics
+// A parameter repeated in the same request is counted once
+func addQueryParams(metrics *models.PathMetrics, path string) {
+	_, rawQuery, found := strings.Cut(path, "?")
+	if !found || rawQuery == "" {
+		return
+	}
+
+	// Malformed pairs are skipped; the well-formed ones are still counted
+	query, _ := url.ParseQuery(rawQuery)
+	if len(query) == 0 {
+		return
+	}
+	if metrics.QueryParams == nil {
+		metrics.QueryParams = make(map[string]int)
+	}
+	for name := range query {
+		metrics.QueryParams[name]++
+	}
+}
+
+// SetQueryParamReport sets whether the query parameter names of the requests to each path are counted
+func (a *Analyzer) SetQueryParamReport(enabled bool) {
+	a.aggregator.queryParams = enabled
+}
+
+// SummarizeQueryParams returns the query parameter names of each path with query parameters, ordered by count
+// descending, then by path, method and log stream
+func SummarizeQueryParams(pathMetrics map[string]*models.PathMetrics) []*models.QueryParamSummary {
+	summaries := make([]*models.QueryParamSummary, 0)
+	for _, metrics := range pathMetrics {
+		if len(metrics.QueryParams) == 0 {
+			continue
+		}
+
+		params := make([]*models.QueryParamCount, 0, len(metrics.QueryParams))
+		for name, count := range metrics.QueryParams {
+			params = append(params, &models.QueryParamCount{Name: name, Count: count})
+		}
+		sort.Slice(params, func(i, j int) bool {
+			if params[i].Count != params[j].Count {
+				return params[i].Count > params[j].Count
+			}
+			return params[i].Name < params[j].Name
+		})
+
+		summaries = append(summaries, &models.QueryParamSummary{
+			Method:    metrics.Method,
+			Path:      metrics.Path,
+			LogStream: metrics.LogStream,
+			Count:     metrics.Count,
+			Params:    params,
+		})
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Count != summaries[j].Count {
+			return summaries[i].Count > summaries[j].Count
+		}
+		if summaries[i].Path != summaries[j].Path {
+			return summaries[i].Path < summaries[j].Path
+		}
+		if summaries[i].Method != summaries[j].Method {
+			return summaries[i].Method < summaries[j].Method
+		}
+		return summaries[i].LogStream < summaries[j].LogStream
+	})
+
+	return summaries
+}
+
+// OutputQueryParams writes the query parameter names of each path using the given formatter
+func (a *Analyzer) OutputQueryParams(result *models.AnalysisResult, formatter output.Formatter, writer io.Writer) error {
+	summaries := SummarizeQueryParams(result.PathMetrics)
+
+	records := make([]output.Record, 0, len(summaries))
+	for _, summary := range summaries {
+		records = append(records, a.metricsRecord(summary, summary.Method, summary.LogStream))
+	}
+
+	return formatter.Format(&output.Table{
+		Columns: a.metricsColumns(models.QueryParamSummaryColumns),
+		Records: records,
+	}, writer)
+}
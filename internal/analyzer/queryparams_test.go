@@ -0,0 +1,87 @@
+package analyzer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/config"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/output"
+)
+
+func TestAnalyzer_QueryParamReport(t *testing.T) {
+	analyzer := NewAnalyzer()
+	analyzer.SetQueryParamReport(true)
+
+	var events []*models.LogEvent
+	events = append(events, requestEvents(1, "GET", "/search?q=alice&page=2", 10)...)
+	events = append(events, requestEvents(2, "GET", "/search?q=bob&q=carol", 10)...)
+	events = append(events, requestEvents(3, "GET", "/search?sort=name&page=3", 10)...)
+	events = append(events, requestEvents(4, "GET", "/users/1?tab=posts", 10)...)
+	events = append(events, requestEvents(5, "GET", "/users/2", 10)...)
+	events = append(events, requestEvents(6, "GET", "/health", 10)...)
+
+	result := analyzer.AnalyzeLogEvents(events, time.Time{}, time.Time{})
+
+	assert.Equal(t, map[string]int{"q": 2, "page": 2, "sort": 1}, result.PathMetrics["/search"].QueryParams)
+	assert.Nil(t, result.PathMetrics["/health"].QueryParams)
+
+	summaries := SummarizeQueryParams(result.PathMetrics)
+	require.Len(t, summaries, 2)
+	assert.Equal(t, &models.QueryParamSummary{
+		Path:  "/search",
+		Count: 3,
+		Params: []*models.QueryParamCount{
+			{Name: "page", Count: 2},
+			{Name: "q", Count: 2},
+			{Name: "sort", Count: 1},
+		},
+	}, summaries[0])
+	assert.Equal(t, "/users/:id", summaries[1].Path)
+
+	var buf bytes.Buffer
+	require.NoError(t, analyzer.OutputQueryParams(result, output.NewCSVFormatter(), &buf))
+	assert.Equal(t, `path,count,params
+/search,3,"page (2), q (2), sort (1)"
+/users/:id,2,tab (1)
+`, buf.String())
+}
+
+func TestAnalyzer_QueryParamReportDisabled(t *testing.T) {
+	analyzer := NewAnalyzer()
+
+	result := analyzer.AnalyzeLogEvents(requestEvents(1, "GET", "/search?q=alice", 10), time.Time{}, time.Time{})
+
+	assert.Nil(t, result.PathMetrics["/search"].QueryParams)
+	assert.Empty(t, SummarizeQueryParams(result.PathMetrics))
+}
+
+func TestAnalyzer_QueryRulesWithCollapse(t *testing.T) {
+	analyzer := NewAnalyzer()
+	analyzer.SetQueryParamReport(true)
+	require.NoError(t, analyzer.SetCollapseThreshold(2))
+	require.NoError(t, analyzer.SetNormalization(config.NormalizationConfig{
+		Query: []config.QueryRule{{Prefix: "/tags/", Params: []string{"type"}, Values: true}},
+	}))
+
+	var events []*models.LogEvent
+	for i, tag := range []string{"go", "ruby", "rust"} {
+		events = append(events, requestEvents(i, "GET", "/tags/"+tag+"?type=post&page=1", 10)...)
+		events = append(events, requestEvents(10+i, "GET", "/tags/"+tag+"?type=user", 10)...)
+	}
+
+	result := analyzer.AnalyzeLogEvents(events, time.Time{}, time.Time{})
+
+	// The kept query parameters are not segments, so they survive collapsing
+	assert.Equal(t, []*models.CollapsedSegment{
+		{Path: "/tags/:param", Position: 2, Distinct: 3, Count: 6, Samples: []string{"go", "ruby", "rust"}},
+	}, result.Collapsed)
+	require.Contains(t, result.PathMetrics, "/tags/:param?type=post")
+	require.Contains(t, result.PathMetrics, "/tags/:param?type=user")
+	assert.Len(t, result.PathMetrics, 2)
+	assert.Equal(t, map[string]int{"type": 3, "page": 3}, result.PathMetrics["/tags/:param?type=post"].QueryParams)
+}
@@ -0,0 +1,211 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/config"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+// Capture group names recognized in the patterns of custom log formats
+const (
+	groupMethod     = "method"
+	groupPath       = "path"
+	groupStatus     = "status"
+	groupDuration   = "duration"
+	groupRequestID  = "request_id"
+	groupTimestamp  = "timestamp"
+	groupView       = "view"
+	groupDB         = "db"
+	groupController = "controller"
+	groupAction     = "action"
+	groupFormat     = "format"
+	groupPID        = "pid"
+	groupThread     = "thread"
+)
+
+// customFormatGroups lists the capture groups allowed in custom log format patterns
+var customFormatGroups = []string{
+	groupMethod, groupPath, groupStatus, groupDuration, groupRequestID, groupTimestamp,
+	groupView, groupDB, groupController, groupAction, groupFormat, groupPID, groupThread,
+}
+
+// RegexParser parses log lines with the regular expressions of a custom log format from the config file
+type RegexParser struct {
+	request         *regexp.Regexp
+	started         *regexp.Regexp
+	completed       *regexp.Regexp
+	timestampLayout string
+}
+
+// NewRegexParser creates a RegexParser from a custom log format
+// A format needs a request pattern, or started and completed patterns, each capturing the fields it requires
+func NewRegexParser(format config.LogFormat) (*RegexParser, error) {
+	if format.Request == "" && (format.Started == "" || format.Completed == "") {
+		return nil, fmt.Errorf("log format %q must specify a request pattern or both started and completed patterns", format.Name)
+	}
+	if (format.Started == "") != (format.Completed == "") {
+		return nil, fmt.Errorf("log format %q must specify both started and completed patterns", format.Name)
+	}
+
+	parser := &RegexParser{timestampLayout: format.TimestampLayout}
+	var err error
+	if parser.request, err = compileFormatPattern(format.Name, "request", format.Request, groupMethod, groupPath, groupStatus, groupDuration); err != nil {
+		return nil, err
+	}
+	if parser.started, err = compileFormatPattern(format.Name, "started", format.Started, groupMethod, groupPath); err != nil {
+		return nil, err
+	}
+	if parser.completed, err = compileFormatPattern(format.Name, "completed", format.Completed, groupStatus, groupDuration); err != nil {
+		return nil, err
+	}
+
+	return parser, nil
+}
+
+// compileFormatPattern compiles a pattern of a custom log format and checks its capture groups
+// An empty pattern compiles to nil
+func compileFormatPattern(name, kind, pattern string, requiredGroups ...string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile %s pattern of log format %q: %w", kind, name, err)
+	}
+
+	groups := regex.SubexpNames()
+	for _, group := range groups[1:] {
+		if group != "" && !slices.Contains(customFormatGroups, group) {
+			return nil, fmt.Errorf("unknown capture group %q in %s pattern of log format %q (supported: %s)",
+				group, kind, name, strings.Join(customFormatGroups, ", "))
+		}
+	}
+	for _, group := range requiredGroups {
+		if !slices.Contains(groups, group) {
+			return nil, fmt.Errorf("%s pattern of log format %q must capture %s", kind, name, strings.Join(requiredGroups, ", "))
+		}
+	}
+
+	return regex, nil
+}
+
+// ParseLogEntry parses a log line with the first pattern of the format that matches it
+func (p *RegexParser) ParseLogEntry(logLine string) (*models.LogEntry, error) {
+	logLine = strings.TrimSpace(logLine)
+	if logLine == "" {
+		return nil, errEmptyLine
+	}
+
+	if fields := matchGroups(p.request, logLine); fields != nil {
+		return newRequestEntry(fields.lookup, p.parseTimestamp, logLine)
+	}
+	if fields := matchGroups(p.started, logLine); fields != nil {
+		return p.parseStarted(fields)
+	}
+	if fields := matchGroups(p.completed, logLine); fields != nil {
+		return p.parseCompleted(fields)
+	}
+
+	return nil, fmt.Errorf("%w: %s", errUnrecognizedFormat, logLine)
+}
+
+// parseStarted builds a Started entry from the groups captured by the started pattern
+func (p *RegexParser) parseStarted(fields capturedGroups) (*models.LogEntry, error) {
+	entry := &models.LogEntry{
+		Type:      "Started",
+		Method:    strings.ToUpper(fields[groupMethod]),
+		Path:      fields[groupPath],
+		SessionID: fields[groupRequestID],
+		ProcessID: fields[groupPID],
+		ThreadID:  fields[groupThread],
+	}
+
+	if timestamp := fields[groupTimestamp]; timestamp != "" {
+		var err error
+		entry.Timestamp, err = p.parseTimestamp(timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidTimestamp, err)
+		}
+	}
+
+	return entry, nil
+}
+
+// parseCompleted builds a Completed entry from the groups captured by the completed pattern
+func (p *RegexParser) parseCompleted(fields capturedGroups) (*models.LogEntry, error) {
+	statusCode, err := strconv.Atoi(fields[groupStatus])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid status code: %w", errInvalidNumber, err)
+	}
+	duration, err := parseMilliseconds(fields[groupDuration])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid duration: %w", errInvalidNumber, err)
+	}
+
+	entry := &models.LogEntry{
+		Type:       "Completed",
+		StatusCode: statusCode,
+		StatusText: http.StatusText(statusCode),
+		Duration:   int(math.Round(duration)),
+		SessionID:  fields[groupRequestID],
+		ProcessID:  fields[groupPID],
+		ThreadID:   fields[groupThread],
+	}
+	if view := fields[groupView]; view != "" {
+		entry.ViewDuration, _ = parseMilliseconds(view)
+	}
+	if db := fields[groupDB]; db != "" {
+		entry.DBDuration, _ = parseMilliseconds(db)
+	}
+
+	return entry, nil
+}
+
+// parseTimestamp parses a timestamp group with the layout of the format, or as a structured timestamp
+func (p *RegexParser) parseTimestamp(value string) (time.Time, error) {
+	if p.timestampLayout == "" {
+		return parseStructuredTimestamp(value)
+	}
+	return time.Parse(p.timestampLayout, value)
+}
+
+// capturedGroups holds the non-empty named groups captured from a log line
+type capturedGroups map[string]string
+
+// matchGroups matches a log line against a pattern; returns nil if the pattern is nil or does not match
+func matchGroups(regex *regexp.Regexp, logLine string) capturedGroups {
+	if regex == nil {
+		return nil
+	}
+	match := regex.FindStringSubmatch(logLine)
+	if match == nil {
+		return nil
+	}
+
+	fields := make(capturedGroups)
+	for i, name := range regex.SubexpNames() {
+		if name != "" && match[i] != "" {
+			fields[name] = match[i]
+		}
+	}
+	return fields
+}
+
+// lookup returns the first of keys that was captured, so groups can be read like structured log fields
+func (c capturedGroups) lookup(keys []string) (string, bool) {
+	for _, key := range keys {
+		if value, exists := c[key]; exists {
+			return value, true
+		}
+	}
+	return "", false
+}
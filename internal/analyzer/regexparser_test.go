@@ -0,0 +1,184 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/config"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+func TestNewRegexParser_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   config.LogFormat
+		errorMsg string
+	}{
+		{
+			name:     "no patterns",
+			format:   config.LogFormat{Name: "custom"},
+			errorMsg: `log format "custom" must specify a request pattern or both started and completed patterns`,
+		},
+		{
+			name:     "started without completed",
+			format:   config.LogFormat{Name: "custom", Request: `(?P<method>\w+) (?P<path>\S+) (?P<status>\d+) (?P<duration>\d+)`, Started: `(?P<method>\w+) (?P<path>\S+)`},
+			errorMsg: `log format "custom" must specify both started and completed patterns`,
+		},
+		{
+			name:     "invalid regex",
+			format:   config.LogFormat{Name: "custom", Request: `(?P<method>\w+`},
+			errorMsg: `failed to compile request pattern of log format "custom"`,
+		},
+		{
+			name:     "unknown capture group",
+			format:   config.LogFormat{Name: "custom", Request: `(?P<method>\w+) (?P<path>\S+) (?P<status>\d+) (?P<elapsed>\d+)`},
+			errorMsg: `unknown capture group "elapsed" in request pattern of log format "custom"`,
+		},
+		{
+			name:     "missing required capture group",
+			format:   config.LogFormat{Name: "custom", Started: `Started (?P<method>\w+)`, Completed: `Completed (?P<status>\d+) in (?P<duration>\d+)ms`},
+			errorMsg: `started pattern of log format "custom" must capture method, path`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRegexParser(tt.format)
+			assert.ErrorContains(t, err, tt.errorMsg)
+		})
+	}
+}
+
+func TestRegexParser_ParseLogEntry(t *testing.T) {
+	parser, err := NewRegexParser(config.LogFormat{
+		Name:            "prefixed",
+		Request:         `^ACCESS (?P<method>\w+) (?P<path>\S+) (?P<status>\d+) (?P<duration>[\d.]+) (?P<timestamp>\S+ \S+)$`,
+		Started:         `^\[(?P<timestamp>[^\]]+)\]\[web(?: (?P<pid>\d+):(?P<thread>\w+))?\]\[(?P<request_id>[^\]]+)\] Started (?P<method>\w+) "(?P<path>[^"]+)"`,
+		Completed:       `^\[[^\]]+\]\[web(?: (?P<pid>\d+):(?P<thread>\w+))?\]\[(?P<request_id>[^\]]+)\] Completed (?P<status>\d+) .* in (?P<duration>\d+)ms(?: \(Views: (?P<view>[\d.]+)ms \| ActiveRecord: (?P<db>[\d.]+)ms)?`,
+		TimestampLayout: "2006/01/02 15:04:05",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		input      string
+		want       *models.LogEntry
+		wantReason string
+	}{
+		{
+			name:  "request line",
+			input: `ACCESS get /users/1 404 7.6 2023/01/01 12:00:00`,
+			want: &models.LogEntry{
+				Type:       "Request",
+				Method:     "GET",
+				Path:       "/users/1",
+				StatusCode: 404,
+				StatusText: "Not Found",
+				Duration:   8,
+				Timestamp:  time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "started line",
+			input: `[2023/01/01 12:00:00][web][abc-123] Started POST "/orders" for 127.0.0.1`,
+			want: &models.LogEntry{
+				Type:      "Started",
+				Method:    "POST",
+				Path:      "/orders",
+				SessionID: "abc-123",
+				Timestamp: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "completed line with optional groups",
+			input: `[2023/01/01 12:00:01][web][abc-123] Completed 201 Created in 150ms (Views: 100.0ms | ActiveRecord: 50.5ms)`,
+			want: &models.LogEntry{
+				Type:         "Completed",
+				StatusCode:   201,
+				StatusText:   "Created",
+				Duration:     150,
+				ViewDuration: 100.0,
+				DBDuration:   50.5,
+				SessionID:    "abc-123",
+			},
+		},
+		{
+			name:  "started line with process and thread",
+			input: `[2023/01/01 12:00:00][web 4321:t7][abc-123] Started GET "/" for 127.0.0.1`,
+			want: &models.LogEntry{
+				Type:      "Started",
+				Method:    "GET",
+				Path:      "/",
+				SessionID: "abc-123",
+				ProcessID: "4321",
+				ThreadID:  "t7",
+				Timestamp: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:       "invalid timestamp",
+			input:      `[2023-01-01 12:00:00][web][abc-123] Started GET "/" for 127.0.0.1`,
+			wantReason: ParseFailureInvalidTimestamp,
+		},
+		{
+			name:       "unrecognized line",
+			input:      `Started GET "/" for 127.0.0.1 at 2023-01-01 12:00:00 +0000`,
+			wantReason: ParseFailureUnrecognizedFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.ParseLogEntry(tt.input)
+			if tt.wantReason != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantReason, parseFailureReason(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAnalyzer_AddLogFormats(t *testing.T) {
+	started := config.LogFormat{
+		Name:      "prefixed",
+		Started:   `\[(?P<request_id>\w+)\] Started (?P<method>\w+) "(?P<path>[^"]+)"`,
+		Completed: `\[(?P<request_id>\w+)\] Completed (?P<status>\d+) .* in (?P<duration>\d+)ms`,
+	}
+
+	analyzer := NewAnalyzer()
+	require.NoError(t, analyzer.AddLogFormats([]config.LogFormat{started}))
+	require.NoError(t, analyzer.SetLogFormat("prefixed"))
+
+	result := analyzer.AnalyzeLogEvents([]*models.LogEvent{
+		{Message: `app=web pid=7 [a] Started GET "/users/1" for 127.0.0.1`},
+		{Message: `app=web pid=7 [b] Started GET "/users/2" for 127.0.0.1`},
+		{Message: `app=web pid=7 [b] Completed 200 OK in 30ms`},
+		{Message: `app=web pid=7 [a] Completed 200 OK in 10ms`},
+	}, time.Time{}, time.Time{})
+
+	require.Contains(t, result.PathMetrics, "/users/:id")
+	assert.Equal(t, 2, result.PathMetrics["/users/:id"].Count)
+	assert.Equal(t, 20.0, result.PathMetrics["/users/:id"].AverageTime)
+	assert.Equal(t, map[string]int{models.MatchByRequestID: 2}, result.MatchedBy)
+
+	tests := []struct {
+		name     string
+		formats  []config.LogFormat
+		errorMsg string
+	}{
+		{name: "missing name", formats: []config.LogFormat{{Request: started.Started}}, errorMsg: "log format at index 0 must specify a name"},
+		{name: "built-in name", formats: []config.LogFormat{{Name: LogFormatJSON}}, errorMsg: `log format "json" conflicts with a built-in log format`},
+		{name: "duplicate name", formats: []config.LogFormat{started}, errorMsg: `log format "prefixed" is defined more than once`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorContains(t, analyzer.AddLogFormats(tt.formats), tt.errorMsg)
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	// routeVerbRegex matches the Verb column of `rails routes`, e.g. GET or GET|POST
+	routeVerbRegex = regexp.MustCompile(`^[A-Z]+(\|[A-Z]+)*$`)
+	// routeParamRegex matches a dynamic segment (:id) or glob (*path) of a route pattern
+	routeParamRegex = regexp.MustCompile(`^[:*][a-zA-Z_]\w*`)
+	// engineSectionRegex matches the heading of the routes of a mounted engine
+	engineSectionRegex = regexp.MustCompile(`^Routes for (\S+):$`)
+)
+
+// formatSuffix is the optional format segment Rails appends to route patterns
+const formatSuffix = "(.:format)"
+
+// Route is a route pattern of the Rails application, e.g. /articles/:slug(.:format)
+type Route struct {
+	Method   string // Verbs of the route, e.g. GET or GET|POST; empty for routes accepting any verb, such as mounts
+	Pattern  string
+	template string
+	prefix   string
+	verbs    []string
+	regex    *regexp.Regexp
+}
+
+// NewRoute compiles a route pattern into a matcher
+// Dynamic segments match up to the next slash, dot or question mark, globs match the rest of the path
+func NewRoute(method, pattern string) (*Route, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	prefix := ""
+	prefixDone := false
+	for i := 0; i < len(pattern); {
+		if param := routeParamRegex.FindString(pattern[i:]); param != "" {
+			if param[0] == '*' {
+				expr.WriteString(".+")
+			} else {
+				expr.WriteString(`[^/.?]+`)
+			}
+			prefixDone = true
+			i += len(param)
+			continue
+		}
+
+		switch ch := pattern[i]; ch {
+		case '(':
+			expr.WriteString("(?:")
+			prefixDone = true
+		case ')':
+			expr.WriteString(")?")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(ch)))
+			if !prefixDone {
+				prefix += string(ch)
+			}
+		}
+		i++
+	}
+	expr.WriteString("$")
+
+	regex, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse route %s: %w", pattern, err)
+	}
+
+	template := strings.TrimSuffix(pattern, formatSuffix)
+	if template == "" {
+		template = "/"
+	}
+
+	var verbs []string
+	if method != "" {
+		verbs = strings.Split(strings.ToUpper(method), "|")
+	}
+
+	return &Route{
+		Method:   method,
+		Pattern:  pattern,
+		template: template,
+		prefix:   prefix,
+		verbs:    verbs,
+		regex:    regex,
+	}, nil
+}
+
+// Template returns the path the requests matching the route are aggregated under
+func (r *Route) Template() string {
+	return r.template
+}
+
+// Match checks if a request with the method and path without query string matches the route
+// HEAD requests match GET routes like in Rails; an empty method matches any route
+func (r *Route) Match(method, path string) bool {
+	return r.allowsMethod(method) && strings.HasPrefix(path, r.prefix) && r.regex.MatchString(path)
+}
+
+// allowsMethod checks if the verbs of the route include the request method
+func (r *Route) allowsMethod(method string) bool {
+	if len(r.verbs) == 0 || method == "" {
+		return true
+	}
+	method = strings.ToUpper(method)
+	if slices.Contains(r.verbs, method) {
+		return true
+	}
+	return method == "HEAD" && slices.Contains(r.verbs, "GET")
+}
+
+// RouteTable matches request paths against the routes of a Rails application in order of precedence
+type RouteTable struct {
+	routes []*Route
+}
+
+// LoadRouteTable reads a `rails routes` dump, either the text output or JSON
+func LoadRouteTable(path string) (*RouteTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read routes file %s: %w", path, err)
+	}
+
+	table, err := ParseRoutes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse routes file %s: %w", path, err)
+	}
+	return table, nil
+}
+
+// ParseRoutes parses a `rails routes` dump; input starting with [ is read as JSON
+func ParseRoutes(data []byte) (*RouteTable, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return parseJSONRoutes(data)
+	}
+	return parseTextRoutes(data)
+}
+
+// parseTextRoutes parses the table printed by `rails routes`
+// Routes of mounted engines are prefixed with the path the engine is mounted at
+func parseTextRoutes(data []byte) (*RouteTable, error) {
+	table := &RouteTable{}
+	mounts := make(map[string]string)
+	mountPath := ""
+	skipSection := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if match := engineSectionRegex.FindStringSubmatch(line); match != nil {
+			// Routes of engines whose mount path is unknown cannot be matched against request paths
+			mount, mounted := mounts[match[1]]
+			mountPath, skipSection = mount, !mounted
+			continue
+		}
+		if skipSection {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		patternIndex := -1
+		for i, field := range fields {
+			if strings.HasPrefix(field, "/") || strings.HasPrefix(field, "(") {
+				patternIndex = i
+				break
+			}
+		}
+		if patternIndex < 0 {
+			continue
+		}
+
+		pattern := fields[patternIndex]
+		method := ""
+		if patternIndex > 0 && routeVerbRegex.MatchString(fields[patternIndex-1]) {
+			method = fields[patternIndex-1]
+		}
+
+		// An engine mount has the engine class instead of a controller#action target
+		target := fields[patternIndex+1:]
+		if method == "" && len(target) == 1 && !strings.Contains(target[0], "#") {
+			mounts[target[0]] = joinRoutePath(mountPath, pattern)
+		}
+
+		if err := table.add(method, joinRoutePath(mountPath, pattern)); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read routes: %w", err)
+	}
+
+	return table, nil
+}
+
+// parseJSONRoutes parses an array of routes with verb and path fields
+// Keys are matched case-insensitively, so "URI Pattern" is accepted as well as "path"
+func parseJSONRoutes(data []byte) (*RouteTable, error) {
+	var rows []map[string]any
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode routes JSON: %w", err)
+	}
+
+	table := &RouteTable{}
+	for i, row := range rows {
+		fields := make(map[string]string, len(row))
+		for key, value := range row {
+			if s, ok := value.(string); ok {
+				fields[strings.ReplaceAll(strings.ToLower(key), " ", "_")] = s
+			}
+		}
+
+		pattern := firstNonEmpty(fields["path"], fields["uri_pattern"], fields["uri"])
+		if pattern == "" {
+			return nil, fmt.Errorf("route at index %d must specify a path", i)
+		}
+		if err := table.add(fields["verb"], pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	return table, nil
+}
+
+// add appends a route with lower precedence than the routes already in the table
+func (t *RouteTable) add(method, pattern string) error {
+	route, err := NewRoute(method, pattern)
+	if err != nil {
+		return err
+	}
+	t.routes = append(t.routes, route)
+	return nil
+}
+
+// Len returns the number of routes in the table
+func (t *RouteTable) Len() int {
+	return len(t.routes)
+}
+
+// Match returns the template of the first route matching a request with the method and path without query string
+func (t *RouteTable) Match(method, path string) (string, bool) {
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	for _, route := range t.routes {
+		if route.Match(method, path) {
+			return route.Template(), true
+		}
+	}
+	return "", false
+}
+
+// joinRoutePath prefixes a route pattern with the path its engine is mounted at
+func joinRoutePath(mountPath, pattern string) string {
+	if mountPath == "" {
+		return pattern
+	}
+	if pattern == "/" {
+		return mountPath
+	}
+	return strings.TrimSuffix(mountPath, "/") + pattern
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package analyzer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRoutesText = `                  Prefix Verb   URI Pattern                                  Controller#Action
+                    root GET    /                                            home#index
+                articles GET    /articles(.:format)                          articles#index
+                         POST   /articles(.:format)                          articles#create
+             new_article GET    /articles/new(.:format)                      articles#new
+                 article GET    /articles/:slug(.:format)                    articles#show
+                         PATCH  /articles/:slug(.:format)                    articles#update
+        article_comments GET    /articles/:article_slug/comments(.:format)   comments#index
+                   about GET    (/:locale)/about(.:format)                   pages#about
+                   files GET    /files/*path                                 files#show
+                    cafe GET    /cafe01(.:format)                            cafes#show
+             rails_admin        /admin                                       RailsAdmin::Engine
+                  sidekiq       /sidekiq                                     Sidekiq::Web
+
+Routes for RailsAdmin::Engine:
+               dashboard GET    /                                            rails_admin/main#dashboard
+                   index GET|POST /:model_name(.:format)                     rails_admin/main#index
+
+Routes for Unmounted::Engine:
+                   index GET    /:anything(.:format)                         unmounted#index
+`
+
+func TestParseRoutes_Text(t *testing.T) {
+	table, err := ParseRoutes([]byte(testRoutesText))
+	require.NoError(t, err)
+	assert.Equal(t, 14, table.Len())
+
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{path: "/", want: "/", ok: true},
+		{path: "/articles", want: "/articles", ok: true},
+		{path: "/articles.json", want: "/articles", ok: true},
+		{path: "/articles/new", want: "/articles/new", ok: true},
+		{path: "/articles/my-first-post", want: "/articles/:slug", ok: true},
+		{path: "/articles/my-first-post/", want: "/articles/:slug", ok: true},
+		{path: "/articles/my-first-post.json", want: "/articles/:slug", ok: true},
+		{path: "/articles/my-first-post/comments", want: "/articles/:article_slug/comments", ok: true},
+		{path: "/about", want: "(/:locale)/about", ok: true},
+		{path: "/ja/about", want: "(/:locale)/about", ok: true},
+		{path: "/files/a/b/c.pdf", want: "/files/*path", ok: true},
+		{path: "/cafe01", want: "/cafe01", ok: true},
+		{path: "/admin", want: "/admin", ok: true},
+		{path: "/admin/users", want: "/admin/:model_name", ok: true},
+		{path: "/sidekiq", want: "/sidekiq", ok: true},
+		{path: "/sidekiq/queues", ok: false},
+		{path: "/users/123", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := table.Match("GET", tt.path)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRouteTable_MatchMethod(t *testing.T) {
+	table, err := ParseRoutes([]byte(testRoutesText))
+	require.NoError(t, err)
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+		ok     bool
+	}{
+		{method: "POST", path: "/articles", want: "/articles", ok: true},
+		{method: "PATCH", path: "/articles/my-first-post", want: "/articles/:slug", ok: true},
+		{method: "HEAD", path: "/articles/my-first-post", want: "/articles/:slug", ok: true},
+		{method: "post", path: "/admin/users", want: "/admin/:model_name", ok: true},
+		{method: "DELETE", path: "/sidekiq", want: "/sidekiq", ok: true},
+		{method: "", path: "/cafe01", want: "/cafe01", ok: true},
+		{method: "DELETE", path: "/articles/my-first-post", ok: false},
+		{method: "POST", path: "/articles/new", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			got, ok := table.Match(tt.method, tt.path)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseRoutes_JSON(t *testing.T) {
+	table, err := ParseRoutes([]byte(`[
+  {"name": "article", "verb": "GET", "path": "/articles/:slug(.:format)", "reqs": "articles#show"},
+  {"Prefix": "users", "Verb": "GET", "URI Pattern": "/users/:id(.:format)", "Controller#Action": "users#show"}
+]`))
+	require.NoError(t, err)
+	assert.Equal(t, 2, table.Len())
+
+	got, ok := table.Match("GET", "/articles/hello-world")
+	assert.True(t, ok)
+	assert.Equal(t, "/articles/:slug", got)
+
+	got, ok = table.Match("GET", "/users/42")
+	assert.True(t, ok)
+	assert.Equal(t, "/users/:id", got)
+}
+
+func TestParseRoutes_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		errorMsg string
+	}{
+		{name: "invalid JSON", input: `[{"path": }]`, errorMsg: "failed to decode routes JSON"},
+		{name: "JSON route without path", input: `[{"verb": "GET"}]`, errorMsg: "route at index 0 must specify a path"},
+		{name: "unbalanced parenthesis", input: "GET /articles(/:page(.:format) articles#index", errorMsg: "failed to parse route /articles(/:page(.:format)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRoutes([]byte(tt.input))
+			assert.ErrorContains(t, err, tt.errorMsg)
+		})
+	}
+}
+
+func TestLoadRouteTable(t *testing.T) {
+	routesPath := filepath.Join(t.TempDir(), "routes.txt")
+	require.NoError(t, os.WriteFile(routesPath, []byte(testRoutesText), 0644))
+
+	table, err := LoadRouteTable(routesPath)
+	require.NoError(t, err)
+	assert.Equal(t, 14, table.Len())
+
+	_, err = LoadRouteTable(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.ErrorContains(t, err, "failed to read routes file")
+}
@@ -0,0 +1,149 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+// Sort keys supported by SelectOptions
+const (
+	SortByCount     = "count"
+	SortByAvg       = "avg"
+	SortByMax       = "max"
+	SortByP95       = "p95"
+	SortByTotalTime = "total_time"
+	SortByErrorRate = "error_rate"
+	SortByDBTime    = "db_time"
+)
+
+// Sort orders supported by SelectOptions
+const (
+	OrderDesc = "desc"
+	OrderAsc  = "asc"
+)
+
+// sortValues maps each sort key to the metric value used for ordering
+var sortValues = map[string]func(*models.PathMetrics) float64{
+	SortByCount:     func(m *models.PathMetrics) float64 { return float64(m.Count) },
+	SortByAvg:       func(m *models.PathMetrics) float64 { return m.AverageTime },
+	SortByMax:       func(m *models.PathMetrics) float64 { return float64(m.MaxTime) },
+	SortByP95:       func(m *models.PathMetrics) float64 { return float64(m.P95Time) },
+	SortByTotalTime: func(m *models.PathMetrics) float64 { return totalTime(m) },
+	SortByErrorRate: func(m *models.PathMetrics) float64 { return serverErrorRate(m) },
+	SortByDBTime:    func(m *models.PathMetrics) float64 { return m.TotalDBDuration },
+}
+
+// SelectOptions controls sorting, top-N and threshold filtering of path metrics before output
+type SelectOptions struct {
+	SortBy   string  // Metric to sort by (see SortBy* constants)
+	Order    string  // "desc" or "asc"
+	Top      int     // Maximum number of paths to output, 0 means unlimited
+	MinCount int     // Minimum request count for a path to be included
+	MinAvgMs float64 // Minimum average time in milliseconds for a path to be included
+}
+
+// DefaultSelectOptions returns options that sort by count in descending order without filtering
+func DefaultSelectOptions() SelectOptions {
+	return SelectOptions{
+		SortBy: SortByCount,
+		Order:  OrderDesc,
+	}
+}
+
+// SortKeys returns the names of all supported sort keys in alphabetical order
+func SortKeys() []string {
+	keys := make([]string, 0, len(sortValues))
+	for key := range sortValues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Validate checks that the options refer to supported sort keys and sane limits
+func (o SelectOptions) Validate() error {
+	if _, exists := sortValues[o.SortBy]; !exists {
+		return fmt.Errorf("unsupported sort key %q (supported: %s)", o.SortBy, strings.Join(SortKeys(), ", "))
+	}
+	if o.Order != OrderDesc && o.Order != OrderAsc {
+		return fmt.Errorf("unsupported sort order %q (supported: %s, %s)", o.Order, OrderAsc, OrderDesc)
+	}
+	if o.Top < 0 {
+		return fmt.Errorf("top must not be negative: %d", o.Top)
+	}
+	if o.MinCount < 0 {
+		return fmt.Errorf("min count must not be negative: %d", o.MinCount)
+	}
+	if o.MinAvgMs < 0 {
+		return fmt.Errorf("min average time must not be negative: %g", o.MinAvgMs)
+	}
+	return nil
+}
+
+// SelectMetrics filters, sorts and truncates path metrics according to the options
+// Paths with equal sort values are ordered alphabetically to keep the output stable
+func SelectMetrics(pathMetrics map[string]*models.PathMetrics, opts SelectOptions) []*models.PathMetrics {
+	selected := make([]*models.PathMetrics, 0, len(pathMetrics))
+	for _, metrics := range pathMetrics {
+		if metrics.Count < opts.MinCount || metrics.AverageTime < opts.MinAvgMs {
+			continue
+		}
+		selected = append(selected, metrics)
+	}
+
+	value, exists := sortValues[opts.SortBy]
+	if !exists {
+		value = sortValues[SortByCount]
+	}
+	ascending := opts.Order == OrderAsc
+
+	sort.Slice(selected, func(i, j int) bool {
+		vi, vj := value(selected[i]), value(selected[j])
+		if vi != vj {
+			if ascending {
+				return vi < vj
+			}
+			return vi > vj
+		}
+		if selected[i].Path != selected[j].Path {
+			return selected[i].Path < selected[j].Path
+		}
+		if selected[i].Method != selected[j].Method {
+			return selected[i].Method < selected[j].Method
+		}
+		return selected[i].LogStream < selected[j].LogStream
+	})
+
+	if opts.Top > 0 && len(selected) > opts.Top {
+		selected = selected[:opts.Top]
+	}
+
+	return selected
+}
+
+// totalTime returns the total time consumed by all requests of the path in milliseconds
+func totalTime(metrics *models.PathMetrics) float64 {
+	return metrics.AverageTime * float64(metrics.Count)
+}
+
+// serverErrorRate returns the ratio of 5xx responses to all requests of the path
+func serverErrorRate(metrics *models.PathMetrics) float64 {
+	if metrics.Count == 0 {
+		return 0
+	}
+	return float64(countStatusRange(metrics.StatusCodes, 500)) / float64(metrics.Count)
+}
+
+// countStatusRange counts the responses whose status code is within [base, base+100)
+func countStatusRange(statusCodes map[int]int, base int) int {
+	total := 0
+	for code, count := range statusCodes {
+		if code >= base && code < base+100 {
+			total += count
+		}
+	}
+	return total
+}
@@ -0,0 +1,170 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+func newSelectorTestMetrics() map[string]*models.PathMetrics {
+	return map[string]*models.PathMetrics{
+		"/users/:id": {
+			Path:            "/users/:id",
+			Count:           100,
+			AverageTime:     50.0,
+			MaxTime:         400,
+			P95Time:         200,
+			StatusCodes:     map[int]int{200: 99, 500: 1},
+			TotalDBDuration: 1000.0,
+		},
+		"/reports": {
+			Path:            "/reports",
+			Count:           10,
+			AverageTime:     900.0,
+			MaxTime:         3000,
+			P95Time:         2500,
+			StatusCodes:     map[int]int{200: 10},
+			TotalDBDuration: 8000.0,
+		},
+		"/health": {
+			Path:        "/health",
+			Count:       500,
+			AverageTime: 1.0,
+			MaxTime:     5,
+			P95Time:     2,
+			StatusCodes: map[int]int{200: 500},
+		},
+		"/checkout": {
+			Path:            "/checkout",
+			Count:           4,
+			AverageTime:     300.0,
+			MaxTime:         600,
+			P95Time:         600,
+			StatusCodes:     map[int]int{200: 2, 502: 2},
+			TotalDBDuration: 100.0,
+		},
+	}
+}
+
+func selectedPaths(metrics []*models.PathMetrics) []string {
+	paths := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		paths = append(paths, m.Path)
+	}
+	return paths
+}
+
+func TestSelectMetrics(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     SelectOptions
+		expected []string
+	}{
+		{
+			name:     "default sorts by count descending",
+			opts:     DefaultSelectOptions(),
+			expected: []string{"/health", "/users/:id", "/reports", "/checkout"},
+		},
+		{
+			name:     "sort by count ascending",
+			opts:     SelectOptions{SortBy: SortByCount, Order: OrderAsc},
+			expected: []string{"/checkout", "/reports", "/users/:id", "/health"},
+		},
+		{
+			name:     "sort by average time",
+			opts:     SelectOptions{SortBy: SortByAvg, Order: OrderDesc},
+			expected: []string{"/reports", "/checkout", "/users/:id", "/health"},
+		},
+		{
+			name:     "sort by max time",
+			opts:     SelectOptions{SortBy: SortByMax, Order: OrderDesc},
+			expected: []string{"/reports", "/checkout", "/users/:id", "/health"},
+		},
+		{
+			name:     "sort by p95",
+			opts:     SelectOptions{SortBy: SortByP95, Order: OrderDesc},
+			expected: []string{"/reports", "/checkout", "/users/:id", "/health"},
+		},
+		{
+			name:     "sort by total time",
+			opts:     SelectOptions{SortBy: SortByTotalTime, Order: OrderDesc},
+			expected: []string{"/reports", "/users/:id", "/checkout", "/health"},
+		},
+		{
+			name:     "sort by error rate with ties ordered by path",
+			opts:     SelectOptions{SortBy: SortByErrorRate, Order: OrderDesc},
+			expected: []string{"/checkout", "/users/:id", "/health", "/reports"},
+		},
+		{
+			name:     "sort by DB time",
+			opts:     SelectOptions{SortBy: SortByDBTime, Order: OrderDesc},
+			expected: []string{"/reports", "/users/:id", "/checkout", "/health"},
+		},
+		{
+			name:     "top N after sorting",
+			opts:     SelectOptions{SortBy: SortByTotalTime, Order: OrderDesc, Top: 2},
+			expected: []string{"/reports", "/users/:id"},
+		},
+		{
+			name:     "minimum count threshold",
+			opts:     SelectOptions{SortBy: SortByCount, Order: OrderDesc, MinCount: 10},
+			expected: []string{"/health", "/users/:id", "/reports"},
+		},
+		{
+			name:     "minimum average time threshold",
+			opts:     SelectOptions{SortBy: SortByCount, Order: OrderDesc, MinAvgMs: 50},
+			expected: []string{"/users/:id", "/reports", "/checkout"},
+		},
+		{
+			name:     "thresholds are applied before top N",
+			opts:     SelectOptions{SortBy: SortByAvg, Order: OrderDesc, MinCount: 10, Top: 1},
+			expected: []string{"/reports"},
+		},
+		{
+			name:     "top larger than result",
+			opts:     SelectOptions{SortBy: SortByCount, Order: OrderDesc, Top: 10},
+			expected: []string{"/health", "/users/:id", "/reports", "/checkout"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := SelectMetrics(newSelectorTestMetrics(), tt.opts)
+			assert.Equal(t, tt.expected, selectedPaths(result))
+		})
+	}
+}
+
+func TestSelectOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     SelectOptions
+		errorMsg string
+	}{
+		{name: "default options", opts: DefaultSelectOptions()},
+		{name: "all sort keys", opts: SelectOptions{SortBy: SortByDBTime, Order: OrderAsc, Top: 20, MinCount: 1, MinAvgMs: 10}},
+		{name: "unsupported sort key", opts: SelectOptions{SortBy: "name", Order: OrderDesc}, errorMsg: "unsupported sort key"},
+		{name: "unsupported order", opts: SelectOptions{SortBy: SortByCount, Order: "up"}, errorMsg: "unsupported sort order"},
+		{name: "negative top", opts: SelectOptions{SortBy: SortByCount, Order: OrderDesc, Top: -1}, errorMsg: "top must not be negative"},
+		{name: "negative min count", opts: SelectOptions{SortBy: SortByCount, Order: OrderDesc, MinCount: -1}, errorMsg: "min count must not be negative"},
+		{name: "negative min average", opts: SelectOptions{SortBy: SortByCount, Order: OrderDesc, MinAvgMs: -1}, errorMsg: "min average time must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.errorMsg != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSortKeys(t *testing.T) {
+	assert.Equal(t, []string{"avg", "count", "db_time", "error_rate", "max", "p95", "total_time"}, SortKeys())
+}
@@ -0,0 +1,263 @@
+package analyzer
+
+import (
+	"time"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+// DefaultPendingTimeout is how long an unmatched Started entry is kept before it is evicted
+const DefaultPendingTimeout = 10 * time.Minute
+
+// pendingStart is a Started entry waiting for its Completed entry
+type pendingStart struct {
+	entry  *models.LogEntry
+	seenAt time.Time
+}
+
+// StreamAggregator incrementally matches and aggregates log entries
+// Memory is bounded by the number of in-flight requests instead of the number of log events
+type StreamAggregator struct {
+	aggregator     *Aggregator
+	normalizer     *Normalizer
+	pendingTimeout time.Duration
+	startedLogs    map[string][]*pendingStart // Started entries by match key, oldest first
+	pending        int
+	pathMetrics    map[string]*models.PathMetrics
+	incomplete     map[string]*models.IncompleteMetrics
+	matchedBy      map[string]int
+	diagnostics    *diagnosticsCollector
+	totalLogs      int
+	evicted        int
+	outsideWindow  int
+	watermark      time.Time
+	lastEviction   time.Time
+	windowStart    time.Time
+	windowEnd      time.Time
+	handlePair     func(pair *models.RequestPair) // Receives each matched request pair inside the window
+	result         *models.AnalysisResult         // Set once Result has finalized the metrics
+}
+
+// NewStreamAggregator creates a new StreamAggregator
+// Unmatched Started entries older than pendingTimeout are evicted; zero disables eviction
+func NewStreamAggregator(aggregator *Aggregator, normalizer *Normalizer, pendingTimeout time.Duration) *StreamAggregator {
+	s := &StreamAggregator{
+		aggregator:     aggregator,
+		normalizer:     normalizer,
+		pendingTimeout: pendingTimeout,
+		startedLogs:    make(map[string][]*pendingStart),
+		pathMetrics:    make(map[string]*models.PathMetrics),
+		incomplete:     make(map[string]*models.IncompleteMetrics),
+		matchedBy:      make(map[string]int),
+		diagnostics:    newDiagnosticsCollector(aggregator.diagnosticSamples),
+	}
+	s.handlePair = func(pair *models.RequestPair) {
+		s.aggregator.addPair(s.pathMetrics, pair, s.normalizer)
+	}
+	return s
+}
+
+// SetWindow drops the requests whose Started timestamp is outside [start, end]; zero bounds are open
+// Entries without a timestamp are kept
+func (s *StreamAggregator) SetWindow(start, end time.Time) {
+	s.windowStart = start
+	s.windowEnd = end
+}
+
+// inWindow checks if a Started entry is inside the window, counting it otherwise
+func (s *StreamAggregator) inWindow(entry *models.LogEntry) bool {
+	if entry.Timestamp.IsZero() {
+		return true
+	}
+	if (!s.windowStart.IsZero() && entry.Timestamp.Before(s.windowStart)) || (!s.windowEnd.IsZero() && entry.Timestamp.After(s.windowEnd)) {
+		s.outsideWindow++
+		return false
+	}
+	return true
+}
+
+// Add processes a single log entry observed at eventTime
+// eventTime may be zero, in which case the Started timestamp is used to advance the clock
+// Entries added after Result are ignored, since the metrics are already finalized
+func (s *StreamAggregator) Add(entry *models.LogEntry, eventTime time.Time) {
+	if s.result != nil {
+		return
+	}
+	s.totalLogs++
+
+	if eventTime.IsZero() {
+		eventTime = entry.Timestamp
+	}
+	s.advance(eventTime)
+
+	key, strategy := matchKey(entry)
+	switch entry.Type {
+	case "Request":
+		if !s.inWindow(entry) {
+			return
+		}
+		// Structured logs need no matching
+		s.handlePair(&models.RequestPair{
+			Started:   entry,
+			Completed: entry,
+			MatchedBy: models.MatchByRecord,
+		})
+		s.matchedBy[models.MatchByRecord]++
+	case "Started":
+		queue := s.startedLogs[key]
+		// A pending Started log with the same request ID is replaced and will never be matched, e.g. a request
+		// retried with the same X-Request-Id after a load balancer timeout, so it is reported as incomplete
+		if strategy == models.MatchByRequestID && len(queue) > 0 {
+			s.diagnostics.duplicateRequestID(entry)
+			for _, pending := range queue {
+				s.addIncomplete(pending.entry)
+			}
+			s.pending -= len(queue)
+			queue = nil
+		}
+		// Untagged Started logs are queued, since a threaded server interleaves requests of the same process
+		s.startedLogs[key] = append(queue, &pendingStart{entry: entry, seenAt: s.watermark})
+		s.pending++
+	case "Processing":
+		// Attach the controller action to the latest pending Started log, which it directly follows
+		if queue := s.startedLogs[key]; len(queue) > 0 {
+			attachAction(queue[len(queue)-1].entry, entry)
+		}
+	case "Completed":
+		queue := s.startedLogs[key]
+		if len(queue) == 0 {
+			s.diagnostics.orphanedCompleted(entry)
+			return
+		}
+
+		// Match with the oldest Started log with the same match key; the pairing is a guess when
+		// several untagged requests were in flight
+		pending := queue[0]
+		if strategy == models.MatchByLogOrder && len(queue) > 1 {
+			strategy = models.MatchByLogOrderAmbiguous
+		}
+		if s.inWindow(pending.entry) {
+			s.handlePair(&models.RequestPair{
+				Started:   pending.entry,
+				Completed: entry,
+				MatchedBy: strategy,
+			})
+			s.matchedBy[strategy]++
+		}
+
+		// Remove matched Started log to avoid duplicate matches
+		if len(queue) == 1 {
+			delete(s.startedLogs, key)
+		} else {
+			s.startedLogs[key] = queue[1:]
+		}
+		s.pending--
+	}
+}
+
+// addIncomplete records a Started entry that will never be matched
+func (s *StreamAggregator) addIncomplete(entry *models.LogEntry) {
+	if !s.inWindow(entry) {
+		return
+	}
+	s.diagnostics.orphanedStarted(entry)
+	s.aggregator.addIncomplete(s.incomplete, entry, s.normalizer)
+}
+
+// ParseFailure records a log line that could not be parsed into a log entry
+func (s *StreamAggregator) ParseFailure(err error, logLine string) {
+	s.diagnostics.parseFailure(err, logLine)
+}
+
+// advance moves the stream clock forward and evicts expired Started entries
+func (s *StreamAggregator) advance(eventTime time.Time) {
+	if eventTime.After(s.watermark) {
+		s.watermark = eventTime
+	}
+	if s.pendingTimeout <= 0 || s.watermark.IsZero() {
+		return
+	}
+
+	if s.lastEviction.IsZero() {
+		s.lastEviction = s.watermark
+		return
+	}
+
+	// Sweep at most once per timeout period to keep eviction cheap
+	if s.watermark.Sub(s.lastEviction) < s.pendingTimeout {
+		return
+	}
+	s.lastEviction = s.watermark
+
+	// Queues are ordered by the time their entries were seen, so expired entries are at the front
+	deadline := s.watermark.Add(-s.pendingTimeout)
+	for key, queue := range s.startedLogs {
+		expired := 0
+		for expired < len(queue) && queue[expired].seenAt.Before(deadline) {
+			s.addIncomplete(queue[expired].entry)
+			expired++
+		}
+		if expired == len(queue) {
+			delete(s.startedLogs, key)
+		} else if expired > 0 {
+			s.startedLogs[key] = queue[expired:]
+		}
+		s.pending -= expired
+		s.evicted += expired
+	}
+}
+
+// Pending returns the number of Started entries still waiting for their Completed entry
+// It is zero once Result has reported them as incomplete
+func (s *StreamAggregator) Pending() int {
+	return s.pending
+}
+
+// Evicted returns the number of Started entries evicted because they exceeded the pending timeout
+func (s *StreamAggregator) Evicted() int {
+	return s.evicted
+}
+
+// OutsideWindow returns the number of requests dropped because they started outside the window
+func (s *StreamAggregator) OutsideWindow() int {
+	return s.outsideWindow
+}
+
+// Result finalizes the aggregated metrics and returns the analysis result
+// Started entries still pending are reported as incomplete. Finalizing is done once, so later calls return the
+// same result instead of reporting the pending entries and merging the collapsed metrics again
+func (s *StreamAggregator) Result(startTime, endTime time.Time) *models.AnalysisResult {
+	if s.result != nil {
+		return s.result
+	}
+
+	for _, entry := range sortPending(s.startedLogs) {
+		s.addIncomplete(entry)
+	}
+	s.startedLogs = make(map[string][]*pendingStart)
+	s.pending = 0
+
+	collapsed := s.aggregator.collapseHighCardinality(s.pathMetrics, s.incomplete, s.aggregator.collapseThreshold, s.aggregator.collapseMaxCount)
+	finalizeMetrics(s.pathMetrics)
+
+	// Ages are measured at the end of the window, or at the latest event when the window is open-ended
+	windowEnd := endTime
+	if windowEnd.IsZero() {
+		windowEnd = s.watermark
+	}
+	finalizeIncomplete(s.incomplete, windowEnd)
+
+	s.result = &models.AnalysisResult{
+		StartTime:   startTime,
+		EndTime:     endTime,
+		TotalLogs:   s.totalLogs,
+		GroupBy:     s.aggregator.groupOptions.groupBy(),
+		AggregateBy: s.aggregator.groupOptions.aggregateBy(),
+		MatchedBy:   s.matchedBy,
+		Diagnostics: s.diagnostics.result(),
+		PathMetrics: s.pathMetrics,
+		Incomplete:  s.incomplete,
+		Collapsed:   collapsed,
+	}
+	return s.result
+}
@@ -0,0 +1,213 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+func TestStreamAggregator_MatchesAnalyzeLogs(t *testing.T) {
+	base := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	entries := []*models.LogEntry{
+		{Type: "Started", Method: "GET", Path: "/users/123", Timestamp: base, SessionID: "a"},
+		{Type: "Started", Method: "POST", Path: "/posts", Timestamp: base.Add(time.Second), SessionID: "b"},
+		{Type: "Completed", StatusCode: 201, Duration: 250, SessionID: "b"},
+		{Type: "Completed", StatusCode: 200, Duration: 150, DBDuration: 20.5, SessionID: "a"},
+		{Type: "Completed", StatusCode: 200, Duration: 10, SessionID: "orphan"},
+		{Type: "Started", Method: "GET", Path: "/users/456", Timestamp: base.Add(2 * time.Second)},
+	}
+
+	startTime := base.Add(-time.Hour)
+	endTime := base.Add(time.Hour)
+	expected := NewAggregator().AnalyzeLogs(entries, NewNormalizer(), startTime, endTime)
+
+	stream := NewStreamAggregator(NewAggregator(), NewNormalizer(), DefaultPendingTimeout)
+	for _, entry := range entries {
+		stream.Add(entry, time.Time{})
+	}
+
+	// The untagged Started entry waits for a Completed entry of the same process
+	assert.Equal(t, 1, stream.Pending())
+	assert.Equal(t, expected, stream.Result(startTime, endTime))
+	assert.Equal(t, 0, stream.Pending())
+	assert.Equal(t, 0, stream.Evicted())
+}
+
+func TestStreamAggregator_MatchesUntaggedEntriesByLogOrder(t *testing.T) {
+	base := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	stream := NewStreamAggregator(NewAggregator(), NewNormalizer(), DefaultPendingTimeout)
+
+	for _, entry := range []*models.LogEntry{
+		{Type: "Started", Method: "GET", Path: "/users/1", Timestamp: base, SessionID: "a"},
+		{Type: "Started", Method: "GET", Path: "/posts", Timestamp: base, LogStream: "task-1", ProcessID: "7"},
+		{Type: "Started", Method: "GET", Path: "/posts", Timestamp: base, LogStream: "task-2", ProcessID: "7"},
+		{Type: "Completed", StatusCode: 200, Duration: 30, LogStream: "task-2", ProcessID: "7"},
+		{Type: "Completed", StatusCode: 200, Duration: 10, LogStream: "task-1", ProcessID: "7"},
+		{Type: "Completed", StatusCode: 200, Duration: 20, SessionID: "a"},
+	} {
+		stream.Add(entry, time.Time{})
+	}
+
+	result := stream.Result(base, base.Add(time.Hour))
+	assert.Equal(t, map[string]int{models.MatchByRequestID: 1, models.MatchByLogOrder: 2}, result.MatchedBy)
+	require.Contains(t, result.PathMetrics, "/posts")
+	assert.Equal(t, 2, result.PathMetrics["/posts"].Count)
+	assert.Equal(t, 20.0, result.PathMetrics["/posts"].AverageTime)
+	assert.Equal(t, 0, stream.Pending())
+}
+
+func TestStreamAggregator_EvictsExpiredStartedEntries(t *testing.T) {
+	base := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	stream := NewStreamAggregator(NewAggregator(), NewNormalizer(), time.Minute)
+
+	stream.Add(&models.LogEntry{Type: "Started", Method: "GET", Path: "/slow", SessionID: "stale"}, base)
+	assert.Equal(t, 1, stream.Pending())
+
+	// 90 seconds later the stale entry has exceeded the timeout and is swept
+	stream.Add(&models.LogEntry{Type: "Started", Method: "GET", Path: "/fast", SessionID: "fresh"}, base.Add(90*time.Second))
+	assert.Equal(t, 1, stream.Pending())
+	assert.Equal(t, 1, stream.Evicted())
+
+	stream.Add(&models.LogEntry{Type: "Completed", StatusCode: 200, Duration: 5, SessionID: "fresh"}, base.Add(2*time.Minute))
+	assert.Equal(t, 0, stream.Pending())
+
+	// A late Completed entry for the evicted request is no longer matched
+	stream.Add(&models.LogEntry{Type: "Completed", StatusCode: 200, Duration: 130000, SessionID: "stale"}, base.Add(2*time.Minute+time.Second))
+
+	result := stream.Result(base, base.Add(time.Hour))
+	require.Len(t, result.PathMetrics, 1)
+	assert.Contains(t, result.PathMetrics, "/fast")
+	assert.Equal(t, 4, result.TotalLogs)
+}
+
+func TestStreamAggregator_EvictsExpiredQueuedEntries(t *testing.T) {
+	base := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	stream := NewStreamAggregator(NewAggregator(), NewNormalizer(), time.Minute)
+
+	// Two untagged requests of the same process are queued; only the older one expires
+	stream.Add(&models.LogEntry{Type: "Started", Method: "GET", Path: "/stale", ProcessID: "7"}, base)
+	stream.Add(&models.LogEntry{Type: "Started", Method: "GET", Path: "/fresh", ProcessID: "7"}, base.Add(45*time.Second))
+	assert.Equal(t, 2, stream.Pending())
+
+	stream.Add(&models.LogEntry{Type: "Started", Method: "GET", Path: "/other", SessionID: "x"}, base.Add(90*time.Second))
+	assert.Equal(t, 1, stream.Evicted())
+	assert.Equal(t, 2, stream.Pending())
+
+	// The remaining queued entry is matched alone, so the match is not ambiguous
+	stream.Add(&models.LogEntry{Type: "Completed", StatusCode: 200, Duration: 5, ProcessID: "7"}, base.Add(100*time.Second))
+
+	result := stream.Result(base, base.Add(time.Hour))
+	assert.Equal(t, map[string]int{models.MatchByLogOrder: 1}, result.MatchedBy)
+	assert.Contains(t, result.PathMetrics, "/fresh")
+	assert.Contains(t, result.Incomplete, "/stale")
+}
+
+func TestStreamAggregator_ReportsReplacedDuplicateRequestIDAsIncomplete(t *testing.T) {
+	base := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	stream := NewStreamAggregator(NewAggregator(), NewNormalizer(), 0)
+
+	// The first request timed out at the load balancer and was retried with the same request ID
+	stream.Add(&models.LogEntry{Type: "Started", Method: "POST", Path: "/orders", Timestamp: base, SessionID: "retry"}, time.Time{})
+	stream.Add(&models.LogEntry{Type: "Started", Method: "POST", Path: "/orders", Timestamp: base.Add(time.Minute), SessionID: "retry"}, time.Time{})
+	stream.Add(&models.LogEntry{Type: "Completed", StatusCode: 201, Duration: 80, SessionID: "retry"}, time.Time{})
+
+	result := stream.Result(base, base.Add(time.Hour))
+	require.Contains(t, result.Incomplete, "/orders")
+	assert.Equal(t, 1, result.Incomplete["/orders"].Count)
+	assert.Equal(t, 1, result.PathMetrics["/orders"].Count)
+	assert.Equal(t, 1, result.Diagnostics.OrphanedStarted.Count)
+	assert.Equal(t, 1, result.Diagnostics.DuplicateRequestIDs.Count)
+	assert.Equal(t, 0, stream.Pending())
+}
+
+func TestStreamAggregator_ResultIsFinalizedOnce(t *testing.T) {
+	base := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	aggregator := NewAggregator()
+	aggregator.collapseThreshold = 1
+	stream := NewStreamAggregator(aggregator, NewNormalizer(), 0)
+
+	for _, entry := range []*models.LogEntry{
+		{Type: "Started", Method: "GET", Path: "/articles/alpha", SessionID: "a"},
+		{Type: "Completed", StatusCode: 200, Duration: 10, SessionID: "a"},
+		{Type: "Started", Method: "GET", Path: "/articles/bravo", SessionID: "b"},
+		{Type: "Completed", StatusCode: 200, Duration: 30, SessionID: "b"},
+		{Type: "Started", Method: "GET", Path: "/reports", SessionID: "r"},
+	} {
+		stream.Add(entry, base)
+	}
+
+	first := stream.Result(base, base.Add(time.Hour))
+	assert.Equal(t, 0, stream.Pending())
+
+	// Calling Result again neither reports the pending entry twice nor merges the collapsed metrics again
+	second := stream.Result(base, base.Add(time.Hour))
+	assert.Same(t, first, second)
+	assert.Equal(t, 1, second.Incomplete["/reports"].Count)
+	assert.Equal(t, 1, second.Diagnostics.OrphanedStarted.Count)
+	assert.Equal(t, 2, second.PathMetrics["/articles/:param"].Count)
+	assert.Equal(t, 20.0, second.PathMetrics["/articles/:param"].AverageTime)
+
+	// The metrics are final, so later entries are ignored
+	stream.Add(&models.LogEntry{Type: "Started", Method: "GET", Path: "/late", SessionID: "l"}, base)
+	assert.Equal(t, 0, stream.Pending())
+	assert.Equal(t, 5, second.TotalLogs)
+	assert.NotContains(t, stream.Result(base, base.Add(time.Hour)).Incomplete, "/late")
+}
+
+func TestStreamAggregator_ZeroTimeoutKeepsEntries(t *testing.T) {
+	base := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	stream := NewStreamAggregator(NewAggregator(), NewNormalizer(), 0)
+
+	stream.Add(&models.LogEntry{Type: "Started", Method: "GET", Path: "/reports", SessionID: "r1"}, base)
+	stream.Add(&models.LogEntry{Type: "Started", Method: "GET", Path: "/other", SessionID: "r2"}, base.Add(24*time.Hour))
+	stream.Add(&models.LogEntry{Type: "Completed", StatusCode: 200, Duration: 100, SessionID: "r1"}, base.Add(24*time.Hour))
+
+	assert.Equal(t, 0, stream.Evicted())
+	assert.Equal(t, 1, stream.Pending())
+	assert.Contains(t, stream.Result(base, base).PathMetrics, "/reports")
+}
+
+func TestStreamAggregator_UsesStartedTimestampWithoutEventTime(t *testing.T) {
+	base := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	stream := NewStreamAggregator(NewAggregator(), NewNormalizer(), time.Minute)
+
+	stream.Add(&models.LogEntry{Type: "Started", Method: "GET", Path: "/a", Timestamp: base, SessionID: "a"}, time.Time{})
+	stream.Add(&models.LogEntry{Type: "Started", Method: "GET", Path: "/b", Timestamp: base.Add(time.Minute), SessionID: "b"}, time.Time{})
+	stream.Add(&models.LogEntry{Type: "Started", Method: "GET", Path: "/c", Timestamp: base.Add(2 * time.Minute), SessionID: "c"}, time.Time{})
+
+	// The sweep at two minutes evicts "a" (two minutes old) but keeps "b" (exactly one minute old)
+	assert.Equal(t, 1, stream.Evicted())
+	assert.Equal(t, 2, stream.Pending())
+}
+
+func TestStreamAggregator_DropsRequestsOutsideWindow(t *testing.T) {
+	base := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	stream := NewStreamAggregator(NewAggregator(), NewNormalizer(), 0)
+	stream.SetWindow(base, base.Add(time.Hour))
+
+	for _, entry := range []*models.LogEntry{
+		{Type: "Started", Method: "GET", Path: "/before", Timestamp: base.Add(-time.Second), SessionID: "a"},
+		{Type: "Started", Method: "GET", Path: "/inside", Timestamp: base, SessionID: "b"},
+		{Type: "Completed", StatusCode: 200, Duration: 10, SessionID: "a"},
+		{Type: "Completed", StatusCode: 200, Duration: 20, SessionID: "b"},
+		{Type: "Started", Method: "GET", Path: "/after", Timestamp: base.Add(2 * time.Hour), SessionID: "c"},
+		{Type: "Request", Method: "GET", Path: "/structured", Timestamp: base.Add(3 * time.Hour), StatusCode: 200, Duration: 5},
+		// Entries without a timestamp cannot be placed and are kept
+		{Type: "Request", Method: "GET", Path: "/untimed", StatusCode: 200, Duration: 5},
+	} {
+		stream.Add(entry, time.Time{})
+	}
+
+	result := stream.Result(base, base.Add(time.Hour))
+	assert.Len(t, result.PathMetrics, 2)
+	assert.Contains(t, result.PathMetrics, "/inside")
+	assert.Contains(t, result.PathMetrics, "/untimed")
+	// The pending request after the window is not reported as incomplete either
+	assert.Empty(t, result.Incomplete)
+	assert.Equal(t, 0, result.Diagnostics.OrphanedCompleted.Count)
+	assert.Equal(t, 3, stream.OutsideWindow())
+}
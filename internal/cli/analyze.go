@@ -3,76 +3,263 @@ package cli
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/kgrsutos/cw-railspathmetrics/internal/analyzer"
 	"github.com/kgrsutos/cw-railspathmetrics/internal/cloudwatch"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/config"
 	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/output"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/source"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/timeutil"
 )
 
 var (
-	startTime  string
-	endTime    string
-	logGroup   string
-	profile    string
-	configPath string
+	startTime         string
+	endTime           string
+	logGroups         []string
+	logGroupPrefixes  []string
+	logStreamPrefix   string
+	groupBy           string
+	aggregateBy       string
+	logFormat         string
+	routesFile        string
+	profile           string
+	configPath        string
+	format            string
+	detailed          bool
+	incomplete        bool
+	queryParams       bool
+	sortBy            string
+	sortOrder         string
+	top               int
+	minCount          int
+	minAvgMs          float64
+	inputFiles        []string
+	pendingTimeout    time.Duration
+	workers           int
+	sliceDuration     time.Duration
+	maxAttempts       int
+	rateLimit         float64
+	backend           string
+	savePath          string
+	timeZone          string
+	since             string
+	last              string
+	maxWindow         string
+	allowLarge        bool
+	dryRun            bool
+	diagnostics       bool
+	diagnosticSamples int
+	collapseThreshold int
+	collapseMaxCount  int
 )
 
+// defaultMaxWindow is the largest CloudWatch time range queried without --allow-large-window
+const defaultMaxWindow = "7d"
+
+// Supported CloudWatch backends
+const (
+	backendFilter   = "filter"
+	backendInsights = "insights"
+)
+
+// filterPatterns maps each log format to the CloudWatch filter pattern fetching its request lines
+// Structured request lines are recognized by their status field
+var filterPatterns = map[string]string{
+	analyzer.LogFormatRails:   cloudwatch.DefaultFilterPattern,
+	analyzer.LogFormatLograge: `?status`,
+	analyzer.LogFormatJSON:    `?status`,
+	analyzer.LogFormatAuto:    cloudwatch.DefaultFilterPattern + ` ?status`,
+}
+
+// eventBufferSize is the number of log events buffered between the source and the analyzer
+const eventBufferSize = 1000
+
+// sourceOptions holds the flags selecting, fetching and parsing the log source
+// Each command binds its own copy, so analyze and compare do not share flag state
+type sourceOptions struct {
+	inputFiles       []string
+	logGroups        []string
+	logGroupPrefixes []string
+	logStreamPrefix  string
+	profile          string
+	backend          string
+	workers          int
+	sliceDuration    time.Duration
+	maxAttempts      int
+	rateLimit        float64
+	logFormat        string
+	routesFile       string
+	configPath       string
+	timeZone         string
+	maxWindow        string
+	allowLarge       bool
+}
+
+// analyzeSourceOptions returns the source options of the analyze command flags
+func analyzeSourceOptions() sourceOptions {
+	return sourceOptions{
+		inputFiles:       inputFiles,
+		logGroups:        logGroups,
+		logGroupPrefixes: logGroupPrefixes,
+		logStreamPrefix:  logStreamPrefix,
+		profile:          profile,
+		backend:          backend,
+		workers:          workers,
+		sliceDuration:    sliceDuration,
+		maxAttempts:      maxAttempts,
+		rateLimit:        rateLimit,
+		logFormat:        logFormat,
+		routesFile:       routesFile,
+		configPath:       configPath,
+		timeZone:         timeZone,
+		maxWindow:        maxWindow,
+		allowLarge:       allowLarge,
+	}
+}
+
+// timeRangeOptions holds the flags selecting the time range of the analyze command
+type timeRangeOptions struct {
+	start string
+	end   string
+	since string
+	last  string
+}
+
+// analyzeTimeRangeOptions returns the time range flags of the analyze command
+func analyzeTimeRangeOptions() timeRangeOptions {
+	return timeRangeOptions{
+		start: startTime,
+		end:   endTime,
+		since: since,
+		last:  last,
+	}
+}
+
 var analyzeCmd = &cobra.Command{
 	Use:   "analyze",
 	Short: "Analyze CloudWatch logs for Rails request metrics",
-	Long:  `Analyze CloudWatch logs to aggregate request metrics by path.`,
-	RunE:  runAnalyze,
+	Long: `Analyze CloudWatch logs to aggregate request metrics by path.
+Times without an offset are interpreted in --tz; use --since or --last for windows relative to now.
+Use --input to analyze local Rails log files (plain or gzipped) or stdin ("-") instead of CloudWatch.`,
+	RunE: runAnalyze,
 }
 
 func init() {
 	rootCmd.AddCommand(analyzeCmd)
 
-	analyzeCmd.Flags().StringVar(&startTime, "start", "", "Start time in JST (required, format: 2006-01-02T15:04:05)")
-	analyzeCmd.Flags().StringVar(&endTime, "end", "", "End time in JST (required, format: 2006-01-02T15:04:05)")
-	analyzeCmd.Flags().StringVar(&logGroup, "log-group", "", "CloudWatch Logs log group name (required)")
-	analyzeCmd.Flags().StringVar(&profile, "profile", "", "AWS profile name (required)")
+	analyzeCmd.Flags().StringVar(&startTime, "start", "", "Start time (required for CloudWatch unless --since/--last): RFC3339, 2006-01-02T15:04:05 in --tz, or now-1d")
+	analyzeCmd.Flags().StringVar(&endTime, "end", "", "End time (required for CloudWatch unless --since/--last): RFC3339, 2006-01-02T15:04:05 in --tz, or now")
+	analyzeCmd.Flags().StringVar(&since, "since", "", "Start this long before now and end now unless --end is given (e.g. 2h, 1d)")
+	analyzeCmd.Flags().StringVar(&last, "last", "", "Analyze the window of this length ending now (e.g. 30m, 1d)")
+	analyzeCmd.Flags().StringVar(&timeZone, "tz", "", "IANA time zone for times without an offset (default: timezone from config file, else local time)")
+	analyzeCmd.Flags().StringVar(&maxWindow, "max-window", defaultMaxWindow, "Largest CloudWatch time range allowed without --allow-large-window (e.g. 12h, 7d; 0 disables the limit)")
+	analyzeCmd.Flags().BoolVar(&allowLarge, "allow-large-window", false, "Query CloudWatch time ranges larger than --max-window")
+	analyzeCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the resolved UTC window, log groups and estimated pages without fetching log events")
+	analyzeCmd.Flags().StringArrayVar(&logGroups, "log-group", nil, "CloudWatch Logs log group name (required for CloudWatch unless --log-group-prefix; repeatable)")
+	analyzeCmd.Flags().StringArrayVar(&logGroupPrefixes, "log-group-prefix", nil, "Also analyze every log group whose name starts with this prefix (repeatable)")
+	analyzeCmd.Flags().StringVar(&logStreamPrefix, "log-stream-prefix", "", "Only read log streams whose name starts with this prefix (e.g. one ECS service or task)")
+	analyzeCmd.Flags().StringVar(&profile, "profile", "", "AWS profile name (required for CloudWatch)")
+	analyzeCmd.Flags().StringVar(&backend, "backend", backendFilter, "CloudWatch fetch strategy: filter (FilterLogEvents) or insights (Logs Insights query)")
+	analyzeCmd.Flags().IntVar(&workers, "workers", cloudwatch.DefaultWorkers, "Number of CloudWatch time slices fetched concurrently (1 fetches sequentially)")
+	analyzeCmd.Flags().DurationVar(&sliceDuration, "slice-duration", cloudwatch.DefaultSliceDuration, "Length of each CloudWatch time slice fetched by a worker; slices are streamed, so memory use does not grow with it")
+	analyzeCmd.Flags().IntVar(&maxAttempts, "max-attempts", cloudwatch.DefaultMaxAttempts, "Attempts per CloudWatch request before giving up on throttling or transient errors")
+	analyzeCmd.Flags().Float64Var(&rateLimit, "rate-limit", 0, "Maximum CloudWatch requests per second across all workers (0 means unlimited)")
+	analyzeCmd.Flags().StringArrayVar(&inputFiles, "input", nil, "Rails log file to analyze instead of CloudWatch, plain or gzipped; \"-\" reads stdin (repeatable)")
+	analyzeCmd.Flags().StringVar(&logFormat, "log-format", analyzer.LogFormatRails, "Format of the log lines: "+strings.Join(analyzer.LogFormats(), ", ")+", or the name of a log_formats entry in the config file")
+	analyzeCmd.Flags().StringVar(&routesFile, "routes", "", "Normalize paths with the routes printed by rails routes (text or JSON), falling back to the heuristics")
 	analyzeCmd.Flags().StringVar(&configPath, "config", "", "Path to custom exclusion configuration file (optional)")
-
-	if err := analyzeCmd.MarkFlagRequired("start"); err != nil {
-		slog.Error("Failed to mark start flag as required", "error", err)
-	}
-	if err := analyzeCmd.MarkFlagRequired("end"); err != nil {
-		slog.Error("Failed to mark end flag as required", "error", err)
-	}
-	if err := analyzeCmd.MarkFlagRequired("log-group"); err != nil {
-		slog.Error("Failed to mark log-group flag as required", "error", err)
-	}
-	if err := analyzeCmd.MarkFlagRequired("profile"); err != nil {
-		slog.Error("Failed to mark profile flag as required", "error", err)
-	}
+	analyzeCmd.Flags().StringVar(&format, "format", output.FormatJSON, "Output format: "+strings.Join(output.SupportedFormats(), ", "))
+	analyzeCmd.Flags().StringVar(&savePath, "save", "", "Also save the full analysis result as JSON to this file for the compare command")
+	analyzeCmd.Flags().BoolVar(&detailed, "detailed", false, "Include status codes, methods, view/DB time and error rates in the output")
+	analyzeCmd.Flags().BoolVar(&incomplete, "incomplete", false, "Output the requests that never completed (killed workers, timeouts) per path instead of the metrics")
+	analyzeCmd.Flags().BoolVar(&queryParams, "query-params", false, "Output the query parameter names of the requests to each path, most common first, instead of the metrics")
+	analyzeCmd.Flags().StringVar(&aggregateBy, "aggregate-by", analyzer.AggregateByPath, "Aggregate requests by: "+strings.Join(analyzer.AggregateByKeys(), ", ")+" (Controller#action)")
+	analyzeCmd.Flags().StringVar(&groupBy, "group-by", "", "Split each path's metrics by these comma separated dimensions: "+strings.Join(analyzer.GroupByKeys(), ", "))
+	analyzeCmd.Flags().IntVar(&collapseThreshold, "collapse-threshold", 0, "Collapse the values of a path position into :param when it has more distinct ones than this (0 disables collapsing)")
+	analyzeCmd.Flags().IntVar(&collapseMaxCount, "collapse-max-count", 0, "Only collapse the values of a path position requested at most this many times, keeping static names apart (0 collapses values however often they are requested)")
+	analyzeCmd.Flags().StringVar(&sortBy, "sort-by", analyzer.SortByCount, "Sort results by: "+strings.Join(analyzer.SortKeys(), ", "))
+	analyzeCmd.Flags().StringVar(&sortOrder, "order", analyzer.OrderDesc, "Sort order: asc or desc")
+	analyzeCmd.Flags().IntVar(&top, "top", 0, "Output only the top N paths after sorting (0 means all)")
+	analyzeCmd.Flags().IntVar(&minCount, "min-count", 0, "Exclude paths with fewer requests than this")
+	analyzeCmd.Flags().Float64Var(&minAvgMs, "min-avg-ms", 0, "Exclude paths with a lower average time in milliseconds than this")
+	analyzeCmd.Flags().BoolVar(&diagnostics, "diagnostics", false, "Also write unparseable lines, unmatched Started/Completed entries and duplicate request IDs to stderr")
+	analyzeCmd.Flags().IntVar(&diagnosticSamples, "diagnostic-samples", 0, "Example log lines kept per diagnostic")
+	analyzeCmd.Flags().DurationVar(&pendingTimeout, "pending-timeout", analyzer.DefaultPendingTimeout, "Discard Started entries without a Completed entry after this long (0 keeps them all)")
 }
 
 func runAnalyze(cmd *cobra.Command, args []string) error {
 	slog.Info("Starting analysis",
 		"start", startTime,
 		"end", endTime,
-		"logGroup", logGroup,
+		"since", since,
+		"last", last,
+		"tz", timeZone,
+		"logGroups", logGroups,
+		"logGroupPrefixes", logGroupPrefixes,
+		"logStreamPrefix", logStreamPrefix,
 		"profile", profile,
+		"input", inputFiles,
+		"backend", backend,
+		"format", format,
 	)
 
-	jst, err := time.LoadLocation("Asia/Tokyo")
+	sourceOpts := analyzeSourceOptions()
+	timeRange := analyzeTimeRangeOptions()
+	if err := validateSourceFlags(sourceOpts, timeRange); err != nil {
+		return err
+	}
+
+	formatter, err := output.NewFormatter(format)
 	if err != nil {
-		return fmt.Errorf("failed to load JST location: %w", err)
+		return err
 	}
 
-	start, err := time.ParseInLocation("2006-01-02T15:04:05", startTime, jst)
+	selectOptions := analyzer.SelectOptions{
+		SortBy:   sortBy,
+		Order:    sortOrder,
+		Top:      top,
+		MinCount: minCount,
+		MinAvgMs: minAvgMs,
+	}
+	if err := selectOptions.Validate(); err != nil {
+		return err
+	}
+	if incomplete && detailed {
+		return fmt.Errorf("--incomplete cannot be combined with --detailed")
+	}
+	if queryParams && (incomplete || detailed) {
+		return fmt.Errorf("--query-params cannot be combined with --incomplete or --detailed")
+	}
+	groupOptions, err := analyzer.ParseGroupBy(groupBy)
 	if err != nil {
-		return fmt.Errorf("failed to parse start time: %w", err)
+		return err
+	}
+	groupOptions.Action, err = analyzer.ParseAggregateBy(aggregateBy)
+	if err != nil {
+		return err
 	}
 
-	end, err := time.ParseInLocation("2006-01-02T15:04:05", endTime, jst)
+	// Time range is optional for local files, so only resolve what was given
+	loc, err := sourceOpts.loadTimeZone()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	start, end, err := timeRange.resolve(loc, now)
 	if err != nil {
-		return fmt.Errorf("failed to parse end time: %w", err)
+		return err
+	}
+	if err := sourceOpts.validateTimeRange(start, end, now, len(inputFiles) == 0); err != nil {
+		return err
 	}
 
 	slog.Info("Parsed time range",
@@ -80,48 +267,459 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		"endUTC", end.UTC(),
 	)
 
-	// Initialize CloudWatch client
-	ctx := context.Background()
-	client, err := cloudwatch.NewClient(ctx, profile)
+	// Initialize analyzer with config if provided
+	analyzer, err := analyzer.NewAnalyzerWithConfig(configPath)
 	if err != nil {
-		return fmt.Errorf("failed to initialize CloudWatch client: %w", err)
+		return fmt.Errorf("failed to initialize analyzer: %w", err)
+	}
+	if err := analyzer.SetSelectOptions(selectOptions); err != nil {
+		return err
 	}
+	analyzer.SetGroupOptions(groupOptions)
+	if err := analyzer.SetPendingTimeout(pendingTimeout); err != nil {
+		return err
+	}
+	if err := analyzer.SetDiagnosticSamples(diagnosticSamples); err != nil {
+		return err
+	}
+	if err := analyzer.SetCollapseThreshold(collapseThreshold); err != nil {
+		return err
+	}
+	if err := analyzer.SetCollapseMaxCount(collapseMaxCount); err != nil {
+		return err
+	}
+	analyzer.SetQueryParamReport(queryParams)
+	// Log files are read whole, so requests are filtered by their Started timestamp instead
+	analyzer.SetWindowFilter(len(inputFiles) > 0)
+	if err := sourceOpts.configureParsing(analyzer); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	// Fetch log events
-	slog.Info("Fetching log events from CloudWatch")
-	events, err := client.FilterLogEventsWithPagination(ctx, logGroup, start, end)
+	if dryRun {
+		return sourceOpts.printDryRun(ctx, os.Stdout, start, end, now)
+	}
+
+	result, err := sourceOpts.analyzeRange(ctx, analyzer, start, end)
 	if err != nil {
-		return fmt.Errorf("failed to fetch log events: %w", err)
+		return err
 	}
 
-	// Convert CloudWatch events to our LogEvent model
-	var logEvents []*models.LogEvent
-	for _, event := range events {
-		if event.EventId != nil && event.Message != nil && event.Timestamp != nil {
-			logEvents = append(logEvents, &models.LogEvent{
-				ID:        *event.EventId,
-				Message:   *event.Message,
-				Timestamp: time.UnixMilli(*event.Timestamp),
-			})
+	slog.Info("Analyzed log events", "count", result.TotalLogs)
+
+	// Output results in the requested format
+	switch {
+	case incomplete:
+		err = analyzer.OutputIncomplete(result, formatter, os.Stdout)
+	case queryParams:
+		err = analyzer.OutputQueryParams(result, formatter, os.Stdout)
+	case detailed:
+		err = analyzer.OutputDetailed(result, formatter, os.Stdout)
+	default:
+		err = analyzer.Output(result, formatter, os.Stdout)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to output results: %w", err)
+	}
+
+	// Diagnostics go to stderr so the metrics on stdout stay parseable
+	if diagnostics {
+		if err := analyzer.OutputDiagnostics(result, formatter, os.Stderr); err != nil {
+			return fmt.Errorf("failed to output diagnostics: %w", err)
+		}
+	}
+	if len(result.Collapsed) > 0 {
+		if err := analyzer.OutputCollapsed(result, formatter, os.Stderr); err != nil {
+			return fmt.Errorf("failed to output collapsed path segments: %w", err)
+		}
+	}
+
+	if savePath != "" {
+		if err := saveResultFile(result, savePath); err != nil {
+			return err
 		}
+		slog.Info("Saved analysis result", "path", savePath)
 	}
 
-	slog.Info("Fetched log events", "count", len(logEvents))
+	return nil
+}
 
-	// Initialize analyzer with config if provided
-	analyzer, err := analyzer.NewAnalyzerWithConfig(configPath)
+// loadTimeZone returns the location for times without an offset: --tz, then the config file, then local time
+func (o sourceOptions) loadTimeZone() (*time.Location, error) {
+	name := o.timeZone
+	if name == "" {
+		settings, err := config.LoadSettingsWithSearch(o.configPath)
+		if err != nil {
+			return nil, err
+		}
+		name = settings.Timezone
+	}
+	return timeutil.LoadLocation(name)
+}
+
+// configureParsing applies the log formats and normalization rules of the config file, --log-format and --routes
+func (o sourceOptions) configureParsing(logAnalyzer *analyzer.Analyzer) error {
+	settings, err := config.LoadSettingsWithSearch(o.configPath)
 	if err != nil {
-		return fmt.Errorf("failed to initialize analyzer: %w", err)
+		return err
+	}
+	if err := logAnalyzer.AddLogFormats(settings.LogFormats); err != nil {
+		return err
+	}
+	if err := logAnalyzer.SetLogFormat(o.logFormat); err != nil {
+		return err
 	}
+	if err := logAnalyzer.SetNormalization(settings.Normalization); err != nil {
+		return err
+	}
+	if o.routesFile != "" {
+		return logAnalyzer.LoadRoutes(o.routesFile)
+	}
+	return nil
+}
 
-	// Analyze log events
-	result := analyzer.AnalyzeLogEvents(logEvents, start.UTC(), end.UTC())
+// logFilterPattern returns the CloudWatch filter pattern of --log-format
+// Custom log formats use their filter_pattern, or the Rails keywords if it is not set
+func (o sourceOptions) logFilterPattern() (string, error) {
+	if pattern, exists := filterPatterns[o.logFormat]; exists {
+		return pattern, nil
+	}
 
-	// Output JSON results
-	err = analyzer.OutputJSON(result, os.Stdout)
+	settings, err := config.LoadSettingsWithSearch(o.configPath)
 	if err != nil {
-		return fmt.Errorf("failed to output results: %w", err)
+		return "", err
+	}
+	for _, format := range settings.LogFormats {
+		if format.Name == o.logFormat {
+			return format.FilterPattern, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported log format: %s", o.logFormat)
+}
+
+// relative reports whether --since or --last provide both bounds of the time range
+func (o timeRangeOptions) relative() bool {
+	return o.since != "" || o.last != ""
+}
+
+// resolve resolves --start, --end, --since and --last into absolute times
+// Bounds that were not given are returned as zero times
+func (o timeRangeOptions) resolve(loc *time.Location, now time.Time) (time.Time, time.Time, error) {
+	var start, end time.Time
+
+	switch {
+	case o.last != "":
+		if o.start != "" || o.end != "" || o.since != "" {
+			return start, end, fmt.Errorf("--last cannot be combined with --start, --end or --since")
+		}
+		window, err := timeutil.ParseDuration(o.last)
+		if err != nil {
+			return start, end, fmt.Errorf("failed to parse --last: %w", err)
+		}
+		return now.Add(-window), now, nil
+	case o.since != "":
+		if o.start != "" {
+			return start, end, fmt.Errorf("--since cannot be combined with --start")
+		}
+		window, err := timeutil.ParseDuration(o.since)
+		if err != nil {
+			return start, end, fmt.Errorf("failed to parse --since: %w", err)
+		}
+		start, end = now.Add(-window), now
+	case o.start != "":
+		parsed, err := timeutil.ParseTime(o.start, now, loc)
+		if err != nil {
+			return start, end, fmt.Errorf("failed to parse start time: %w", err)
+		}
+		start = parsed
+	}
+
+	if o.end != "" {
+		parsed, err := timeutil.ParseTime(o.end, now, loc)
+		if err != nil {
+			return start, end, fmt.Errorf("failed to parse end time: %w", err)
+		}
+		end = parsed
+	}
+
+	return start, end, nil
+}
+
+// validateTimeRange rejects inverted ranges and, when checkWindow is set, ranges larger than --max-window or split
+// into more than cloudwatch.MaxSlices slices
+// Bounds that were not given are zero and skip the checks that need them
+func (o sourceOptions) validateTimeRange(start, end, now time.Time, checkWindow bool) error {
+	if start.IsZero() || end.IsZero() {
+		return nil
+	}
+	if !end.After(start) {
+		return fmt.Errorf("end time %s must be after start time %s", end.UTC().Format(time.RFC3339), start.UTC().Format(time.RFC3339))
+	}
+	if start.After(now) {
+		return fmt.Errorf("start time %s is in the future", start.UTC().Format(time.RFC3339))
+	}
+
+	if !checkWindow {
+		return nil
+	}
+	if o.backend == backendFilter && o.workers > 1 {
+		if slices := cloudwatch.CountSlices(start, end, o.sliceDuration); slices > cloudwatch.MaxSlices {
+			return fmt.Errorf("--slice-duration %s splits the time range into %d slices, more than the maximum of %d", o.sliceDuration, slices, cloudwatch.MaxSlices)
+		}
+	}
+	if o.allowLarge {
+		return nil
+	}
+	limit, err := timeutil.ParseDuration(o.maxWindow)
+	if err != nil {
+		return fmt.Errorf("failed to parse --max-window: %w", err)
+	}
+	if window := end.Sub(start); limit > 0 && window > limit {
+		return fmt.Errorf("time range of %s exceeds the maximum window of %s (use --allow-large-window to query it anyway)", window, o.maxWindow)
+	}
+
+	return nil
+}
+
+// printDryRun writes the resolved time range and source, plus an estimate of the CloudWatch pages to scan
+func (o sourceOptions) printDryRun(ctx context.Context, w io.Writer, start, end, now time.Time) error {
+	fmt.Fprintln(w, "Dry run: no log events are fetched")
+	fmt.Fprintf(w, "Window (UTC):    %s - %s (%s)\n", formatBound(start), formatBound(end), end.Sub(start))
+
+	if len(o.inputFiles) > 0 {
+		fmt.Fprintf(w, "Input:           %s\n", strings.Join(o.inputFiles, ", "))
+		return nil
+	}
+
+	client, err := o.newCloudWatchClient(ctx)
+	if err != nil {
+		return err
+	}
+	names, err := client.ResolveLogGroups(ctx, o.logGroups, o.logGroupPrefixes)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Log groups:      %s\n", strings.Join(names, ", "))
+	if o.logStreamPrefix != "" {
+		fmt.Fprintf(w, "Log streams:     %s*\n", o.logStreamPrefix)
+	}
+	if o.backend == backendInsights {
+		fmt.Fprintf(w, "Backend:         %s\n", o.backend)
+	} else {
+		fmt.Fprintf(w, "Backend:         %s (%d slices of %s, %d workers)\n",
+			o.backend, cloudwatch.CountSlices(start, end, o.sliceDuration), o.sliceDuration, o.workers)
+	}
+
+	var total cloudwatch.Estimate
+	for _, name := range names {
+		estimate, err := client.EstimateRange(ctx, name, start, end, now)
+		if err != nil {
+			slog.Warn("Failed to estimate CloudWatch pages", "logGroup", name, "error", err)
+			fmt.Fprintln(w, "Estimated pages: unknown")
+			return nil
+		}
+		total.StoredBytes += estimate.StoredBytes
+		total.EstimatedBytes += estimate.EstimatedBytes
+		total.EstimatedPages += estimate.EstimatedPages
+	}
+	fmt.Fprintf(w, "Estimated pages: ~%d (%d of %d stored bytes)\n", total.EstimatedPages, total.EstimatedBytes, total.StoredBytes)
+
+	return nil
+}
+
+// formatBound formats a time range bound in UTC, or "-" when it was not given
+func formatBound(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// analyzeRange fetches the log events between start and end from the selected source and analyzes them
+// Events are streamed into the analyzer while they are being fetched
+func (o sourceOptions) analyzeRange(ctx context.Context, logAnalyzer *analyzer.Analyzer, start, end time.Time) (*models.AnalysisResult, error) {
+	logSource, err := o.newLogSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Fetching and analyzing log events", "start", start.UTC(), "end", end.UTC())
+	result, err := logSource.analyze(ctx, logAnalyzer, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch log events: %w", err)
+	}
+
+	return result, nil
+}
+
+// logSource streams the log events between two times into an analyzer
+// It is implemented for both sources of raw log events and sources of parsed entries
+type logSource interface {
+	analyze(ctx context.Context, logAnalyzer *analyzer.Analyzer, start, end time.Time) (*models.AnalysisResult, error)
+}
+
+// eventSource is a logSource of raw log events that the analyzer parses
+type eventSource struct {
+	source source.Source
+}
+
+// analyze streams the raw log events into the analyzer and returns the result once the source is drained
+func (s eventSource) analyze(ctx context.Context, logAnalyzer *analyzer.Analyzer, start, end time.Time) (*models.AnalysisResult, error) {
+	events := make(chan *models.LogEvent, eventBufferSize)
+	fetchErr := make(chan error, 1)
+	go func() {
+		fetchErr <- s.source.Stream(ctx, start, end, events)
+	}()
+	result := logAnalyzer.AnalyzeStream(events, start.UTC(), end.UTC())
+	if err := <-fetchErr; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// entrySource is a logSource of entries that were already parsed, such as Logs Insights results
+type entrySource struct {
+	source source.EntrySource
+}
+
+// analyze streams the parsed entries into the analyzer and returns the result once the source is drained
+func (s entrySource) analyze(ctx context.Context, logAnalyzer *analyzer.Analyzer, start, end time.Time) (*models.AnalysisResult, error) {
+	entries := make(chan *models.LogEntry, eventBufferSize)
+	fetchErr := make(chan error, 1)
+	go func() {
+		fetchErr <- s.source.StreamEntries(ctx, start, end, entries)
+	}()
+	result := logAnalyzer.AnalyzeEntryStream(entries, start.UTC(), end.UTC())
+	if err := <-fetchErr; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// saveResultFile writes the full analysis result to path
+func saveResultFile(result *models.AnalysisResult, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create result file: %w", err)
+	}
+	if err := analyzer.SaveResult(result, file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to save analysis result: %w", err)
+	}
+	return file.Close()
+}
+
+// validateSourceFlags checks that the flags required by the selected log source and time range are set
+func validateSourceFlags(opts sourceOptions, timeRange timeRangeOptions) error {
+	if len(opts.inputFiles) > 0 {
+		return nil
+	}
+
+	// CloudWatch is the default source and needs a time range, log group and profile
+	var missing []string
+	relative := timeRange.relative()
+	for name, value := range map[string]string{
+		"start":   timeRange.start,
+		"end":     timeRange.end,
+		"profile": opts.profile,
+	} {
+		if value == "" && !(relative && (name == "start" || name == "end")) {
+			missing = append(missing, name)
+		}
+	}
+	if len(opts.logGroups) == 0 && len(opts.logGroupPrefixes) == 0 {
+		missing = append(missing, "log-group")
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("required flag(s) %q not set (or use --input to analyze local log files)", missing)
+	}
+
+	return opts.validateCloudWatch()
+}
+
+// validateCloudWatch checks the flags controlling how events are fetched from CloudWatch
+func (o sourceOptions) validateCloudWatch() error {
+	if o.backend != backendFilter && o.backend != backendInsights {
+		return fmt.Errorf("unsupported backend: %s (supported: %s, %s)", o.backend, backendFilter, backendInsights)
+	}
+	if o.backend == backendInsights && o.logFormat != analyzer.LogFormatRails {
+		return fmt.Errorf("the %s backend only supports --log-format %s", backendInsights, analyzer.LogFormatRails)
+	}
+
+	parallelOptions := cloudwatch.ParallelOptions{Workers: o.workers, SliceDuration: o.sliceDuration}
+	if err := parallelOptions.Validate(); err != nil {
+		return err
+	}
+	if o.maxAttempts < 1 {
+		return fmt.Errorf("max attempts must be at least 1: %d", o.maxAttempts)
+	}
+	if o.rateLimit < 0 {
+		return fmt.Errorf("rate limit must not be negative: %g", o.rateLimit)
 	}
 
 	return nil
 }
+
+// newLogSource creates the log source selected by the command line flags
+func (o sourceOptions) newLogSource(ctx context.Context) (logSource, error) {
+	if len(o.inputFiles) > 0 {
+		slog.Info("Reading log events from local input", "input", o.inputFiles)
+		return eventSource{source: source.NewFileSource(o.inputFiles)}, nil
+	}
+
+	client, err := o.newCloudWatchClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names, err := client.ResolveLogGroups(ctx, o.logGroups, o.logGroupPrefixes)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.backend == backendInsights {
+		slog.Info("Reading log entries from CloudWatch Logs Insights", "logGroups", names)
+		return entrySource{source: source.NewInsightsSource(client, names)}, nil
+	}
+
+	slog.Info("Reading log events from CloudWatch",
+		"logGroups", names,
+		"workers", o.workers,
+		"sliceDuration", o.sliceDuration,
+	)
+	if o.workers == 1 {
+		return eventSource{source: source.NewCloudWatchSource(client, names)}, nil
+	}
+	return eventSource{source: source.NewParallelCloudWatchSource(client, names, cloudwatch.ParallelOptions{
+		Workers:       o.workers,
+		SliceDuration: o.sliceDuration,
+	})}, nil
+}
+
+// newCloudWatchClient creates a CloudWatch client configured with the retry and rate limit flags
+func (o sourceOptions) newCloudWatchClient(ctx context.Context) (*cloudwatch.Client, error) {
+	client, err := cloudwatch.NewClient(ctx, o.profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize CloudWatch client: %w", err)
+	}
+
+	retryOptions := cloudwatch.DefaultRetryOptions()
+	retryOptions.MaxAttempts = o.maxAttempts
+	if err := client.SetRetryOptions(retryOptions); err != nil {
+		return nil, err
+	}
+	if err := client.SetRateLimit(o.rateLimit); err != nil {
+		return nil, err
+	}
+	client.SetLogStreamPrefix(o.logStreamPrefix)
+	pattern, err := o.logFilterPattern()
+	if err != nil {
+		return nil, err
+	}
+	client.SetFilterPattern(pattern)
+
+	return client, nil
+}
@@ -1,7 +1,11 @@
 package cli
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -10,6 +14,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/analyzer"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/cloudwatch"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/source"
 )
 
 func TestParseTime(t *testing.T) {
@@ -64,8 +73,46 @@ func TestAnalyzeCommand(t *testing.T) {
 	assert.NotNil(t, analyzeCmd.Flags().Lookup("start"))
 	assert.NotNil(t, analyzeCmd.Flags().Lookup("end"))
 	assert.NotNil(t, analyzeCmd.Flags().Lookup("log-group"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("log-group-prefix"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("log-stream-prefix"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("group-by"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("aggregate-by"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("log-format"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("routes"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("collapse-threshold"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("collapse-max-count"))
+	assert.Equal(t, "rails", analyzeCmd.Flags().Lookup("log-format").DefValue)
+	assert.Equal(t, "path", analyzeCmd.Flags().Lookup("aggregate-by").DefValue)
 	assert.NotNil(t, analyzeCmd.Flags().Lookup("profile"))
 	assert.NotNil(t, analyzeCmd.Flags().Lookup("config"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("format"))
+	assert.Equal(t, "json", analyzeCmd.Flags().Lookup("format").DefValue)
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("detailed"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("incomplete"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("query-params"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("sort-by"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("order"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("top"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("min-count"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("min-avg-ms"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("input"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("pending-timeout"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("diagnostics"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("diagnostic-samples"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("workers"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("slice-duration"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("max-attempts"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("rate-limit"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("backend"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("save"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("tz"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("since"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("last"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("max-window"))
+	assert.Equal(t, "7d", analyzeCmd.Flags().Lookup("max-window").DefValue)
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("allow-large-window"))
+	assert.NotNil(t, analyzeCmd.Flags().Lookup("dry-run"))
+	assert.Equal(t, "filter", analyzeCmd.Flags().Lookup("backend").DefValue)
 }
 
 func TestAnalyzeCommand_ConfigFlag(t *testing.T) {
@@ -115,7 +162,45 @@ func (m *MockCloudWatchAPI) FilterLogEvents(ctx context.Context, params *cloudwa
 	return args.Get(0).(*cloudwatchlogs.FilterLogEventsOutput), args.Error(1)
 }
 
+func (m *MockCloudWatchAPI) StartQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*cloudwatchlogs.StartQueryOutput), args.Error(1)
+}
+
+func (m *MockCloudWatchAPI) GetQueryResults(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*cloudwatchlogs.GetQueryResultsOutput), args.Error(1)
+}
+
+func (m *MockCloudWatchAPI) DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*cloudwatchlogs.DescribeLogGroupsOutput), args.Error(1)
+}
+
 func TestRunAnalyze(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "production.log")
+	logContent := `Started GET "/users/123" for 127.0.0.1 at 2025-07-10 17:28:13 +0900 [abc123]
+Completed 200 OK in 150ms (Views: 100.0ms | ActiveRecord: 50.0ms) [abc123]
+`
+	require.NoError(t, os.WriteFile(logFile, []byte(logContent), 0644))
+
+	customLogFile := filepath.Join(t.TempDir(), "custom.log")
+	require.NoError(t, os.WriteFile(customLogFile, []byte("web-1 2025-07-10T08:28:13Z GET /users/123 -> 200 in 12.5ms\n"), 0644))
+	customConfigFile := filepath.Join(t.TempDir(), "excluded_paths.yml")
+	require.NoError(t, os.WriteFile(customConfigFile, []byte(`log_formats:
+  - name: custom
+    request: '^\S+ (?P<timestamp>\S+) (?P<method>\w+) (?P<path>\S+) -> (?P<status>\d+) in (?P<duration>[\d.]+)ms'
+`), 0644))
+	routesFilePath := filepath.Join(t.TempDir(), "routes.txt")
+	require.NoError(t, os.WriteFile(routesFilePath, []byte("user GET /users/:id(.:format) users#show\n"), 0644))
+	invalidNormalizationFile := filepath.Join(t.TempDir(), "excluded_paths.yml")
+	require.NoError(t, os.WriteFile(invalidNormalizationFile, []byte("normalization:\n  disable: [slug]\n"), 0644))
+	invalidConfigFile := filepath.Join(t.TempDir(), "excluded_paths.yml")
+	require.NoError(t, os.WriteFile(invalidConfigFile, []byte(`log_formats:
+  - name: custom
+    request: '(?P<method>\w+) (?P<path>\S+)'
+`), 0644))
+
 	tests := []struct {
 		name         string
 		setupFlags   func()
@@ -128,13 +213,13 @@ func TestRunAnalyze(t *testing.T) {
 			setupFlags: func() {
 				startTime = "invalid-format"
 				endTime = "2023-01-01T12:00:00"
-				logGroup = "test-log-group"
+				logGroups = []string{"test-log-group"}
 				profile = "test-profile"
 			},
 			cleanupFlags: func() {
 				startTime = ""
 				endTime = ""
-				logGroup = ""
+				logGroups = nil
 				profile = ""
 			},
 			expectError: true,
@@ -145,33 +230,521 @@ func TestRunAnalyze(t *testing.T) {
 			setupFlags: func() {
 				startTime = "2023-01-01T12:00:00"
 				endTime = "invalid-format"
-				logGroup = "test-log-group"
+				logGroups = []string{"test-log-group"}
 				profile = "test-profile"
 			},
 			cleanupFlags: func() {
 				startTime = ""
 				endTime = ""
-				logGroup = ""
+				logGroups = nil
 				profile = ""
 			},
 			expectError: true,
 			errorMsg:    "failed to parse end time",
 		},
+		{
+			name: "missing CloudWatch flags",
+			setupFlags: func() {
+				startTime = "2023-01-01T12:00:00"
+			},
+			cleanupFlags: func() {
+				startTime = ""
+			},
+			expectError: true,
+			errorMsg:    `required flag(s) ["end" "log-group" "profile"] not set`,
+		},
+		{
+			name: "log group prefix instead of log group",
+			setupFlags: func() {
+				startTime = "2023-01-01T12:00:00"
+				endTime = "2023-01-01T13:00:00"
+				logGroupPrefixes = []string{"/ecs/rails-"}
+			},
+			cleanupFlags: func() {
+				startTime = ""
+				endTime = ""
+				logGroupPrefixes = nil
+			},
+			expectError: true,
+			errorMsg:    `required flag(s) ["profile"] not set`,
+		},
+		{
+			name: "analyze local log file without CloudWatch flags",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+			},
+			expectError: false,
+		},
+		{
+			name: "save analysis result",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				savePath = filepath.Join(t.TempDir(), "result.json")
+			},
+			cleanupFlags: func() {
+				result, err := analyzer.LoadResultFile(savePath)
+				require.NoError(t, err)
+				assert.Equal(t, 1, result.PathMetrics["/users/:id"].Count)
+
+				inputFiles = nil
+				savePath = ""
+			},
+			expectError: false,
+		},
+		{
+			name: "relative window satisfies start and end",
+			setupFlags: func() {
+				last = "1h"
+			},
+			cleanupFlags: func() {
+				last = ""
+			},
+			expectError: true,
+			errorMsg:    `required flag(s) ["log-group" "profile"] not set`,
+		},
+		{
+			name: "group by method",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				groupBy = "method"
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				groupBy = ""
+			},
+			expectError: false,
+		},
+		{
+			name: "unsupported group by dimension",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				groupBy = "host"
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				groupBy = ""
+			},
+			expectError: true,
+			errorMsg:    `unsupported group by dimension "host"`,
+		},
+		{
+			name: "output incomplete requests",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				incomplete = true
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				incomplete = false
+			},
+			expectError: false,
+		},
+		{
+			name: "incomplete requests with detailed output",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				incomplete = true
+				detailed = true
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				incomplete = false
+				detailed = false
+			},
+			expectError: true,
+			errorMsg:    "--incomplete cannot be combined with --detailed",
+		},
+		{
+			name: "output query parameters",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				queryParams = true
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				queryParams = false
+			},
+			expectError: false,
+		},
+		{
+			name: "query parameters with detailed output",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				queryParams = true
+				detailed = true
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				queryParams = false
+				detailed = false
+			},
+			expectError: true,
+			errorMsg:    "--query-params cannot be combined with --incomplete or --detailed",
+		},
+		{
+			name: "negative diagnostic samples",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				diagnosticSamples = -1
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				diagnosticSamples = 0
+			},
+			expectError: true,
+			errorMsg:    "diagnostic samples must not be negative",
+		},
+		{
+			name: "unsupported aggregate by key",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				aggregateBy = "controller"
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				aggregateBy = analyzer.AggregateByPath
+			},
+			expectError: true,
+			errorMsg:    `unsupported aggregate by key "controller"`,
+		},
+		{
+			name: "auto detected log format",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				logFormat = analyzer.LogFormatAuto
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				logFormat = analyzer.LogFormatRails
+			},
+			expectError: false,
+		},
+		{
+			name: "unsupported log format",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				logFormat = "syslog"
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				logFormat = analyzer.LogFormatRails
+			},
+			expectError: true,
+			errorMsg:    "unsupported log format: syslog",
+		},
+		{
+			name: "normalize paths with routes",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				routesFile = routesFilePath
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				routesFile = ""
+			},
+			expectError: false,
+		},
+		{
+			name: "collapse high-cardinality path segments",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				collapseThreshold = 1
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				collapseThreshold = 0
+			},
+			expectError: false,
+		},
+		{
+			name: "negative collapse threshold",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				collapseThreshold = -1
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				collapseThreshold = 0
+			},
+			expectError: true,
+			errorMsg:    "collapse threshold must not be negative: -1",
+		},
+		{
+			name: "negative collapse max count",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				collapseThreshold = 1
+				collapseMaxCount = -1
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				collapseThreshold = 0
+				collapseMaxCount = 0
+			},
+			expectError: true,
+			errorMsg:    "collapse max count must not be negative: -1",
+		},
+		{
+			name: "missing routes file",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				routesFile = filepath.Join(t.TempDir(), "missing.txt")
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				routesFile = ""
+			},
+			expectError: true,
+			errorMsg:    "failed to read routes file",
+		},
+		{
+			name: "invalid normalization rules in the config file",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				configPath = invalidNormalizationFile
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				configPath = ""
+			},
+			expectError: true,
+			errorMsg:    `unknown normalization detector "slug"`,
+		},
+		{
+			name: "custom log format from the config file",
+			setupFlags: func() {
+				inputFiles = []string{customLogFile}
+				configPath = customConfigFile
+				logFormat = "custom"
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				configPath = ""
+				logFormat = analyzer.LogFormatRails
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid custom log format in the config file",
+			setupFlags: func() {
+				inputFiles = []string{customLogFile}
+				configPath = invalidConfigFile
+				logFormat = "custom"
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				configPath = ""
+				logFormat = analyzer.LogFormatRails
+			},
+			expectError: true,
+			errorMsg:    `request pattern of log format "custom" must capture method, path, status, duration`,
+		},
+		{
+			name: "insights backend with structured log format",
+			setupFlags: func() {
+				startTime = "2023-01-01T12:00:00"
+				endTime = "2023-01-01T13:00:00"
+				logGroups = []string{"test-log-group"}
+				profile = "test-profile"
+				backend = backendInsights
+				logFormat = analyzer.LogFormatJSON
+			},
+			cleanupFlags: func() {
+				startTime = ""
+				endTime = ""
+				logGroups = nil
+				profile = ""
+				backend = backendFilter
+				logFormat = analyzer.LogFormatRails
+			},
+			expectError: true,
+			errorMsg:    "the insights backend only supports --log-format rails",
+		},
+		{
+			name: "negative pending timeout",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				pendingTimeout = -time.Second
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				pendingTimeout = 10 * time.Minute
+			},
+			expectError: true,
+			errorMsg:    "pending timeout must not be negative",
+		},
+		{
+			name: "invalid worker count",
+			setupFlags: func() {
+				startTime = "2023-01-01T12:00:00"
+				endTime = "2023-01-01T13:00:00"
+				logGroups = []string{"test-log-group"}
+				profile = "test-profile"
+				workers = 0
+			},
+			cleanupFlags: func() {
+				startTime = ""
+				endTime = ""
+				logGroups = nil
+				profile = ""
+				workers = 4
+			},
+			expectError: true,
+			errorMsg:    "workers must be at least 1",
+		},
+		{
+			name: "negative rate limit",
+			setupFlags: func() {
+				startTime = "2023-01-01T12:00:00"
+				endTime = "2023-01-01T13:00:00"
+				logGroups = []string{"test-log-group"}
+				profile = "test-profile"
+				rateLimit = -1
+			},
+			cleanupFlags: func() {
+				startTime = ""
+				endTime = ""
+				logGroups = nil
+				profile = ""
+				rateLimit = 0
+			},
+			expectError: true,
+			errorMsg:    "rate limit must not be negative",
+		},
+		{
+			name: "unsupported backend",
+			setupFlags: func() {
+				startTime = "2023-01-01T12:00:00"
+				endTime = "2023-01-01T13:00:00"
+				logGroups = []string{"test-log-group"}
+				profile = "test-profile"
+				backend = "athena"
+			},
+			cleanupFlags: func() {
+				startTime = ""
+				endTime = ""
+				logGroups = nil
+				profile = ""
+				backend = "filter"
+			},
+			expectError: true,
+			errorMsg:    "unsupported backend: athena",
+		},
+		{
+			name: "missing local log file",
+			setupFlags: func() {
+				inputFiles = []string{filepath.Join(t.TempDir(), "missing.log")}
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+			},
+			expectError: true,
+			errorMsg:    "failed to open log file",
+		},
+		{
+			name: "unsupported output format",
+			setupFlags: func() {
+				startTime = "2023-01-01T12:00:00"
+				endTime = "2023-01-01T13:00:00"
+				logGroups = []string{"test-log-group"}
+				profile = "test-profile"
+				format = "xml"
+			},
+			cleanupFlags: func() {
+				startTime = ""
+				endTime = ""
+				logGroups = nil
+				profile = ""
+				format = "json"
+			},
+			expectError: true,
+			errorMsg:    "unsupported output format",
+		},
+		{
+			name: "unsupported sort key",
+			setupFlags: func() {
+				startTime = "2023-01-01T12:00:00"
+				endTime = "2023-01-01T13:00:00"
+				logGroups = []string{"test-log-group"}
+				profile = "test-profile"
+				sortBy = "name"
+			},
+			cleanupFlags: func() {
+				startTime = ""
+				endTime = ""
+				logGroups = nil
+				profile = ""
+				sortBy = "count"
+			},
+			expectError: true,
+			errorMsg:    "unsupported sort key",
+		},
 		{
 			name: "end time before start time",
 			setupFlags: func() {
 				startTime = "2023-01-01T12:00:00"
 				endTime = "2023-01-01T11:00:00"
-				logGroup = "test-log-group"
+				logGroups = []string{"test-log-group"}
+				profile = "test-profile"
+			},
+			cleanupFlags: func() {
+				startTime = ""
+				endTime = ""
+				logGroups = nil
+				profile = ""
+			},
+			expectError: true,
+			errorMsg:    "end time 2023-01-01T11:00:00Z must be after start time 2023-01-01T12:00:00Z",
+		},
+		{
+			name: "window larger than max window",
+			setupFlags: func() {
+				startTime = "2023-01-01T00:00:00"
+				endTime = "2023-01-09T00:00:00"
+				logGroups = []string{"test-log-group"}
 				profile = "test-profile"
 			},
 			cleanupFlags: func() {
 				startTime = ""
 				endTime = ""
-				logGroup = ""
+				logGroups = nil
 				profile = ""
 			},
-			expectError: false, // Time validation happens in CloudWatch layer
+			expectError: true,
+			errorMsg:    "exceeds the maximum window of 7d",
+		},
+		{
+			name: "large window allowed explicitly",
+			setupFlags: func() {
+				startTime = "2023-01-01T00:00:00"
+				endTime = "2023-01-09T00:00:00"
+				logGroups = []string{"test-log-group"}
+				profile = "test-profile"
+				allowLarge = true
+			},
+			cleanupFlags: func() {
+				startTime = ""
+				endTime = ""
+				logGroups = nil
+				profile = ""
+				allowLarge = false
+			},
+			expectError: false,
+		},
+		{
+			name: "dry run with local input",
+			setupFlags: func() {
+				inputFiles = []string{logFile}
+				dryRun = true
+			},
+			cleanupFlags: func() {
+				inputFiles = nil
+				dryRun = false
+			},
+			expectError: false,
 		},
 	}
 
@@ -199,6 +772,303 @@ func TestRunAnalyze(t *testing.T) {
 	}
 }
 
+func TestTimeRangeOptions_Resolve(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	now := time.Date(2023, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		start         string
+		end           string
+		since         string
+		last          string
+		expectedStart time.Time
+		expectedEnd   time.Time
+		errorMsg      string
+	}{
+		{
+			name:          "absolute times in time zone",
+			start:         "2023-01-01T12:00:00",
+			end:           "2023-01-01T13:00:00",
+			expectedStart: time.Date(2023, 1, 1, 3, 0, 0, 0, time.UTC),
+			expectedEnd:   time.Date(2023, 1, 1, 4, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "RFC3339 and relative end",
+			start:         "2023-06-15T00:00:00Z",
+			end:           "now-1h",
+			expectedStart: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC),
+			expectedEnd:   now.Add(-time.Hour),
+		},
+		{
+			name:          "since until now",
+			since:         "2h",
+			expectedStart: now.Add(-2 * time.Hour),
+			expectedEnd:   now,
+		},
+		{
+			name:          "since with explicit end",
+			since:         "1d",
+			end:           "now-1h",
+			expectedStart: now.Add(-24 * time.Hour),
+			expectedEnd:   now.Add(-time.Hour),
+		},
+		{
+			name:          "last",
+			last:          "30m",
+			expectedStart: now.Add(-30 * time.Minute),
+			expectedEnd:   now,
+		},
+		{
+			name:     "last combined with start",
+			last:     "30m",
+			start:    "now-1h",
+			errorMsg: "--last cannot be combined",
+		},
+		{
+			name:     "since combined with start",
+			since:    "30m",
+			start:    "now-1h",
+			errorMsg: "--since cannot be combined with --start",
+		},
+		{
+			name:     "invalid since",
+			since:    "soon",
+			errorMsg: "failed to parse --since",
+		},
+		{
+			name:     "invalid start",
+			start:    "yesterday",
+			errorMsg: "failed to parse start time",
+		},
+		{
+			name:     "invalid end",
+			start:    "now-1h",
+			end:      "later",
+			errorMsg: "failed to parse end time",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeRange := timeRangeOptions{start: tt.start, end: tt.end, since: tt.since, last: tt.last}
+
+			start, end, err := timeRange.resolve(tokyo, now)
+
+			if tt.errorMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStart, start.UTC())
+			assert.Equal(t, tt.expectedEnd, end.UTC())
+		})
+	}
+}
+
+func TestValidateTimeRange(t *testing.T) {
+	now := time.Date(2023, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		start         time.Time
+		end           time.Time
+		checkWindow   bool
+		maxWindow     string
+		allowLarge    bool
+		workers       int
+		sliceDuration time.Duration
+		errorMsg      string
+	}{
+		{
+			name:        "valid window",
+			start:       now.Add(-time.Hour),
+			end:         now,
+			checkWindow: true,
+			maxWindow:   "7d",
+		},
+		{
+			name:        "open range is not checked",
+			start:       now.Add(-30 * 24 * time.Hour),
+			checkWindow: true,
+			maxWindow:   "7d",
+		},
+		{
+			name:      "inverted range",
+			start:     now,
+			end:       now.Add(-time.Hour),
+			maxWindow: "7d",
+			errorMsg:  "must be after start time",
+		},
+		{
+			name:      "empty range",
+			start:     now,
+			end:       now,
+			maxWindow: "7d",
+			errorMsg:  "must be after start time",
+		},
+		{
+			name:      "start in the future",
+			start:     now.Add(time.Hour),
+			end:       now.Add(2 * time.Hour),
+			maxWindow: "7d",
+			errorMsg:  "is in the future",
+		},
+		{
+			name:        "window too large",
+			start:       now.Add(-25 * time.Hour),
+			end:         now,
+			checkWindow: true,
+			maxWindow:   "1d",
+			errorMsg:    "time range of 25h0m0s exceeds the maximum window of 1d",
+		},
+		{
+			name:        "large window allowed",
+			start:       now.Add(-25 * time.Hour),
+			end:         now,
+			checkWindow: true,
+			maxWindow:   "1d",
+			allowLarge:  true,
+		},
+		{
+			name:        "limit disabled",
+			start:       now.Add(-365 * 24 * time.Hour),
+			end:         now,
+			checkWindow: true,
+			maxWindow:   "0",
+		},
+		{
+			name:      "window not checked for local files",
+			start:     now.Add(-25 * time.Hour),
+			end:       now,
+			maxWindow: "1d",
+		},
+		{
+			name:        "invalid max window",
+			start:       now.Add(-time.Hour),
+			end:         now,
+			checkWindow: true,
+			maxWindow:   "a week",
+			errorMsg:    "failed to parse --max-window",
+		},
+		{
+			name:          "too many slices",
+			start:         now.Add(-365 * 24 * time.Hour),
+			end:           now,
+			checkWindow:   true,
+			allowLarge:    true,
+			workers:       4,
+			sliceDuration: time.Minute,
+			errorMsg:      "--slice-duration 1m0s splits the time range into 525601 slices, more than the maximum of 100000",
+		},
+		{
+			name:          "slices not checked when fetching sequentially",
+			start:         now.Add(-365 * 24 * time.Hour),
+			end:           now,
+			checkWindow:   true,
+			allowLarge:    true,
+			workers:       1,
+			sliceDuration: time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := sourceOptions{
+				maxWindow:     tt.maxWindow,
+				allowLarge:    tt.allowLarge,
+				backend:       backendFilter,
+				workers:       tt.workers,
+				sliceDuration: tt.sliceDuration,
+			}
+
+			err := opts.validateTimeRange(tt.start, tt.end, now, tt.checkWindow)
+
+			if tt.errorMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPrintDryRun(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(3 * time.Hour)
+
+	opts := sourceOptions{inputFiles: []string{"production.log", "-"}}
+
+	var buf bytes.Buffer
+	require.NoError(t, opts.printDryRun(context.Background(), &buf, start, end, end))
+
+	assert.Equal(t, `Dry run: no log events are fetched
+Window (UTC):    2023-01-01T00:00:00Z - 2023-01-01T03:00:00Z (3h0m0s)
+Input:           production.log, -
+`, buf.String())
+}
+
+func TestLoadTimeZone(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "excluded_paths.yml")
+	require.NoError(t, os.WriteFile(configFile, []byte("timezone: America/New_York\n"), 0644))
+
+	opts := sourceOptions{configPath: configFile}
+
+	// The config file is used when --tz is not given
+	loc, err := opts.loadTimeZone()
+	require.NoError(t, err)
+	assert.Equal(t, "America/New_York", loc.String())
+
+	// --tz takes precedence over the config file
+	opts.timeZone = "UTC"
+	loc, err = opts.loadTimeZone()
+	require.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+
+	opts.timeZone = "Invalid/Zone"
+	_, err = opts.loadTimeZone()
+	assert.ErrorContains(t, err, "failed to load time zone")
+}
+
+func TestLogFilterPattern(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "excluded_paths.yml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`log_formats:
+  - name: access
+    request: '(?P<method>\w+) (?P<path>\S+) (?P<status>\d+) (?P<duration>\d+)'
+    filter_pattern: '?access'
+  - name: prefixed
+    started: 'Started (?P<method>\w+) "(?P<path>[^"]+)"'
+    completed: 'Completed (?P<status>\d+) .* in (?P<duration>\d+)ms'
+`), 0644))
+
+	opts := sourceOptions{configPath: configFile}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: analyzer.LogFormatRails, want: cloudwatch.DefaultFilterPattern},
+		{format: analyzer.LogFormatJSON, want: "?status"},
+		{format: "access", want: "?access"},
+		{format: "prefixed", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			opts.logFormat = tt.format
+			pattern, err := opts.logFilterPattern()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, pattern)
+		})
+	}
+
+	opts.logFormat = "syslog"
+	_, err := opts.logFilterPattern()
+	assert.ErrorContains(t, err, "unsupported log format: syslog")
+}
+
 func TestRunAnalyzeTimeConversion(t *testing.T) {
 	// Test JST to UTC conversion logic
 	jst, err := time.LoadLocation("Asia/Tokyo")
@@ -270,3 +1140,53 @@ func TestAnalyzeCommandIntegration(t *testing.T) {
 		assert.Equal(t, "analyze", analyzeCmd.Use, "Command use should be 'analyze'")
 	})
 }
+
+// fakeEntrySource sends fixed entries, then returns err
+type fakeEntrySource struct {
+	entries []*models.LogEntry
+	err     error
+}
+
+func (f *fakeEntrySource) StreamEntries(ctx context.Context, startTime, endTime time.Time, entries chan<- *models.LogEntry) error {
+	defer close(entries)
+	for _, entry := range f.entries {
+		entries <- entry
+	}
+	return f.err
+}
+
+func TestLogSource_Analyze(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	entries := []*models.LogEntry{
+		{Type: "Started", Method: "GET", Path: "/users", Timestamp: start.Add(time.Minute), SessionID: "req-1"},
+		{Type: "Completed", StatusCode: 200, Duration: 120, Timestamp: start.Add(time.Minute), SessionID: "req-1"},
+	}
+
+	t.Run("entry source", func(t *testing.T) {
+		result, err := entrySource{source: &fakeEntrySource{entries: entries}}.analyze(context.Background(), analyzer.NewAnalyzer(), start, end)
+
+		require.NoError(t, err)
+		require.Contains(t, result.PathMetrics, "/users")
+		assert.Equal(t, 1, result.PathMetrics["/users"].Count)
+	})
+
+	t.Run("entry source error", func(t *testing.T) {
+		_, err := entrySource{source: &fakeEntrySource{entries: entries, err: errors.New("query failed")}}.analyze(context.Background(), analyzer.NewAnalyzer(), start, end)
+
+		assert.EqualError(t, err, "query failed")
+	})
+
+	t.Run("event source", func(t *testing.T) {
+		logFile := filepath.Join(t.TempDir(), "production.log")
+		require.NoError(t, os.WriteFile(logFile, []byte(`Started GET "/users" for 127.0.0.1 at 2023-01-01 00:01:00 +0000
+Completed 200 OK in 120ms (Views: 80.0ms | ActiveRecord: 20.0ms)
+`), 0o644))
+
+		result, err := eventSource{source: source.NewFileSource([]string{logFile})}.analyze(context.Background(), analyzer.NewAnalyzer(), start, end)
+
+		require.NoError(t, err)
+		require.Contains(t, result.PathMetrics, "/users")
+		assert.Equal(t, 1, result.PathMetrics["/users"].Count)
+	})
+}
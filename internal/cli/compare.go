@@ -0,0 +1,244 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/analyzer"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/cloudwatch"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/output"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/timeutil"
+)
+
+// compareOptions holds the flags of the compare command
+type compareOptions struct {
+	sourceOptions
+	baselineStart   string
+	baselineEnd     string
+	baselineFile    string
+	targetStart     string
+	targetEnd       string
+	targetFile      string
+	format          string
+	pendingTimeout  time.Duration
+	aggregateBy     string
+	threshold       float64
+	minCount        int
+	regressionsOnly bool
+}
+
+// compareOpts is bound to the flags of the compare command
+var compareOpts compareOptions
+
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Compare request metrics of two time windows and report regressions",
+	Long: `Compare request metrics by path between a baseline and a target.
+Each side is either a time window fetched from CloudWatch or a result file saved with "analyze --save".
+Paths whose average or p95 time grew by more than --threshold percent are flagged as regressions.`,
+	RunE: runCompare,
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().StringVar(&compareOpts.baselineStart, "baseline-start", "", "Baseline start time: RFC3339, 2006-01-02T15:04:05 in --tz, or now-1d")
+	compareCmd.Flags().StringVar(&compareOpts.baselineEnd, "baseline-end", "", "Baseline end time: RFC3339, 2006-01-02T15:04:05 in --tz, or now-1d")
+	compareCmd.Flags().StringVar(&compareOpts.baselineFile, "baseline-file", "", "Baseline result file saved with analyze --save instead of a time window")
+	compareCmd.Flags().StringVar(&compareOpts.targetStart, "target-start", "", "Target start time: RFC3339, 2006-01-02T15:04:05 in --tz, or now-1d")
+	compareCmd.Flags().StringVar(&compareOpts.targetEnd, "target-end", "", "Target end time: RFC3339, 2006-01-02T15:04:05 in --tz, or now-1d")
+	compareCmd.Flags().StringVar(&compareOpts.targetFile, "target-file", "", "Target result file saved with analyze --save instead of a time window")
+	compareCmd.Flags().StringVar(&compareOpts.timeZone, "tz", "", "IANA time zone for times without an offset (default: timezone from config file, else local time)")
+	compareCmd.Flags().StringVar(&compareOpts.maxWindow, "max-window", defaultMaxWindow, "Largest CloudWatch time range allowed without --allow-large-window (e.g. 12h, 7d; 0 disables the limit)")
+	compareCmd.Flags().BoolVar(&compareOpts.allowLarge, "allow-large-window", false, "Query CloudWatch time ranges larger than --max-window")
+	compareCmd.Flags().StringArrayVar(&compareOpts.logGroups, "log-group", nil, "CloudWatch Logs log group name (required for time windows unless --log-group-prefix; repeatable)")
+	compareCmd.Flags().StringArrayVar(&compareOpts.logGroupPrefixes, "log-group-prefix", nil, "Also compare every log group whose name starts with this prefix (repeatable)")
+	compareCmd.Flags().StringVar(&compareOpts.logStreamPrefix, "log-stream-prefix", "", "Only read log streams whose name starts with this prefix (e.g. one ECS service or task)")
+	compareCmd.Flags().StringVar(&compareOpts.profile, "profile", "", "AWS profile name (required for time windows)")
+	compareCmd.Flags().StringVar(&compareOpts.backend, "backend", backendFilter, "CloudWatch fetch strategy: filter (FilterLogEvents) or insights (Logs Insights query)")
+	compareCmd.Flags().IntVar(&compareOpts.workers, "workers", cloudwatch.DefaultWorkers, "Number of CloudWatch time slices fetched concurrently (1 fetches sequentially)")
+	compareCmd.Flags().DurationVar(&compareOpts.sliceDuration, "slice-duration", cloudwatch.DefaultSliceDuration, "Length of each CloudWatch time slice fetched by a worker; slices are streamed, so memory use does not grow with it")
+	compareCmd.Flags().IntVar(&compareOpts.maxAttempts, "max-attempts", cloudwatch.DefaultMaxAttempts, "Attempts per CloudWatch request before giving up on throttling or transient errors")
+	compareCmd.Flags().Float64Var(&compareOpts.rateLimit, "rate-limit", 0, "Maximum CloudWatch requests per second across all workers (0 means unlimited)")
+	compareCmd.Flags().DurationVar(&compareOpts.pendingTimeout, "pending-timeout", analyzer.DefaultPendingTimeout, "Discard Started entries without a Completed entry after this long (0 keeps them all)")
+	compareCmd.Flags().StringVar(&compareOpts.logFormat, "log-format", analyzer.LogFormatRails, "Format of the log lines: "+strings.Join(analyzer.LogFormats(), ", ")+", or the name of a log_formats entry in the config file")
+	compareCmd.Flags().StringVar(&compareOpts.aggregateBy, "aggregate-by", analyzer.AggregateByPath, "Aggregate requests by: "+strings.Join(analyzer.AggregateByKeys(), ", ")+" (Controller#action)")
+	compareCmd.Flags().StringVar(&compareOpts.routesFile, "routes", "", "Normalize paths with the routes printed by rails routes (text or JSON), falling back to the heuristics")
+	compareCmd.Flags().StringVar(&compareOpts.configPath, "config", "", "Path to custom exclusion configuration file (optional)")
+	compareCmd.Flags().StringVar(&compareOpts.format, "format", output.FormatJSON, "Output format: "+strings.Join(output.SupportedFormats(), ", "))
+	compareCmd.Flags().Float64Var(&compareOpts.threshold, "threshold", analyzer.DefaultRegressionThreshold, "Growth of the average or p95 time in percent flagged as a regression")
+	compareCmd.Flags().IntVar(&compareOpts.minCount, "min-count", 0, "Skip paths with fewer requests than this in both windows")
+	compareCmd.Flags().BoolVar(&compareOpts.regressionsOnly, "regressions-only", false, "Output only regressed paths")
+}
+
+func runCompare(cmd *cobra.Command, args []string) error {
+	opts := compareOpts
+	slog.Info("Starting comparison",
+		"baselineStart", opts.baselineStart,
+		"baselineEnd", opts.baselineEnd,
+		"baselineFile", opts.baselineFile,
+		"targetStart", opts.targetStart,
+		"targetEnd", opts.targetEnd,
+		"targetFile", opts.targetFile,
+		"threshold", opts.threshold,
+	)
+
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	formatter, err := output.NewFormatter(opts.format)
+	if err != nil {
+		return err
+	}
+
+	regressionOptions := analyzer.CompareOptions{
+		ThresholdPct:    opts.threshold,
+		MinCount:        opts.minCount,
+		RegressionsOnly: opts.regressionsOnly,
+	}
+	if err := regressionOptions.Validate(); err != nil {
+		return err
+	}
+
+	aggregateByAction, err := analyzer.ParseAggregateBy(opts.aggregateBy)
+	if err != nil {
+		return err
+	}
+
+	// Both windows are validated before anything is fetched
+	loc, err := opts.loadTimeZone()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	baselineSide, err := opts.resolveComparisonSide("baseline", opts.baselineFile, opts.baselineStart, opts.baselineEnd, loc, now)
+	if err != nil {
+		return err
+	}
+	targetSide, err := opts.resolveComparisonSide("target", opts.targetFile, opts.targetStart, opts.targetEnd, loc, now)
+	if err != nil {
+		return err
+	}
+
+	logAnalyzer, err := analyzer.NewAnalyzerWithConfig(opts.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to initialize analyzer: %w", err)
+	}
+	if err := logAnalyzer.SetPendingTimeout(opts.pendingTimeout); err != nil {
+		return err
+	}
+	logAnalyzer.SetGroupOptions(analyzer.GroupOptions{Action: aggregateByAction})
+	if err := opts.configureParsing(logAnalyzer); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	baseline, err := baselineSide.load(ctx, opts.sourceOptions, logAnalyzer)
+	if err != nil {
+		return err
+	}
+	target, err := targetSide.load(ctx, opts.sourceOptions, logAnalyzer)
+	if err != nil {
+		return err
+	}
+
+	if err := analyzer.CheckComparable(baseline, target); err != nil {
+		return err
+	}
+
+	comparisons := analyzer.CompareResults(baseline, target, regressionOptions)
+	if err := logAnalyzer.OutputComparison(comparisons, formatter, os.Stdout); err != nil {
+		return fmt.Errorf("failed to output comparison: %w", err)
+	}
+
+	return nil
+}
+
+// validate checks that each side is either a result file or a complete time window
+func (o compareOptions) validate() error {
+	usesCloudWatch := false
+	for _, side := range []struct {
+		name, file, start, end string
+	}{
+		{"baseline", o.baselineFile, o.baselineStart, o.baselineEnd},
+		{"target", o.targetFile, o.targetStart, o.targetEnd},
+	} {
+		hasWindow := side.start != "" || side.end != ""
+		switch {
+		case side.file != "" && hasWindow:
+			return fmt.Errorf("--%s-file cannot be combined with --%s-start/--%s-end", side.name, side.name, side.name)
+		case side.file != "":
+			continue
+		case side.start == "" || side.end == "":
+			return fmt.Errorf("%s requires --%s-file or both --%s-start and --%s-end", side.name, side.name, side.name, side.name)
+		}
+		usesCloudWatch = true
+	}
+
+	if !usesCloudWatch {
+		return nil
+	}
+
+	var missing []string
+	if len(o.logGroups) == 0 && len(o.logGroupPrefixes) == 0 {
+		missing = append(missing, "log-group")
+	}
+	if o.profile == "" {
+		missing = append(missing, "profile")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("required flag(s) %q not set (or use --baseline-file and --target-file)", missing)
+	}
+
+	return o.validateCloudWatch()
+}
+
+// comparisonSide is one side of the comparison, either a saved result file or a time window
+type comparisonSide struct {
+	name  string
+	file  string
+	start time.Time
+	end   time.Time
+}
+
+// resolveComparisonSide parses and validates the time window of a side unless it is loaded from a file
+func (o compareOptions) resolveComparisonSide(name, file, start, end string, loc *time.Location, now time.Time) (*comparisonSide, error) {
+	side := &comparisonSide{name: name, file: file}
+	if file != "" {
+		return side, nil
+	}
+
+	var err error
+	side.start, err = timeutil.ParseTime(start, now, loc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s start time: %w", name, err)
+	}
+	side.end, err = timeutil.ParseTime(end, now, loc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s end time: %w", name, err)
+	}
+	if err := o.validateTimeRange(side.start, side.end, now, true); err != nil {
+		return nil, fmt.Errorf("invalid %s window: %w", name, err)
+	}
+
+	return side, nil
+}
+
+// load reads the side from its result file or analyzes its time window from the source of sourceOpts
+func (s *comparisonSide) load(ctx context.Context, sourceOpts sourceOptions, logAnalyzer *analyzer.Analyzer) (*models.AnalysisResult, error) {
+	if s.file != "" {
+		slog.Info("Loading saved analysis result", "side", s.name, "path", s.file)
+		return analyzer.LoadResultFile(s.file)
+	}
+	return sourceOpts.analyzeRange(ctx, logAnalyzer, s.start, s.end)
+}
@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/analyzer"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/cloudwatch"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+func TestCompareCommand(t *testing.T) {
+	assert.NotNil(t, compareCmd)
+	assert.Equal(t, "compare", compareCmd.Use)
+
+	for _, name := range []string{
+		"baseline-start", "baseline-end", "baseline-file",
+		"target-start", "target-end", "target-file",
+		"log-group", "log-group-prefix", "log-stream-prefix", "profile", "backend", "log-format", "aggregate-by", "routes", "config", "format",
+		"threshold", "min-count", "regressions-only",
+	} {
+		assert.NotNil(t, compareCmd.Flags().Lookup(name), "Flag %s should exist", name)
+	}
+	assert.Equal(t, "20", compareCmd.Flags().Lookup("threshold").DefValue)
+}
+
+func writeResultFile(t *testing.T, result *models.AnalysisResult) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "result.json")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, analyzer.SaveResult(result, file))
+	require.NoError(t, file.Close())
+	return path
+}
+
+func TestRunCompare(t *testing.T) {
+	baselinePath := writeResultFile(t, &models.AnalysisResult{
+		PathMetrics: map[string]*models.PathMetrics{
+			"/users": {Path: "/users", Count: 10, AverageTime: 100, P95Time: 150},
+		},
+	})
+	targetPath := writeResultFile(t, &models.AnalysisResult{
+		PathMetrics: map[string]*models.PathMetrics{
+			"/users": {Path: "/users", Count: 12, AverageTime: 180, P95Time: 250},
+		},
+	})
+	groupedPath := writeResultFile(t, &models.AnalysisResult{
+		GroupBy: analyzer.GroupByMethod,
+		PathMetrics: map[string]*models.PathMetrics{
+			"GET /users": {Method: "GET", Path: "/users", Count: 12, AverageTime: 180, P95Time: 250},
+		},
+	})
+
+	tests := []struct {
+		name        string
+		setupFlags  func(opts *compareOptions)
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name: "compare saved result files",
+			setupFlags: func(opts *compareOptions) {
+				opts.baselineFile = baselinePath
+				opts.targetFile = targetPath
+			},
+			expectError: false,
+		},
+		{
+			name: "result files with different grouping",
+			setupFlags: func(opts *compareOptions) {
+				opts.baselineFile = baselinePath
+				opts.targetFile = groupedPath
+			},
+			expectError: true,
+			errorMsg:    "baseline is grouped by path only but target by path and method",
+		},
+		{
+			name: "missing target",
+			setupFlags: func(opts *compareOptions) {
+				opts.baselineFile = baselinePath
+			},
+			expectError: true,
+			errorMsg:    "target requires --target-file or both --target-start and --target-end",
+		},
+		{
+			name: "file combined with window",
+			setupFlags: func(opts *compareOptions) {
+				opts.baselineFile = baselinePath
+				opts.baselineStart = "2023-01-01T12:00:00"
+				opts.targetFile = targetPath
+			},
+			expectError: true,
+			errorMsg:    "--baseline-file cannot be combined with --baseline-start/--baseline-end",
+		},
+		{
+			name: "time window without CloudWatch flags",
+			setupFlags: func(opts *compareOptions) {
+				opts.baselineStart = "2023-01-01T12:00:00"
+				opts.baselineEnd = "2023-01-01T13:00:00"
+				opts.targetFile = targetPath
+			},
+			expectError: true,
+			errorMsg:    `required flag(s) ["log-group" "profile"] not set`,
+		},
+		{
+			name: "inverted baseline window",
+			setupFlags: func(opts *compareOptions) {
+				opts.baselineStart = "2023-01-01T13:00:00Z"
+				opts.baselineEnd = "2023-01-01T12:00:00Z"
+				opts.targetFile = targetPath
+				opts.logGroups = []string{"test-log-group"}
+				opts.profile = "test-profile"
+			},
+			expectError: true,
+			errorMsg:    "invalid baseline window: end time 2023-01-01T12:00:00Z must be after start time",
+		},
+		{
+			name: "negative threshold",
+			setupFlags: func(opts *compareOptions) {
+				opts.baselineFile = baselinePath
+				opts.targetFile = targetPath
+				opts.threshold = -1
+			},
+			expectError: true,
+			errorMsg:    "regression threshold must not be negative",
+		},
+		{
+			name: "missing result file",
+			setupFlags: func(opts *compareOptions) {
+				opts.baselineFile = filepath.Join(t.TempDir(), "missing.json")
+				opts.targetFile = targetPath
+			},
+			expectError: true,
+			errorMsg:    "failed to open result file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := compareOpts
+			defer func() { compareOpts = saved }()
+			tt.setupFlags(&compareOpts)
+
+			err := runCompare(nil, nil)
+
+			if tt.expectError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCompareFlags_IndependentOfAnalyze(t *testing.T) {
+	savedCompare := compareOpts
+	defer func() {
+		compareOpts = savedCompare
+		logGroups = nil
+		workers = cloudwatch.DefaultWorkers
+	}()
+
+	require.NoError(t, compareCmd.Flags().Set("log-group", "compare-group"))
+	require.NoError(t, compareCmd.Flags().Set("workers", "2"))
+	require.NoError(t, analyzeCmd.Flags().Set("workers", "8"))
+
+	assert.Equal(t, []string{"compare-group"}, compareOpts.logGroups)
+	assert.Equal(t, 2, compareOpts.workers)
+	assert.Nil(t, logGroups)
+	assert.Equal(t, 8, workers)
+	assert.Equal(t, 8, analyzeSourceOptions().workers)
+}
@@ -2,6 +2,7 @@ package cloudwatch
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -13,30 +14,45 @@ import (
 // CloudWatchLogsAPI defines the interface for CloudWatch Logs operations
 type CloudWatchLogsAPI interface {
 	FilterLogEvents(ctx context.Context, params *cloudwatchlogs.FilterLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error)
+	StartQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error)
+	GetQueryResults(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error)
+	DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error)
 }
 
 // Client wraps AWS CloudWatch Logs client
 type Client struct {
-	api CloudWatchLogsAPI
+	api             CloudWatchLogsAPI
+	retry           RetryOptions
+	limiter         *rateLimiter
+	pollInterval    time.Duration
+	logStreamPrefix string
+	filterPattern   string
 }
 
+// DefaultFilterPattern fetches only the Rails Started, Processing and Completed lines
+// This reduces data transfer and costs by filtering at CloudWatch level
+const DefaultFilterPattern = `?Started ?Processing ?Completed`
+
 // NewClient creates a new CloudWatch client with AWS SDK configuration
 func NewClient(ctx context.Context, profile string) (*Client, error) {
-	var cfg aws.Config
-	var err error
-
+	// Throttled and transient failures are retried by withRetry with the configured options and rate
+	// limit, so the SDK must not retry on its own and multiply the attempts
+	optFns := []func(*config.LoadOptions) error{
+		config.WithRetryer(func() aws.Retryer { return aws.NopRetryer{} }),
+	}
 	if profile != "" {
-		cfg, err = config.LoadDefaultConfig(ctx, config.WithSharedConfigProfile(profile))
-	} else {
-		cfg, err = config.LoadDefaultConfig(ctx)
+		optFns = append(optFns, config.WithSharedConfigProfile(profile))
 	}
 
+	cfg, err := config.LoadDefaultConfig(ctx, optFns...)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Client{
-		api: cloudwatchlogs.NewFromConfig(cfg),
+		api:          cloudwatchlogs.NewFromConfig(cfg),
+		retry:        DefaultRetryOptions(),
+		pollInterval: DefaultPollInterval,
 	}, nil
 }
 
@@ -44,16 +60,55 @@ func NewClient(ctx context.Context, profile string) (*Client, error) {
 // This is primarily used for testing
 func NewClientWithAPI(api CloudWatchLogsAPI) *Client {
 	return &Client{
-		api: api,
+		api:          api,
+		retry:        DefaultRetryOptions(),
+		pollInterval: DefaultPollInterval,
+	}
+}
+
+// SetRetryOptions sets how throttled and transiently failing requests are retried
+func (c *Client) SetRetryOptions(opts RetryOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	c.retry = opts
+	return nil
+}
+
+// SetRateLimit limits the client to requestsPerSecond API calls per second; zero removes the limit
+func (c *Client) SetRateLimit(requestsPerSecond float64) error {
+	if requestsPerSecond < 0 {
+		return fmt.Errorf("rate limit must not be negative: %g", requestsPerSecond)
+	}
+	if requestsPerSecond == 0 {
+		c.limiter = nil
+		return nil
 	}
+	c.limiter = newRateLimiter(requestsPerSecond)
+	return nil
+}
+
+// SetLogStreamPrefix restricts every query to log streams whose name starts with prefix; empty searches all streams
+func (c *Client) SetLogStreamPrefix(prefix string) {
+	c.logStreamPrefix = prefix
+}
+
+// SetFilterPattern sets the CloudWatch filter pattern selecting the log events to fetch; empty uses DefaultFilterPattern
+func (c *Client) SetFilterPattern(pattern string) {
+	c.filterPattern = pattern
+}
+
+// eventFilterPattern returns the filter pattern of FilterLogEvents requests
+func (c *Client) eventFilterPattern() string {
+	if c.filterPattern == "" {
+		return DefaultFilterPattern
+	}
+	return c.filterPattern
 }
 
 // FilterLogEvents retrieves log events from CloudWatch Logs
 func (c *Client) FilterLogEvents(ctx context.Context, logGroupName string, startTime, endTime time.Time) ([]types.FilteredLogEvent, error) {
-	// Filter pattern to only fetch logs containing "Started" or "Completed"
-	// This reduces data transfer and costs by filtering at CloudWatch level
-	// Using regex pattern for unstructured Rails logs
-	filterPattern := `?Started ?Completed`
+	filterPattern := c.eventFilterPattern()
 
 	input := &cloudwatchlogs.FilterLogEventsInput{
 		LogGroupName:  &logGroupName,
@@ -61,8 +116,16 @@ func (c *Client) FilterLogEvents(ctx context.Context, logGroupName string, start
 		EndTime:       int64Ptr(endTime.UnixMilli()),
 		FilterPattern: &filterPattern,
 	}
+	if c.logStreamPrefix != "" {
+		input.LogStreamNamePrefix = &c.logStreamPrefix
+	}
 
-	output, err := c.api.FilterLogEvents(ctx, input)
+	var output *cloudwatchlogs.FilterLogEventsOutput
+	err := c.withRetry(ctx, func() error {
+		var err error
+		output, err = c.api.FilterLogEvents(ctx, input)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -73,28 +136,56 @@ func (c *Client) FilterLogEvents(ctx context.Context, logGroupName string, start
 // FilterLogEventsWithPagination retrieves all log events with pagination support
 func (c *Client) FilterLogEventsWithPagination(ctx context.Context, logGroupName string, startTime, endTime time.Time) ([]types.FilteredLogEvent, error) {
 	var allEvents []types.FilteredLogEvent
+
+	err := c.FilterLogEventsPages(ctx, logGroupName, startTime, endTime, func(events []types.FilteredLogEvent) error {
+		allEvents = append(allEvents, events...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return allEvents, nil
+}
+
+// FilterLogEventsPages retrieves log events page by page, calling handlePage for each page
+// Pages are not retained, so callers can process large time ranges with bounded memory
+// Pagination stops at the first error returned by the API or handlePage
+func (c *Client) FilterLogEventsPages(ctx context.Context, logGroupName string, startTime, endTime time.Time, handlePage func([]types.FilteredLogEvent) error) error {
+	return c.filterLogEventsPages(ctx, logGroupName, startTime.UnixMilli(), endTime.UnixMilli(), handlePage)
+}
+
+// filterLogEventsPages paginates FilterLogEvents over a time range given in epoch milliseconds
+func (c *Client) filterLogEventsPages(ctx context.Context, logGroupName string, startMillis, endMillis int64, handlePage func([]types.FilteredLogEvent) error) error {
 	var nextToken *string
 
-	// Filter pattern to only fetch logs containing "Started" or "Completed"
-	// This reduces data transfer and costs by filtering at CloudWatch level
-	// Using regex pattern for unstructured Rails logs
-	filterPattern := `?Started ?Completed`
+	filterPattern := c.eventFilterPattern()
 
 	for {
 		input := &cloudwatchlogs.FilterLogEventsInput{
 			LogGroupName:  &logGroupName,
-			StartTime:     int64Ptr(startTime.UnixMilli()),
-			EndTime:       int64Ptr(endTime.UnixMilli()),
+			StartTime:     int64Ptr(startMillis),
+			EndTime:       int64Ptr(endMillis),
 			NextToken:     nextToken,
 			FilterPattern: &filterPattern,
 		}
+		if c.logStreamPrefix != "" {
+			input.LogStreamNamePrefix = &c.logStreamPrefix
+		}
 
-		output, err := c.api.FilterLogEvents(ctx, input)
+		var output *cloudwatchlogs.FilterLogEventsOutput
+		err := c.withRetry(ctx, func() error {
+			var err error
+			output, err = c.api.FilterLogEvents(ctx, input)
+			return err
+		})
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		allEvents = append(allEvents, output.Events...)
+		if err := handlePage(output.Events); err != nil {
+			return err
+		}
 
 		if output.NextToken == nil {
 			break
@@ -102,7 +193,7 @@ func (c *Client) FilterLogEventsWithPagination(ctx context.Context, logGroupName
 		nextToken = output.NextToken
 	}
 
-	return allEvents, nil
+	return nil
 }
 
 // Helper function to create int64 pointer
@@ -6,10 +6,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockCloudWatchLogsAPI struct {
@@ -21,6 +23,21 @@ func (m *MockCloudWatchLogsAPI) FilterLogEvents(ctx context.Context, params *clo
 	return args.Get(0).(*cloudwatchlogs.FilterLogEventsOutput), args.Error(1)
 }
 
+func (m *MockCloudWatchLogsAPI) StartQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*cloudwatchlogs.StartQueryOutput), args.Error(1)
+}
+
+func (m *MockCloudWatchLogsAPI) GetQueryResults(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*cloudwatchlogs.GetQueryResultsOutput), args.Error(1)
+}
+
+func (m *MockCloudWatchLogsAPI) DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*cloudwatchlogs.DescribeLogGroupsOutput), args.Error(1)
+}
+
 func TestClient_FilterLogEvents(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -91,7 +108,7 @@ func TestClient_FilterLogEvents(t *testing.T) {
 				api: mockAPI,
 			}
 
-			filterPattern := `?Started ?Completed`
+			filterPattern := `?Started ?Processing ?Completed`
 			expectedInput := &cloudwatchlogs.FilterLogEventsInput{
 				LogGroupName:  &tt.logGroupName,
 				StartTime:     int64Ptr(tt.startTime.UnixMilli()),
@@ -127,7 +144,7 @@ func TestClient_FilterLogEventsWithPagination(t *testing.T) {
 	endTime := time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC)
 
 	// First page
-	filterPattern := `?Started ?Completed`
+	filterPattern := `?Started ?Processing ?Completed`
 	firstPageInput := &cloudwatchlogs.FilterLogEventsInput{
 		LogGroupName:  &logGroupName,
 		StartTime:     int64Ptr(startTime.UnixMilli()),
@@ -176,6 +193,47 @@ func TestClient_FilterLogEventsWithPagination(t *testing.T) {
 	mockAPI.AssertExpectations(t)
 }
 
+func TestClient_SetLogStreamPrefix(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	client := NewClientWithAPI(mockAPI)
+	client.SetLogStreamPrefix("web/app/")
+
+	mockAPI.On("FilterLogEvents", mock.Anything, mock.MatchedBy(func(input *cloudwatchlogs.FilterLogEventsInput) bool {
+		return input.LogStreamNamePrefix != nil && *input.LogStreamNamePrefix == "web/app/"
+	})).Return(&cloudwatchlogs.FilterLogEventsOutput{
+		Events: []types.FilteredLogEvent{
+			{
+				EventId:       stringPtr("event1"),
+				LogStreamName: stringPtr("web/app/task-1"),
+				Message:       stringPtr("Started GET \"/users/123\""),
+				Timestamp:     int64Ptr(1672531200000),
+			},
+		},
+	}, nil)
+
+	events, err := client.FilterLogEventsWithPagination(context.Background(), "test-log-group", time.Now().Add(-time.Hour), time.Now())
+
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "web/app/task-1", *events[0].LogStreamName)
+	mockAPI.AssertExpectations(t)
+}
+
+func TestClient_SetFilterPattern(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	client := NewClientWithAPI(mockAPI)
+	client.SetFilterPattern(`?status`)
+
+	mockAPI.On("FilterLogEvents", mock.Anything, mock.MatchedBy(func(input *cloudwatchlogs.FilterLogEventsInput) bool {
+		return *input.FilterPattern == `?status`
+	})).Return(&cloudwatchlogs.FilterLogEventsOutput{}, nil)
+
+	_, err := client.FilterLogEventsWithPagination(context.Background(), "test-log-group", time.Now().Add(-time.Hour), time.Now())
+
+	require.NoError(t, err)
+	mockAPI.AssertExpectations(t)
+}
+
 // Helper functions
 func stringPtr(s string) *string {
 	return &s
@@ -192,7 +250,7 @@ func TestClient_FilterLogEventsWithPagination_ErrorHandling(t *testing.T) {
 	endTime := time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC)
 
 	// Mock API error on first call
-	filterPattern := `?Started ?Completed`
+	filterPattern := `?Started ?Processing ?Completed`
 	expectedInput := &cloudwatchlogs.FilterLogEventsInput{
 		LogGroupName:  &logGroupName,
 		StartTime:     int64Ptr(startTime.UnixMilli()),
@@ -220,7 +278,7 @@ func TestClient_FilterLogEventsWithPagination_EmptyResponse(t *testing.T) {
 	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
 	endTime := time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC)
 
-	filterPattern := `?Started ?Completed`
+	filterPattern := `?Started ?Processing ?Completed`
 	expectedInput := &cloudwatchlogs.FilterLogEventsInput{
 		LogGroupName:  &logGroupName,
 		StartTime:     int64Ptr(startTime.UnixMilli()),
@@ -251,7 +309,7 @@ func TestClient_FilterLogEventsWithPagination_MultiplePages(t *testing.T) {
 	endTime := time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC)
 
 	// Setup 3 pages of results
-	filterPattern := `?Started ?Completed`
+	filterPattern := `?Started ?Processing ?Completed`
 	page1Input := &cloudwatchlogs.FilterLogEventsInput{
 		LogGroupName:  &logGroupName,
 		StartTime:     int64Ptr(startTime.UnixMilli()),
@@ -308,6 +366,71 @@ func TestClient_FilterLogEventsWithPagination_MultiplePages(t *testing.T) {
 	mockAPI.AssertExpectations(t)
 }
 
+func TestClient_FilterLogEventsPages(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	client := NewClientWithAPI(mockAPI)
+
+	logGroupName := "test-log-group"
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	endTime := time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC)
+
+	firstPage := &cloudwatchlogs.FilterLogEventsOutput{
+		Events: []types.FilteredLogEvent{
+			{EventId: stringPtr("event1"), Message: stringPtr("log1"), Timestamp: int64Ptr(1672531200000)},
+			{EventId: stringPtr("event2"), Message: stringPtr("log2"), Timestamp: int64Ptr(1672531200100)},
+		},
+		NextToken: stringPtr("token1"),
+	}
+	secondPage := &cloudwatchlogs.FilterLogEventsOutput{
+		Events: []types.FilteredLogEvent{
+			{EventId: stringPtr("event3"), Message: stringPtr("log3"), Timestamp: int64Ptr(1672531200200)},
+		},
+	}
+	mockAPI.On("FilterLogEvents", mock.Anything, mock.MatchedBy(func(input *cloudwatchlogs.FilterLogEventsInput) bool {
+		return input.NextToken == nil
+	})).Return(firstPage, nil)
+	mockAPI.On("FilterLogEvents", mock.Anything, mock.MatchedBy(func(input *cloudwatchlogs.FilterLogEventsInput) bool {
+		return input.NextToken != nil && *input.NextToken == "token1"
+	})).Return(secondPage, nil)
+
+	t.Run("handles each page in order", func(t *testing.T) {
+		var pageSizes []int
+		err := client.FilterLogEventsPages(context.Background(), logGroupName, startTime, endTime, func(events []types.FilteredLogEvent) error {
+			pageSizes = append(pageSizes, len(events))
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2, 1}, pageSizes)
+	})
+
+	t.Run("stops at handler error", func(t *testing.T) {
+		pages := 0
+		err := client.FilterLogEventsPages(context.Background(), logGroupName, startTime, endTime, func(events []types.FilteredLogEvent) error {
+			pages++
+			return errors.New("consumer stopped")
+		})
+
+		assert.Error(t, err)
+		assert.Equal(t, "consumer stopped", err.Error())
+		assert.Equal(t, 1, pages)
+	})
+}
+
+func TestNewClient_DisablesSDKRetries(t *testing.T) {
+	// Static credentials keep the test independent of the environment
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	client, err := NewClient(context.Background(), "")
+	require.NoError(t, err)
+
+	api, ok := client.api.(*cloudwatchlogs.Client)
+	require.True(t, ok)
+	assert.IsType(t, aws.NopRetryer{}, api.Options().Retryer)
+}
+
 func TestNewClientWithAPI(t *testing.T) {
 	mockAPI := new(MockCloudWatchLogsAPI)
 	client := NewClientWithAPI(mockAPI)
@@ -347,7 +470,7 @@ func TestClient_FilterLogEvents_NilPointers(t *testing.T) {
 		},
 	}
 
-	filterPattern := `?Started ?Completed`
+	filterPattern := `?Started ?Processing ?Completed`
 	expectedInput := &cloudwatchlogs.FilterLogEventsInput{
 		LogGroupName:  &logGroupName,
 		StartTime:     int64Ptr(startTime.UnixMilli()),
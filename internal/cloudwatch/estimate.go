@@ -0,0 +1,92 @@
+package cloudwatch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// filterPageBytes is the maximum size of a single FilterLogEvents response
+const filterPageBytes = 1 << 20
+
+// Estimate is a rough estimate of how much data a time range of a log group covers
+type Estimate struct {
+	StoredBytes    int64 // Bytes currently stored in the log group
+	EstimatedBytes int64 // Bytes expected within the time range
+	EstimatedPages int64 // FilterLogEvents pages expected for the time range
+}
+
+// EstimateRange estimates the data covered by [startTime, endTime] in the log group
+// The stored bytes are assumed to be spread evenly from the creation of the log group, or the start of
+// its retention period, until now. Only DescribeLogGroups is called, so no log data is scanned.
+func (c *Client) EstimateRange(ctx context.Context, logGroupName string, startTime, endTime, now time.Time) (*Estimate, error) {
+	logGroup, err := c.describeLogGroup(ctx, logGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	estimate := &Estimate{}
+	if logGroup.StoredBytes != nil {
+		estimate.StoredBytes = *logGroup.StoredBytes
+	}
+
+	storedFrom := now
+	if logGroup.CreationTime != nil {
+		storedFrom = time.UnixMilli(*logGroup.CreationTime)
+	}
+	if logGroup.RetentionInDays != nil {
+		if retentionStart := now.AddDate(0, 0, -int(*logGroup.RetentionInDays)); retentionStart.After(storedFrom) {
+			storedFrom = retentionStart
+		}
+	}
+
+	fraction := 1.0
+	if stored := now.Sub(storedFrom); stored > 0 {
+		overlapStart, overlapEnd := startTime, endTime
+		if overlapStart.Before(storedFrom) {
+			overlapStart = storedFrom
+		}
+		if overlapEnd.After(now) {
+			overlapEnd = now
+		}
+		fraction = float64(overlapEnd.Sub(overlapStart)) / float64(stored)
+		fraction = max(0, min(1, fraction))
+	}
+
+	estimate.EstimatedBytes = int64(float64(estimate.StoredBytes) * fraction)
+	estimate.EstimatedPages = (estimate.EstimatedBytes + filterPageBytes - 1) / filterPageBytes
+	return estimate, nil
+}
+
+// describeLogGroup returns the description of the log group with exactly the given name
+func (c *Client) describeLogGroup(ctx context.Context, logGroupName string) (*types.LogGroup, error) {
+	input := &cloudwatchlogs.DescribeLogGroupsInput{
+		LogGroupNamePrefix: &logGroupName,
+	}
+
+	for {
+		var output *cloudwatchlogs.DescribeLogGroupsOutput
+		err := c.withRetry(ctx, func() error {
+			var err error
+			output, err = c.api.DescribeLogGroups(ctx, input)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe log group: %w", err)
+		}
+
+		for i, logGroup := range output.LogGroups {
+			if logGroup.LogGroupName != nil && *logGroup.LogGroupName == logGroupName {
+				return &output.LogGroups[i], nil
+			}
+		}
+
+		if output.NextToken == nil || *output.NextToken == "" {
+			return nil, fmt.Errorf("log group %s not found", logGroupName)
+		}
+		input.NextToken = output.NextToken
+	}
+}
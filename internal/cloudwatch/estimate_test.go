@@ -0,0 +1,149 @@
+package cloudwatch
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_EstimateRange(t *testing.T) {
+	now := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		logGroup      types.LogGroup
+		start         time.Time
+		end           time.Time
+		expectedBytes int64
+		expectedPages int64
+	}{
+		{
+			name: "fraction of the log group lifetime",
+			logGroup: types.LogGroup{
+				LogGroupName: aws.String("test-log-group"),
+				StoredBytes:  aws.Int64(30 * filterPageBytes),
+				CreationTime: aws.Int64(now.AddDate(0, 0, -30).UnixMilli()),
+			},
+			start:         now.AddDate(0, 0, -3),
+			end:           now,
+			expectedBytes: 3 * filterPageBytes,
+			expectedPages: 3,
+		},
+		{
+			name: "retention period limits the stored range",
+			logGroup: types.LogGroup{
+				LogGroupName:    aws.String("test-log-group"),
+				StoredBytes:     aws.Int64(10 * filterPageBytes),
+				CreationTime:    aws.Int64(now.AddDate(-1, 0, 0).UnixMilli()),
+				RetentionInDays: aws.Int32(10),
+			},
+			start:         now.AddDate(0, 0, -1),
+			end:           now,
+			expectedBytes: filterPageBytes,
+			expectedPages: 1,
+		},
+		{
+			name: "range before the stored data",
+			logGroup: types.LogGroup{
+				LogGroupName: aws.String("test-log-group"),
+				StoredBytes:  aws.Int64(10 * filterPageBytes),
+				CreationTime: aws.Int64(now.AddDate(0, 0, -1).UnixMilli()),
+			},
+			start:         now.AddDate(0, 0, -5),
+			end:           now.AddDate(0, 0, -3),
+			expectedBytes: 0,
+			expectedPages: 0,
+		},
+		{
+			name: "partial page is rounded up",
+			logGroup: types.LogGroup{
+				LogGroupName: aws.String("test-log-group"),
+				StoredBytes:  aws.Int64(1000),
+				CreationTime: aws.Int64(now.AddDate(0, 0, -2).UnixMilli()),
+			},
+			start:         now.AddDate(0, 0, -1),
+			end:           now,
+			expectedBytes: 500,
+			expectedPages: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAPI := new(MockCloudWatchLogsAPI)
+			mockAPI.On("DescribeLogGroups", mock.Anything, mock.Anything).Return(&cloudwatchlogs.DescribeLogGroupsOutput{
+				LogGroups: []types.LogGroup{tt.logGroup},
+			}, nil)
+			client := NewClientWithAPI(mockAPI)
+
+			estimate, err := client.EstimateRange(context.Background(), "test-log-group", tt.start, tt.end, now)
+
+			require.NoError(t, err)
+			assert.Equal(t, *tt.logGroup.StoredBytes, estimate.StoredBytes)
+			assert.Equal(t, tt.expectedBytes, estimate.EstimatedBytes)
+			assert.Equal(t, tt.expectedPages, estimate.EstimatedPages)
+		})
+	}
+}
+
+func TestClient_EstimateRange_FindsExactLogGroup(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	// The prefix also matches other log groups, which may be on an earlier page
+	mockAPI.On("DescribeLogGroups", mock.Anything, mock.MatchedBy(func(input *cloudwatchlogs.DescribeLogGroupsInput) bool {
+		return *input.LogGroupNamePrefix == "app" && input.NextToken == nil
+	})).Return(&cloudwatchlogs.DescribeLogGroupsOutput{
+		LogGroups: []types.LogGroup{{LogGroupName: aws.String("app-staging"), StoredBytes: aws.Int64(1)}},
+		NextToken: aws.String("token1"),
+	}, nil).Once()
+	mockAPI.On("DescribeLogGroups", mock.Anything, mock.MatchedBy(func(input *cloudwatchlogs.DescribeLogGroupsInput) bool {
+		return input.NextToken != nil && *input.NextToken == "token1"
+	})).Return(&cloudwatchlogs.DescribeLogGroupsOutput{
+		LogGroups: []types.LogGroup{{LogGroupName: aws.String("app"), StoredBytes: aws.Int64(42)}},
+	}, nil).Once()
+	client := NewClientWithAPI(mockAPI)
+
+	now := time.Now()
+	estimate, err := client.EstimateRange(context.Background(), "app", now.Add(-time.Hour), now, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), estimate.StoredBytes)
+	mockAPI.AssertExpectations(t)
+}
+
+func TestClient_EstimateRange_Errors(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	mockAPI.On("DescribeLogGroups", mock.Anything, mock.Anything).Return((*cloudwatchlogs.DescribeLogGroupsOutput)(nil), errors.New("access denied")).Once()
+	mockAPI.On("DescribeLogGroups", mock.Anything, mock.Anything).Return(&cloudwatchlogs.DescribeLogGroupsOutput{}, nil).Once()
+	client := NewClientWithAPI(mockAPI)
+
+	now := time.Now()
+	_, err := client.EstimateRange(context.Background(), "test-log-group", now.Add(-time.Hour), now, now)
+	assert.ErrorContains(t, err, "failed to describe log group: access denied")
+
+	_, err = client.EstimateRange(context.Background(), "test-log-group", now.Add(-time.Hour), now, now)
+	assert.ErrorContains(t, err, "log group test-log-group not found")
+}
+
+func TestClient_EstimateRange_RetriesThrottling(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	mockAPI.On("DescribeLogGroups", mock.Anything, mock.Anything).Return((*cloudwatchlogs.DescribeLogGroupsOutput)(nil), throttlingError).Once()
+	mockAPI.On("DescribeLogGroups", mock.Anything, mock.Anything).Return(&cloudwatchlogs.DescribeLogGroupsOutput{
+		LogGroups: []types.LogGroup{{LogGroupName: aws.String("test-log-group"), StoredBytes: aws.Int64(42)}},
+	}, nil).Once()
+	client := newRetryTestClient(mockAPI)
+
+	now := time.Now()
+	estimate, err := client.EstimateRange(context.Background(), "test-log-group", now.Add(-time.Hour), now, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), estimate.StoredBytes)
+	mockAPI.AssertExpectations(t)
+}
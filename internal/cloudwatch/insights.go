@@ -0,0 +1,160 @@
+package cloudwatch
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// InsightsRowLimit is the maximum number of rows a single Logs Insights query can return
+const InsightsRowLimit = 10000
+
+// DefaultPollInterval is how often a running Logs Insights query is polled for results
+const DefaultPollInterval = time.Second
+
+// InsightsMaxLogGroups is the maximum number of log groups a single Logs Insights query can search
+const InsightsMaxLogGroups = 50
+
+// InsightsRow is a single Logs Insights result row keyed by field name
+type InsightsRow map[string]string
+
+// RunInsightsQuery runs a Logs Insights query over [startTime, endTime] in the log groups and waits for its results
+// Insights works with second precision and treats both bounds as inclusive
+func (c *Client) RunInsightsQuery(ctx context.Context, logGroupNames []string, startTime, endTime time.Time, query string) ([]InsightsRow, error) {
+	if err := validateInsightsLogGroups(logGroupNames); err != nil {
+		return nil, err
+	}
+	return c.runInsightsQuery(ctx, logGroupNames, startTime.Unix(), endTime.Unix(), c.withLogStreamFilter(query))
+}
+
+// InsightsQueryPages runs a Logs Insights query over the time range, calling handleRows for each part
+// Whenever a part reaches InsightsRowLimit, it is split in half and queried again so no rows are lost.
+// Parts are handled in chronological order.
+func (c *Client) InsightsQueryPages(ctx context.Context, logGroupNames []string, startTime, endTime time.Time, query string, handleRows func([]InsightsRow) error) error {
+	if err := validateInsightsLogGroups(logGroupNames); err != nil {
+		return err
+	}
+	return c.insightsQueryRange(ctx, logGroupNames, startTime.Unix(), endTime.Unix(), c.withLogStreamFilter(query), handleRows)
+}
+
+// withLogStreamFilter prepends a filter on the log stream prefix to query when one is set
+func (c *Client) withLogStreamFilter(query string) string {
+	if c.logStreamPrefix == "" {
+		return query
+	}
+	pattern := strings.ReplaceAll(regexp.QuoteMeta(c.logStreamPrefix), "/", `\/`)
+	return fmt.Sprintf("filter @logStream like /^%s/\n| %s", pattern, query)
+}
+
+// validateInsightsLogGroups checks that a single query can search all log groups
+func validateInsightsLogGroups(logGroupNames []string) error {
+	if len(logGroupNames) == 0 {
+		return fmt.Errorf("no log groups to query")
+	}
+	if len(logGroupNames) > InsightsMaxLogGroups {
+		return fmt.Errorf("a Logs Insights query can search at most %d log groups: %d", InsightsMaxLogGroups, len(logGroupNames))
+	}
+	return nil
+}
+
+// insightsQueryRange queries [start, end] in epoch seconds, splitting the range while results are truncated
+func (c *Client) insightsQueryRange(ctx context.Context, logGroupNames []string, start, end int64, query string, handleRows func([]InsightsRow) error) error {
+	rows, err := c.runInsightsQuery(ctx, logGroupNames, start, end, query)
+	if err != nil {
+		return err
+	}
+
+	if len(rows) < InsightsRowLimit {
+		return handleRows(rows)
+	}
+
+	if end <= start {
+		// A single second cannot be split any further
+		slog.Warn("Logs Insights results truncated at the row limit",
+			"start", time.Unix(start, 0).UTC(),
+			"limit", InsightsRowLimit,
+		)
+		return handleRows(rows)
+	}
+
+	middle := start + (end-start)/2
+	if err := c.insightsQueryRange(ctx, logGroupNames, start, middle, query, handleRows); err != nil {
+		return err
+	}
+	return c.insightsQueryRange(ctx, logGroupNames, middle+1, end, query, handleRows)
+}
+
+// runInsightsQuery starts a query over [start, end] in epoch seconds and polls until it finishes
+func (c *Client) runInsightsQuery(ctx context.Context, logGroupNames []string, start, end int64, query string) ([]InsightsRow, error) {
+	input := &cloudwatchlogs.StartQueryInput{
+		LogGroupNames: logGroupNames,
+		StartTime:     int64Ptr(start),
+		EndTime:       int64Ptr(end),
+		QueryString:   &query,
+		Limit:         aws.Int32(InsightsRowLimit),
+	}
+
+	var started *cloudwatchlogs.StartQueryOutput
+	err := c.withRetry(ctx, func() error {
+		var err error
+		started, err = c.api.StartQuery(ctx, input)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start Logs Insights query: %w", err)
+	}
+	if started.QueryId == nil {
+		return nil, fmt.Errorf("failed to start Logs Insights query: no query ID returned")
+	}
+
+	for {
+		var output *cloudwatchlogs.GetQueryResultsOutput
+		err := c.withRetry(ctx, func() error {
+			var err error
+			output, err = c.api.GetQueryResults(ctx, &cloudwatchlogs.GetQueryResultsInput{
+				QueryId: started.QueryId,
+			})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Logs Insights query results: %w", err)
+		}
+
+		switch output.Status {
+		case types.QueryStatusComplete:
+			return toInsightsRows(output.Results), nil
+		case types.QueryStatusFailed, types.QueryStatusCancelled, types.QueryStatusTimeout, types.QueryStatusUnknown:
+			return nil, fmt.Errorf("insights query %s ended with status %s", *started.QueryId, output.Status)
+		}
+
+		timer := time.NewTimer(c.pollInterval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// toInsightsRows converts raw result fields to rows keyed by field name
+func toInsightsRows(results [][]types.ResultField) []InsightsRow {
+	rows := make([]InsightsRow, 0, len(results))
+	for _, fields := range results {
+		row := make(InsightsRow, len(fields))
+		for _, field := range fields {
+			if field.Field != nil && field.Value != nil {
+				row[*field.Field] = *field.Value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
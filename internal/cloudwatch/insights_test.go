@@ -0,0 +1,231 @@
+package cloudwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newInsightsTestClient(api CloudWatchLogsAPI) *Client {
+	client := NewClientWithAPI(api)
+	client.pollInterval = time.Millisecond
+	return client
+}
+
+func resultRow(fields map[string]string) []types.ResultField {
+	row := make([]types.ResultField, 0, len(fields))
+	for name, value := range fields {
+		row = append(row, types.ResultField{Field: stringPtr(name), Value: stringPtr(value)})
+	}
+	return row
+}
+
+func TestClient_RunInsightsQuery(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	client := newInsightsTestClient(mockAPI)
+
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	endTime := startTime.Add(time.Hour)
+
+	mockAPI.On("StartQuery", mock.Anything, mock.MatchedBy(func(input *cloudwatchlogs.StartQueryInput) bool {
+		return assert.ObjectsAreEqual([]string{"test-log-group"}, input.LogGroupNames) &&
+			*input.StartTime == startTime.Unix() &&
+			*input.EndTime == endTime.Unix() &&
+			*input.QueryString == "fields @timestamp" &&
+			*input.Limit == InsightsRowLimit
+	})).Return(&cloudwatchlogs.StartQueryOutput{QueryId: stringPtr("query1")}, nil).Once()
+	// The query is still running on the first poll
+	mockAPI.On("GetQueryResults", mock.Anything, mock.Anything).Return(&cloudwatchlogs.GetQueryResultsOutput{
+		Status: types.QueryStatusRunning,
+	}, nil).Once()
+	mockAPI.On("GetQueryResults", mock.Anything, mock.Anything).Return(&cloudwatchlogs.GetQueryResultsOutput{
+		Status: types.QueryStatusComplete,
+		Results: [][]types.ResultField{
+			resultRow(map[string]string{"@timestamp": "2023-01-01 00:00:01.000", "path": "/users"}),
+			resultRow(map[string]string{"@timestamp": "2023-01-01 00:00:02.000", "status": "200"}),
+		},
+	}, nil).Once()
+
+	rows, err := client.RunInsightsQuery(context.Background(), []string{"test-log-group"}, startTime, endTime, "fields @timestamp")
+
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "/users", rows[0]["path"])
+	assert.Equal(t, "200", rows[1]["status"])
+	mockAPI.AssertExpectations(t)
+}
+
+func TestClient_RunInsightsQuery_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(*MockCloudWatchLogsAPI)
+		errorMsg string
+	}{
+		{
+			name: "start query fails",
+			setup: func(m *MockCloudWatchLogsAPI) {
+				m.On("StartQuery", mock.Anything, mock.Anything).Return((*cloudwatchlogs.StartQueryOutput)(nil), errors.New("access denied"))
+			},
+			errorMsg: "failed to start Logs Insights query",
+		},
+		{
+			name: "get query results fails",
+			setup: func(m *MockCloudWatchLogsAPI) {
+				m.On("StartQuery", mock.Anything, mock.Anything).Return(&cloudwatchlogs.StartQueryOutput{QueryId: stringPtr("query1")}, nil)
+				m.On("GetQueryResults", mock.Anything, mock.Anything).Return((*cloudwatchlogs.GetQueryResultsOutput)(nil), errors.New("access denied"))
+			},
+			errorMsg: "failed to get Logs Insights query results",
+		},
+		{
+			name: "query fails",
+			setup: func(m *MockCloudWatchLogsAPI) {
+				m.On("StartQuery", mock.Anything, mock.Anything).Return(&cloudwatchlogs.StartQueryOutput{QueryId: stringPtr("query1")}, nil)
+				m.On("GetQueryResults", mock.Anything, mock.Anything).Return(&cloudwatchlogs.GetQueryResultsOutput{
+					Status: types.QueryStatusFailed,
+				}, nil)
+			},
+			errorMsg: "insights query query1 ended with status Failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAPI := new(MockCloudWatchLogsAPI)
+			tt.setup(mockAPI)
+			client := newInsightsTestClient(mockAPI)
+
+			rows, err := client.RunInsightsQuery(context.Background(), []string{"test-log-group"}, time.Now().Add(-time.Hour), time.Now(), "fields @timestamp")
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+			assert.Nil(t, rows)
+		})
+	}
+}
+
+func TestClient_RunInsightsQuery_ContextCancel(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	client := NewClientWithAPI(mockAPI)
+
+	mockAPI.On("StartQuery", mock.Anything, mock.Anything).Return(&cloudwatchlogs.StartQueryOutput{QueryId: stringPtr("query1")}, nil)
+	mockAPI.On("GetQueryResults", mock.Anything, mock.Anything).Return(&cloudwatchlogs.GetQueryResultsOutput{
+		Status: types.QueryStatusRunning,
+	}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := client.RunInsightsQuery(ctx, []string{"test-log-group"}, time.Now().Add(-time.Hour), time.Now(), "fields @timestamp")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// fakeInsightsAPI answers queries with one row per second of the queried range
+type fakeInsightsAPI struct {
+	fakeCloudWatchLogsAPI
+	mu      sync.Mutex
+	queries map[string][2]int64
+	ranges  [][2]int64
+}
+
+func (f *fakeInsightsAPI) StartQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	queryID := fmt.Sprintf("query%d", len(f.ranges))
+	queryRange := [2]int64{*params.StartTime, *params.EndTime}
+	f.queries[queryID] = queryRange
+	f.ranges = append(f.ranges, queryRange)
+	return &cloudwatchlogs.StartQueryOutput{QueryId: &queryID}, nil
+}
+
+func (f *fakeInsightsAPI) GetQueryResults(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+	f.mu.Lock()
+	queryRange := f.queries[*params.QueryId]
+	f.mu.Unlock()
+
+	var results [][]types.ResultField
+	for second := queryRange[0]; second <= queryRange[1] && len(results) < InsightsRowLimit; second++ {
+		results = append(results, resultRow(map[string]string{"second": fmt.Sprint(second)}))
+	}
+	return &cloudwatchlogs.GetQueryResultsOutput{
+		Status:  types.QueryStatusComplete,
+		Results: results,
+	}, nil
+}
+
+func TestClient_InsightsQueryPages_SplitsAtRowLimit(t *testing.T) {
+	api := &fakeInsightsAPI{queries: make(map[string][2]int64)}
+	client := newInsightsTestClient(api)
+
+	// 15000 seconds produce more rows than a single query can return
+	startTime := time.Unix(0, 0)
+	endTime := time.Unix(14999, 0)
+
+	var seconds []string
+	err := client.InsightsQueryPages(context.Background(), []string{"test-log-group"}, startTime, endTime, "fields @timestamp", func(rows []InsightsRow) error {
+		for _, row := range rows {
+			seconds = append(seconds, row["second"])
+		}
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Len(t, seconds, 15000)
+	assert.Equal(t, "0", seconds[0])
+	assert.Equal(t, "14999", seconds[len(seconds)-1])
+	assert.Equal(t, [][2]int64{{0, 14999}, {0, 7499}, {7500, 14999}}, api.ranges)
+}
+
+func TestClient_InsightsQueryPages_HandlerError(t *testing.T) {
+	api := &fakeInsightsAPI{queries: make(map[string][2]int64)}
+	client := newInsightsTestClient(api)
+
+	handlerErr := errors.New("handler failed")
+	err := client.InsightsQueryPages(context.Background(), []string{"test-log-group"}, time.Unix(0, 0), time.Unix(10, 0), "fields @timestamp", func(rows []InsightsRow) error {
+		return handlerErr
+	})
+
+	assert.ErrorIs(t, err, handlerErr)
+}
+
+func TestClient_RunInsightsQuery_LogGroupLimits(t *testing.T) {
+	client := newInsightsTestClient(new(MockCloudWatchLogsAPI))
+
+	_, err := client.RunInsightsQuery(context.Background(), nil, time.Now().Add(-time.Hour), time.Now(), "fields @timestamp")
+	assert.ErrorContains(t, err, "no log groups to query")
+
+	tooMany := make([]string, InsightsMaxLogGroups+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("log-group-%d", i)
+	}
+	_, err = client.RunInsightsQuery(context.Background(), tooMany, time.Now().Add(-time.Hour), time.Now(), "fields @timestamp")
+	assert.ErrorContains(t, err, "a Logs Insights query can search at most 50 log groups: 51")
+}
+
+func TestClient_InsightsLogStreamFilter(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	client := newInsightsTestClient(mockAPI)
+	client.SetLogStreamPrefix("web/app.1")
+
+	mockAPI.On("StartQuery", mock.Anything, mock.MatchedBy(func(input *cloudwatchlogs.StartQueryInput) bool {
+		return *input.QueryString == "filter @logStream like /^web\\/app\\.1/\n| fields @timestamp"
+	})).Return(&cloudwatchlogs.StartQueryOutput{QueryId: stringPtr("query1")}, nil)
+	mockAPI.On("GetQueryResults", mock.Anything, mock.Anything).Return(&cloudwatchlogs.GetQueryResultsOutput{
+		Status: types.QueryStatusComplete,
+	}, nil)
+
+	_, err := client.RunInsightsQuery(context.Background(), []string{"test-log-group"}, time.Now().Add(-time.Hour), time.Now(), "fields @timestamp")
+
+	require.NoError(t, err)
+	mockAPI.AssertExpectations(t)
+}
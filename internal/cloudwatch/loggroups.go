@@ -0,0 +1,73 @@
+package cloudwatch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+)
+
+// ResolveLogGroups returns the given log group names plus every log group starting with one of the prefixes
+// Names are deduplicated and sorted. A prefix that matches no log group is an error
+func (c *Client) ResolveLogGroups(ctx context.Context, names, prefixes []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var resolved []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			resolved = append(resolved, name)
+		}
+	}
+
+	for _, name := range names {
+		add(name)
+	}
+
+	for _, prefix := range prefixes {
+		matched, err := c.listLogGroups(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		if len(matched) == 0 {
+			return nil, fmt.Errorf("no log groups match prefix %q", prefix)
+		}
+		for _, name := range matched {
+			add(name)
+		}
+	}
+
+	sort.Strings(resolved)
+	return resolved, nil
+}
+
+// listLogGroups returns the names of all log groups starting with prefix
+func (c *Client) listLogGroups(ctx context.Context, prefix string) ([]string, error) {
+	input := &cloudwatchlogs.DescribeLogGroupsInput{
+		LogGroupNamePrefix: &prefix,
+	}
+
+	var names []string
+	for {
+		var output *cloudwatchlogs.DescribeLogGroupsOutput
+		err := c.withRetry(ctx, func() error {
+			var err error
+			output, err = c.api.DescribeLogGroups(ctx, input)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list log groups with prefix %q: %w", prefix, err)
+		}
+
+		for _, logGroup := range output.LogGroups {
+			if logGroup.LogGroupName != nil {
+				names = append(names, *logGroup.LogGroupName)
+			}
+		}
+
+		if output.NextToken == nil || *output.NextToken == "" {
+			return names, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
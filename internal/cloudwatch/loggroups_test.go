@@ -0,0 +1,60 @@
+package cloudwatch
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ResolveLogGroups(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	mockAPI.On("DescribeLogGroups", mock.Anything, mock.MatchedBy(func(input *cloudwatchlogs.DescribeLogGroupsInput) bool {
+		return *input.LogGroupNamePrefix == "/ecs/app-" && input.NextToken == nil
+	})).Return(&cloudwatchlogs.DescribeLogGroupsOutput{
+		LogGroups: []types.LogGroup{{LogGroupName: aws.String("/ecs/app-web")}},
+		NextToken: aws.String("token1"),
+	}, nil).Once()
+	mockAPI.On("DescribeLogGroups", mock.Anything, mock.MatchedBy(func(input *cloudwatchlogs.DescribeLogGroupsInput) bool {
+		return input.NextToken != nil && *input.NextToken == "token1"
+	})).Return(&cloudwatchlogs.DescribeLogGroupsOutput{
+		LogGroups: []types.LogGroup{{LogGroupName: aws.String("/ecs/app-api")}, {LogGroupName: aws.String("/ecs/app-worker")}},
+	}, nil).Once()
+	client := NewClientWithAPI(mockAPI)
+
+	names, err := client.ResolveLogGroups(context.Background(), []string{"/ecs/app-worker", "/ecs/admin"}, []string{"/ecs/app-"})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/ecs/admin", "/ecs/app-api", "/ecs/app-web", "/ecs/app-worker"}, names)
+	mockAPI.AssertExpectations(t)
+}
+
+func TestClient_ResolveLogGroups_NamesOnly(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	client := NewClientWithAPI(mockAPI)
+
+	names, err := client.ResolveLogGroups(context.Background(), []string{"b", "a", "b"}, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, names)
+	mockAPI.AssertNotCalled(t, "DescribeLogGroups", mock.Anything, mock.Anything)
+}
+
+func TestClient_ResolveLogGroups_Errors(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	mockAPI.On("DescribeLogGroups", mock.Anything, mock.Anything).Return((*cloudwatchlogs.DescribeLogGroupsOutput)(nil), errors.New("access denied")).Once()
+	mockAPI.On("DescribeLogGroups", mock.Anything, mock.Anything).Return(&cloudwatchlogs.DescribeLogGroupsOutput{}, nil).Once()
+	client := NewClientWithAPI(mockAPI)
+
+	_, err := client.ResolveLogGroups(context.Background(), nil, []string{"app"})
+	assert.ErrorContains(t, err, `failed to list log groups with prefix "app": access denied`)
+
+	_, err = client.ResolveLogGroups(context.Background(), nil, []string{"app"})
+	assert.ErrorContains(t, err, `no log groups match prefix "app"`)
+}
@@ -0,0 +1,158 @@
+package cloudwatch
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// Buffer sizes of the timestamp merge across log groups
+const (
+	mergeBufferPages = 2    // Pages a log group is fetched ahead of the merge
+	mergeBatchSize   = 1000 // Merged events passed to the handler at once
+	mergeMaxRequests = 16   // Page requests in flight at once across all log groups and time slices
+)
+
+// newRequestSlots returns the semaphore that bounds the page requests of a fetch to mergeMaxRequests
+func newRequestSlots() chan struct{} {
+	return make(chan struct{}, mergeMaxRequests)
+}
+
+// groupPage is a page of events fetched from a single log group, or the error that stopped fetching
+type groupPage struct {
+	events []types.FilteredLogEvent
+	err    error
+}
+
+// FilterLogEventsMerged retrieves log events from every log group, calling handlePage with pages of all groups
+// merged in timestamp order, so the handler sees a single event stream whatever the number of groups
+// The log groups are paginated concurrently and only a few pages per group are held in memory
+func (c *Client) FilterLogEventsMerged(ctx context.Context, logGroupNames []string, startTime, endTime time.Time, handlePage func([]LogGroupEvent) error) error {
+	return c.mergeLogGroups(ctx, newRequestSlots(), logGroupNames, startTime.UnixMilli(), endTime.UnixMilli(), handlePage)
+}
+
+// mergeLogGroups fetches the time range from every log group concurrently and merges the events in timestamp order
+// FilterLogEvents returns the events of a log group sorted by timestamp, so only the head page of every group is
+// merged at a time and at most mergeBufferPages further pages per group are held in memory. Events with the same
+// timestamp keep the order of logGroupNames. handlePage is called with batches of up to mergeBatchSize events.
+// The page requests of all log groups share requestSlots, which bounds the requests in flight however many log
+// groups and concurrent merges there are. A slot is released while its page waits for the merge, since the merge
+// may first need a page of a log group that has no slot yet.
+func (c *Client) mergeLogGroups(ctx context.Context, requestSlots chan struct{}, logGroupNames []string, startMillis, endMillis int64, handlePage func([]LogGroupEvent) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pages := make([]chan groupPage, len(logGroupNames))
+	for i, logGroupName := range logGroupNames {
+		pages[i] = make(chan groupPage, mergeBufferPages)
+		go c.fetchGroupPages(ctx, requestSlots, pages[i], logGroupName, startMillis, endMillis)
+	}
+
+	heads := make([][]types.FilteredLogEvent, len(logGroupNames))
+	exhausted := make([]bool, len(logGroupNames))
+
+	// refill waits for the next non-empty page of a log group once its head page has been merged
+	refill := func(i int) error {
+		for len(heads[i]) == 0 && !exhausted[i] {
+			select {
+			case page, ok := <-pages[i]:
+				if !ok {
+					exhausted[i] = true
+					return nil
+				}
+				if page.err != nil {
+					return page.err
+				}
+				heads[i] = page.events
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	}
+
+	batch := make([]LogGroupEvent, 0, mergeBatchSize)
+	for {
+		next := -1
+		for i := range heads {
+			if err := refill(i); err != nil {
+				return err
+			}
+			if len(heads[i]) > 0 && (next < 0 || timestampOf(heads[i][0]) < timestampOf(heads[next][0])) {
+				next = i
+			}
+		}
+		if next < 0 {
+			break
+		}
+
+		batch = append(batch, LogGroupEvent{LogGroupName: logGroupNames[next], FilteredLogEvent: heads[next][0]})
+		heads[next] = heads[next][1:]
+
+		// The handler may keep the batch, so a new one is started after every call
+		if len(batch) == mergeBatchSize {
+			if err := handlePage(batch); err != nil {
+				return err
+			}
+			batch = make([]LogGroupEvent, 0, mergeBatchSize)
+		}
+	}
+
+	if len(batch) > 0 {
+		return handlePage(batch)
+	}
+	return nil
+}
+
+// fetchGroupPages paginates a log group into pages, closing it when done
+// A slot of requestSlots is held only while a page is requested, not while the page waits for the merge
+func (c *Client) fetchGroupPages(ctx context.Context, requestSlots chan struct{}, pages chan<- groupPage, logGroupName string, startMillis, endMillis int64) {
+	defer close(pages)
+
+	held := false
+	acquire := func() error {
+		select {
+		case requestSlots <- struct{}{}:
+			held = true
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	release := func() {
+		if held {
+			<-requestSlots
+			held = false
+		}
+	}
+
+	err := acquire()
+	if err == nil {
+		err = c.filterLogEventsPages(ctx, logGroupName, startMillis, endMillis, func(page []types.FilteredLogEvent) error {
+			release()
+			select {
+			case pages <- groupPage{events: page}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return acquire()
+		})
+	}
+	release()
+
+	if err != nil {
+		select {
+		case pages <- groupPage{err: err}:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// timestampOf returns the event timestamp, treating a missing timestamp as zero
+func timestampOf(event types.FilteredLogEvent) int64 {
+	if event.Timestamp == nil {
+		return 0
+	}
+	return *event.Timestamp
+}
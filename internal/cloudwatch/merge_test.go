@@ -0,0 +1,158 @@
+package cloudwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGroupEvents returns count events starting at start, one every step, with IDs prefixed by the log group name
+func newGroupEvents(logGroupName string, start time.Time, count int, step time.Duration) []types.FilteredLogEvent {
+	events := make([]types.FilteredLogEvent, 0, count)
+	for i := range count {
+		events = append(events, types.FilteredLogEvent{
+			EventId:   stringPtr(fmt.Sprintf("%s-%d", logGroupName, i)),
+			Message:   stringPtr(fmt.Sprintf("log%d", i)),
+			Timestamp: int64Ptr(start.Add(time.Duration(i) * step).UnixMilli()),
+		})
+	}
+	return events
+}
+
+func TestClient_MergeLogGroups(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	t.Run("interleaves log groups by timestamp", func(t *testing.T) {
+		fake := &fakeCloudWatchLogsAPI{
+			groupEvents: map[string][]types.FilteredLogEvent{
+				"app-web": newGroupEvents("app-web", start, 3, 10*time.Minute),
+				"app-api": newGroupEvents("app-api", start.Add(5*time.Minute), 3, 10*time.Minute),
+				"app-job": newGroupEvents("app-job", start, 2, 10*time.Minute),
+			},
+			pageSize: 1,
+		}
+		client := NewClientWithAPI(fake)
+
+		var ids []string
+		err := client.mergeLogGroups(context.Background(), newRequestSlots(), []string{"app-web", "app-api", "app-job"}, start.UnixMilli(), end.UnixMilli(),
+			func(events []LogGroupEvent) error {
+				for _, event := range events {
+					assert.Contains(t, *event.EventId, event.LogGroupName)
+					ids = append(ids, *event.EventId)
+				}
+				return nil
+			})
+		require.NoError(t, err)
+
+		// Events with the same timestamp keep the order of the log group names
+		assert.Equal(t, []string{
+			"app-web-0", "app-job-0", "app-api-0",
+			"app-web-1", "app-job-1", "app-api-1",
+			"app-web-2", "app-api-2",
+		}, ids)
+	})
+
+	t.Run("passes batches of bounded size", func(t *testing.T) {
+		fake := &fakeCloudWatchLogsAPI{
+			groupEvents: map[string][]types.FilteredLogEvent{
+				"app-web": newGroupEvents("app-web", start, 1500, time.Second),
+				"app-api": newGroupEvents("app-api", start, 600, time.Second),
+			},
+			pageSize: 100,
+		}
+		client := NewClientWithAPI(fake)
+
+		var sizes []int
+		var previous int64
+		err := client.mergeLogGroups(context.Background(), newRequestSlots(), []string{"app-web", "app-api"}, start.UnixMilli(), end.UnixMilli(),
+			func(events []LogGroupEvent) error {
+				sizes = append(sizes, len(events))
+				for _, event := range events {
+					assert.LessOrEqual(t, previous, *event.Timestamp)
+					previous = *event.Timestamp
+				}
+				return nil
+			})
+		require.NoError(t, err)
+		assert.Equal(t, []int{mergeBatchSize, mergeBatchSize, 100}, sizes)
+	})
+
+	t.Run("fetches a bounded number of pages ahead", func(t *testing.T) {
+		fake := &fakeCloudWatchLogsAPI{
+			groupEvents: map[string][]types.FilteredLogEvent{
+				"app-web": newGroupEvents("app-web", start, 3000, time.Second),
+			},
+			pageSize: 10,
+		}
+		client := NewClientWithAPI(fake)
+
+		stop := errors.New("consumer stopped")
+		err := client.mergeLogGroups(context.Background(), newRequestSlots(), []string{"app-web"}, start.UnixMilli(), end.UnixMilli(),
+			func(events []LogGroupEvent) error {
+				// Give the fetcher time to run ahead while the first batch is being handled
+				time.Sleep(50 * time.Millisecond)
+				fake.mu.Lock()
+				defer fake.mu.Unlock()
+				// The pages of the first batch, the buffered pages and the one waiting to be buffered
+				assert.LessOrEqual(t, fake.calls, mergeBatchSize/10+mergeBufferPages+2)
+				return stop
+			})
+		assert.ErrorIs(t, err, stop)
+	})
+
+	t.Run("log group error", func(t *testing.T) {
+		fake := &fakeCloudWatchLogsAPI{
+			events:   newGroupEvents("app-web", start, 10, time.Minute),
+			pageSize: 2,
+			failAt:   start.UnixMilli(),
+		}
+		client := NewClientWithAPI(fake)
+
+		handled := 0
+		err := client.mergeLogGroups(context.Background(), newRequestSlots(), []string{"app-web", "app-api"}, start.UnixMilli(), end.UnixMilli(),
+			func(events []LogGroupEvent) error {
+				handled++
+				return nil
+			})
+		assert.EqualError(t, err, "slice failed")
+		assert.Zero(t, handled)
+	})
+}
+
+func TestClient_FilterLogEventsMerged(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(3 * time.Hour)
+
+	fake := &fakeCloudWatchLogsAPI{
+		groupEvents: map[string][]types.FilteredLogEvent{
+			"app-web": newGroupEvents("app-web", start, 30, 6*time.Minute),
+			"app-api": newGroupEvents("app-api", start.Add(3*time.Minute), 30, 6*time.Minute),
+		},
+		pageSize: 7,
+	}
+	client := NewClientWithAPI(fake)
+
+	var timestamps []int64
+	err := client.FilterLogEventsMerged(context.Background(), []string{"app-web", "app-api"}, start, end,
+		func(events []LogGroupEvent) error {
+			for _, event := range events {
+				timestamps = append(timestamps, *event.Timestamp)
+			}
+			return nil
+		})
+	require.NoError(t, err)
+
+	// The whole range is requested at once from each group, and the groups are interleaved
+	require.Len(t, timestamps, 60)
+	for i := 1; i < len(timestamps); i++ {
+		assert.Less(t, timestamps[i-1], timestamps[i])
+	}
+	assert.Equal(t, []int64{end.UnixMilli(), end.UnixMilli()}, fake.requestedEnds)
+}
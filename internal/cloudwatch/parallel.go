@@ -0,0 +1,180 @@
+package cloudwatch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// Default settings for parallel fetching
+const (
+	DefaultWorkers       = 4
+	DefaultSliceDuration = time.Hour
+	MaxSlices            = 100000 // Time slices a range may be split into, e.g. over 11 years of 1h slices
+)
+
+// ParallelOptions controls how a time range is split and fetched concurrently
+type ParallelOptions struct {
+	Workers       int           // Maximum number of time slices fetched at the same time
+	SliceDuration time.Duration // Length of each time slice
+}
+
+// DefaultParallelOptions returns the default parallel fetching options
+func DefaultParallelOptions() ParallelOptions {
+	return ParallelOptions{
+		Workers:       DefaultWorkers,
+		SliceDuration: DefaultSliceDuration,
+	}
+}
+
+// Validate checks that the options can be used for fetching
+func (o ParallelOptions) Validate() error {
+	if o.Workers < 1 {
+		return fmt.Errorf("workers must be at least 1: %d", o.Workers)
+	}
+	if o.SliceDuration <= 0 {
+		return fmt.Errorf("slice duration must be positive: %s", o.SliceDuration)
+	}
+	return nil
+}
+
+// timeSlice is a closed time range [start, end] in milliseconds as used by FilterLogEvents
+type timeSlice struct {
+	start int64
+	end   int64
+}
+
+// splitTimeRange splits [startTime, endTime] into consecutive non-overlapping slices
+// FilterLogEvents treats both bounds as inclusive, so each slice ends 1ms before the next begins
+func splitTimeRange(startTime, endTime time.Time, sliceDuration time.Duration) []timeSlice {
+	start := startTime.UnixMilli()
+	end := endTime.UnixMilli()
+	step := sliceDuration.Milliseconds()
+	if step < 1 {
+		step = 1
+	}
+
+	var slices []timeSlice
+	for sliceStart := start; sliceStart <= end; sliceStart += step {
+		sliceEnd := sliceStart + step - 1
+		if sliceEnd > end {
+			sliceEnd = end
+		}
+		slices = append(slices, timeSlice{start: sliceStart, end: sliceEnd})
+	}
+
+	// An inverted range is passed through unchanged so CloudWatch reports it
+	if len(slices) == 0 {
+		slices = append(slices, timeSlice{start: start, end: end})
+	}
+
+	return slices
+}
+
+// CountSlices returns the number of time slices FilterLogEventsParallel fetches for the time range
+// The slices are counted without being allocated, so a too short slice duration can be reported cheaply
+func CountSlices(startTime, endTime time.Time, sliceDuration time.Duration) int {
+	start := startTime.UnixMilli()
+	end := endTime.UnixMilli()
+	step := sliceDuration.Milliseconds()
+	if step < 1 {
+		step = 1
+	}
+	if end < start {
+		return 1
+	}
+	return int((end-start)/step) + 1
+}
+
+// LogGroupEvent is a filtered log event together with the name of the log group it was read from
+type LogGroupEvent struct {
+	LogGroupName string
+	types.FilteredLogEvent
+}
+
+// slicePage is a page of merged events of a single time slice, or the error that stopped the slice
+type slicePage struct {
+	events []LogGroupEvent
+	err    error
+}
+
+// FilterLogEventsParallel splits the time range into slices and fetches them with a bounded worker pool
+// Every slice is fetched from all log groups, which are merged in timestamp order while they are paginated.
+// handlePage is called with the merged pages in slice order, so the handler sees all events in timestamp order.
+// Slices are streamed rather than held in memory: each of the at most opts.Workers slices in flight buffers a few
+// pages per log group, however long the slice is. At most mergeMaxRequests pages are requested at once.
+func (c *Client) FilterLogEventsParallel(ctx context.Context, logGroupNames []string, startTime, endTime time.Time, opts ParallelOptions, handlePage func([]LogGroupEvent) error) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if count := CountSlices(startTime, endTime, opts.SliceDuration); count > MaxSlices {
+		return fmt.Errorf("slice duration %s splits the time range into %d slices, more than the maximum of %d", opts.SliceDuration, count, MaxSlices)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	slices := splitTimeRange(startTime, endTime, opts.SliceDuration)
+	requestSlots := newRequestSlots()
+
+	// A slot is taken when a slice starts and released once the slice has been handled, which bounds both
+	// concurrency and the number of buffered pages. The page channel of a slice is only created when it starts
+	// and is handed to the consumer in slice order; as every started slice holds a slot, sending it never blocks.
+	slots := make(chan struct{}, opts.Workers)
+	results := make(chan chan slicePage, opts.Workers)
+	go func() {
+		defer close(results)
+		for _, slice := range slices {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			pages := make(chan slicePage, mergeBufferPages)
+			results <- pages
+			go func(pages chan<- slicePage, slice timeSlice) {
+				defer close(pages)
+				err := c.mergeLogGroups(ctx, requestSlots, logGroupNames, slice.start, slice.end, func(events []LogGroupEvent) error {
+					select {
+					case pages <- slicePage{events: events}:
+						return nil
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				})
+				if err != nil {
+					select {
+					case pages <- slicePage{err: err}:
+					case <-ctx.Done():
+					}
+				}
+			}(pages, slice)
+		}
+	}()
+
+	for pages := range results {
+		for done := false; !done; {
+			select {
+			case page, ok := <-pages:
+				if !ok {
+					done = true
+					break
+				}
+				if page.err != nil {
+					return page.err
+				}
+				if err := handlePage(page.events); err != nil {
+					return err
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		<-slots
+	}
+
+	// Slices stop being started when the context is canceled
+	return ctx.Err()
+}
@@ -0,0 +1,335 @@
+package cloudwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCloudWatchLogsAPI serves stored events by time range with pagination
+type fakeCloudWatchLogsAPI struct {
+	events      []types.FilteredLogEvent
+	groupEvents map[string][]types.FilteredLogEvent // Events of a single log group, overriding events for it
+	pageSize    int
+	delay       time.Duration
+	failAt      int64 // Slice start time (ms) that fails, 0 disables
+
+	mu            sync.Mutex
+	calls         int
+	inFlight      int
+	maxInFlight   int
+	requestedEnds []int64
+}
+
+func (f *fakeCloudWatchLogsAPI) FilterLogEvents(ctx context.Context, params *cloudwatchlogs.FilterLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	f.mu.Lock()
+	f.calls++
+	f.inFlight++
+	if f.inFlight > f.maxInFlight {
+		f.maxInFlight = f.inFlight
+	}
+	if params.NextToken == nil {
+		f.requestedEnds = append(f.requestedEnds, *params.EndTime)
+	}
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		f.inFlight--
+		f.mu.Unlock()
+	}()
+
+	time.Sleep(f.delay)
+
+	if f.failAt != 0 && *params.StartTime == f.failAt {
+		return nil, errors.New("slice failed")
+	}
+
+	events := f.events
+	if groupEvents, ok := f.groupEvents[*params.LogGroupName]; ok {
+		events = groupEvents
+	}
+
+	// Like CloudWatch, return the matching events sorted by timestamp
+	var matched []types.FilteredLogEvent
+	for _, event := range events {
+		if *event.Timestamp >= *params.StartTime && *event.Timestamp <= *params.EndTime {
+			matched = append(matched, event)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return *matched[i].Timestamp < *matched[j].Timestamp
+	})
+
+	offset := 0
+	if params.NextToken != nil {
+		_, err := fmt.Sscanf(*params.NextToken, "offset-%d", &offset)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	end := offset + f.pageSize
+	output := &cloudwatchlogs.FilterLogEventsOutput{}
+	if end < len(matched) {
+		output.NextToken = stringPtr(fmt.Sprintf("offset-%d", end))
+	} else {
+		end = len(matched)
+	}
+	output.Events = matched[offset:end]
+
+	return output, nil
+}
+
+func (f *fakeCloudWatchLogsAPI) StartQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeCloudWatchLogsAPI) GetQueryResults(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeCloudWatchLogsAPI) DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	return nil, errors.New("not implemented")
+}
+
+func newFakeEvents(start time.Time, count int, step time.Duration) []types.FilteredLogEvent {
+	events := make([]types.FilteredLogEvent, 0, count)
+	// Store events in reverse order to verify they are served in timestamp order
+	for i := count - 1; i >= 0; i-- {
+		events = append(events, types.FilteredLogEvent{
+			EventId:   stringPtr(fmt.Sprintf("event%d", i)),
+			Message:   stringPtr(fmt.Sprintf("log%d", i)),
+			Timestamp: int64Ptr(start.Add(time.Duration(i) * step).UnixMilli()),
+		})
+	}
+	return events
+}
+
+func TestSplitTimeRange(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		end      time.Time
+		slice    time.Duration
+		expected []timeSlice
+	}{
+		{
+			name:  "even split",
+			end:   start.Add(2 * time.Hour),
+			slice: time.Hour,
+			expected: []timeSlice{
+				{start: start.UnixMilli(), end: start.Add(time.Hour).UnixMilli() - 1},
+				{start: start.Add(time.Hour).UnixMilli(), end: start.Add(2*time.Hour).UnixMilli() - 1},
+				{start: start.Add(2 * time.Hour).UnixMilli(), end: start.Add(2 * time.Hour).UnixMilli()},
+			},
+		},
+		{
+			name:  "range shorter than slice",
+			end:   start.Add(30 * time.Minute),
+			slice: time.Hour,
+			expected: []timeSlice{
+				{start: start.UnixMilli(), end: start.Add(30 * time.Minute).UnixMilli()},
+			},
+		},
+		{
+			name:  "inverted range is passed through",
+			end:   start.Add(-time.Hour),
+			slice: time.Hour,
+			expected: []timeSlice{
+				{start: start.UnixMilli(), end: start.Add(-time.Hour).UnixMilli()},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, splitTimeRange(start, tt.end, tt.slice))
+		})
+	}
+}
+
+func TestCountSlices(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 3, CountSlices(start, start.Add(3*time.Hour-time.Millisecond), time.Hour))
+	assert.Equal(t, 4, CountSlices(start, start.Add(3*time.Hour), time.Hour))
+	assert.Equal(t, 1, CountSlices(start, start.Add(time.Minute), time.Hour))
+	assert.Equal(t, 1, CountSlices(start, start.Add(-time.Hour), time.Hour))
+}
+
+func TestClient_FilterLogEventsParallel(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(6 * time.Hour)
+
+	fake := &fakeCloudWatchLogsAPI{
+		events:   newFakeEvents(start, 60, 6*time.Minute),
+		pageSize: 4,
+		delay:    5 * time.Millisecond,
+	}
+	client := NewClientWithAPI(fake)
+
+	var timestamps []int64
+	err := client.FilterLogEventsParallel(context.Background(), []string{"test-log-group"}, start, end,
+		ParallelOptions{Workers: 3, SliceDuration: time.Hour},
+		func(events []LogGroupEvent) error {
+			for _, event := range events {
+				timestamps = append(timestamps, *event.Timestamp)
+			}
+			return nil
+		})
+	require.NoError(t, err)
+
+	// Every event is returned exactly once, in timestamp order
+	require.Len(t, timestamps, 60)
+	for i := 1; i < len(timestamps); i++ {
+		assert.Less(t, timestamps[i-1], timestamps[i])
+	}
+
+	assert.LessOrEqual(t, fake.maxInFlight, 3)
+	assert.Greater(t, fake.maxInFlight, 1)
+	assert.Len(t, fake.requestedEnds, 7)
+}
+
+func TestClient_FilterLogEventsParallel_MultipleLogGroups(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+
+	fake := &fakeCloudWatchLogsAPI{
+		events:   newFakeEvents(start, 20, 6*time.Minute),
+		pageSize: 5,
+	}
+	client := NewClientWithAPI(fake)
+
+	var timestamps []int64
+	perGroup := make(map[string]int)
+	err := client.FilterLogEventsParallel(context.Background(), []string{"app-web", "app-api"}, start, end,
+		ParallelOptions{Workers: 2, SliceDuration: time.Hour},
+		func(events []LogGroupEvent) error {
+			for _, event := range events {
+				timestamps = append(timestamps, *event.Timestamp)
+				perGroup[event.LogGroupName]++
+			}
+			return nil
+		})
+	require.NoError(t, err)
+
+	// Both groups serve the same events, merged in timestamp order
+	assert.Equal(t, map[string]int{"app-web": 20, "app-api": 20}, perGroup)
+	require.Len(t, timestamps, 40)
+	for i := 1; i < len(timestamps); i++ {
+		assert.LessOrEqual(t, timestamps[i-1], timestamps[i])
+	}
+}
+
+func TestClient_FilterLogEventsParallel_BoundsRequestsAcrossLogGroups(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(4 * time.Hour)
+
+	fake := &fakeCloudWatchLogsAPI{
+		events:   newFakeEvents(start, 40, 6*time.Minute),
+		pageSize: 2,
+		delay:    2 * time.Millisecond,
+	}
+	client := NewClientWithAPI(fake)
+
+	// 50 log groups in each of 4 slices would paginate 200 log groups at once without the shared bound
+	logGroupNames := make([]string, 50)
+	for i := range logGroupNames {
+		logGroupNames[i] = fmt.Sprintf("app-%d", i)
+	}
+
+	events := 0
+	err := client.FilterLogEventsParallel(context.Background(), logGroupNames, start, end,
+		ParallelOptions{Workers: 4, SliceDuration: time.Hour},
+		func(page []LogGroupEvent) error {
+			events += len(page)
+			return nil
+		})
+	require.NoError(t, err)
+
+	assert.Equal(t, 40*len(logGroupNames), events)
+	assert.LessOrEqual(t, fake.maxInFlight, 16)
+	assert.Greater(t, fake.maxInFlight, 1)
+}
+
+func TestClient_FilterLogEventsParallel_Errors(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(4 * time.Hour)
+
+	t.Run("slice error", func(t *testing.T) {
+		fake := &fakeCloudWatchLogsAPI{
+			events:   newFakeEvents(start, 40, 6*time.Minute),
+			pageSize: 10,
+			failAt:   start.Add(2 * time.Hour).UnixMilli(),
+		}
+		client := NewClientWithAPI(fake)
+
+		handled := 0
+		err := client.FilterLogEventsParallel(context.Background(), []string{"test-log-group"}, start, end,
+			ParallelOptions{Workers: 2, SliceDuration: time.Hour},
+			func(events []LogGroupEvent) error {
+				handled++
+				return nil
+			})
+
+		assert.Error(t, err)
+		assert.Equal(t, "slice failed", err.Error())
+		assert.Equal(t, 2, handled)
+	})
+
+	t.Run("handler error", func(t *testing.T) {
+		fake := &fakeCloudWatchLogsAPI{
+			events:   newFakeEvents(start, 40, 6*time.Minute),
+			pageSize: 10,
+		}
+		client := NewClientWithAPI(fake)
+
+		err := client.FilterLogEventsParallel(context.Background(), []string{"test-log-group"}, start, end,
+			ParallelOptions{Workers: 2, SliceDuration: time.Hour},
+			func(events []LogGroupEvent) error {
+				return errors.New("consumer stopped")
+			})
+
+		assert.Error(t, err)
+		assert.Equal(t, "consumer stopped", err.Error())
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		client := NewClientWithAPI(&fakeCloudWatchLogsAPI{})
+
+		err := client.FilterLogEventsParallel(context.Background(), []string{"test-log-group"}, start, end,
+			ParallelOptions{Workers: 0, SliceDuration: time.Hour},
+			func(events []LogGroupEvent) error { return nil })
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "workers must be at least 1")
+	})
+
+	t.Run("too many slices", func(t *testing.T) {
+		fake := &fakeCloudWatchLogsAPI{}
+		client := NewClientWithAPI(fake)
+
+		err := client.FilterLogEventsParallel(context.Background(), []string{"test-log-group"}, start, end,
+			ParallelOptions{Workers: 2, SliceDuration: 100 * time.Millisecond},
+			func(events []LogGroupEvent) error { return nil })
+		require.Error(t, err)
+		assert.Equal(t, "slice duration 100ms splits the time range into 144001 slices, more than the maximum of 100000", err.Error())
+		assert.Zero(t, fake.calls)
+	})
+}
+
+func TestParallelOptions_Validate(t *testing.T) {
+	assert.NoError(t, DefaultParallelOptions().Validate())
+	assert.Error(t, ParallelOptions{Workers: 0, SliceDuration: time.Hour}.Validate())
+	assert.Error(t, ParallelOptions{Workers: 2, SliceDuration: 0}.Validate())
+}
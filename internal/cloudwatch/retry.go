@@ -0,0 +1,185 @@
+package cloudwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aws/smithy-go"
+)
+
+// Default retry settings
+const (
+	DefaultMaxAttempts = 5
+	DefaultBaseDelay   = 200 * time.Millisecond
+	DefaultMaxDelay    = 10 * time.Second
+)
+
+// retryableErrorCodes lists CloudWatch Logs error codes caused by throttling or transient failures
+var retryableErrorCodes = map[string]bool{
+	"ThrottlingException":         true,
+	"Throttling":                  true,
+	"TooManyRequestsException":    true,
+	"RequestLimitExceeded":        true,
+	"LimitExceededException":      true,
+	"ServiceUnavailableException": true,
+	"ServiceUnavailable":          true,
+	"InternalFailure":             true,
+	"RequestTimeout":              true,
+	"RequestTimeoutException":     true,
+}
+
+// RetryOptions controls retries of throttled or transiently failing API calls
+type RetryOptions struct {
+	MaxAttempts int           // Total number of attempts per API call, including the first
+	BaseDelay   time.Duration // Backoff before the first retry, doubled for every further retry
+	MaxDelay    time.Duration // Upper bound of a single backoff
+}
+
+// DefaultRetryOptions returns the default retry options
+func DefaultRetryOptions() RetryOptions {
+	return RetryOptions{
+		MaxAttempts: DefaultMaxAttempts,
+		BaseDelay:   DefaultBaseDelay,
+		MaxDelay:    DefaultMaxDelay,
+	}
+}
+
+// Validate checks that the options can be used for retrying
+func (o RetryOptions) Validate() error {
+	if o.MaxAttempts < 1 {
+		return fmt.Errorf("max attempts must be at least 1: %d", o.MaxAttempts)
+	}
+	if o.BaseDelay < 0 || o.MaxDelay < 0 {
+		return fmt.Errorf("retry delays must not be negative: base=%s max=%s", o.BaseDelay, o.MaxDelay)
+	}
+	return nil
+}
+
+// backoff returns the delay before the given retry (1-based) using exponential backoff with full jitter
+func (o RetryOptions) backoff(retry int) time.Duration {
+	delay := o.BaseDelay
+	for i := 1; i < retry && delay < o.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > o.MaxDelay {
+		delay = o.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return rand.N(delay) + 1
+}
+
+// httpStatusError is implemented by the response errors of the SDK, which carry the HTTP status code
+type httpStatusError interface {
+	HTTPStatusCode() int
+}
+
+// isRetryable reports whether err is caused by throttling or a transient failure
+// Since SDK retries are disabled, this also covers the server errors and dropped connections the SDK would retry
+func isRetryable(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && retryableErrorCodes[apiErr.ErrorCode()] {
+		return true
+	}
+
+	var statusErr httpStatusError
+	if errors.As(err, &statusErr) && statusErr.HTTPStatusCode() >= 500 {
+		return true
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// rateLimiter spaces out API calls to stay below a number of requests per second
+// It is shared by all workers of a client
+type rateLimiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     time.Time
+}
+
+// newRateLimiter creates a rate limiter allowing requestsPerSecond calls per second
+func newRateLimiter(requestsPerSecond float64) *rateLimiter {
+	return &rateLimiter{
+		interval: time.Duration(float64(time.Second) / requestsPerSecond),
+	}
+}
+
+// Wait blocks until the next call is allowed or the context is cancelled
+func (r *rateLimiter) Wait(ctx context.Context) error {
+	r.mu.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	wait := r.next.Sub(now)
+	r.next = r.next.Add(r.interval)
+	r.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// withRetry runs an API call, retrying throttled and transient failures with backoff
+// Every attempt waits for the rate limiter first, so retries count against the limit like any other request.
+// call must be safe to repeat; paginated calls reuse the same input, including its NextToken, so pagination
+// resumes where it stopped
+func (c *Client) withRetry(ctx context.Context, call func() error) error {
+	for attempt := 1; ; attempt++ {
+		if c.limiter != nil {
+			if err := c.limiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+
+		err := call()
+		if err == nil {
+			return nil
+		}
+		if !isRetryable(err) {
+			return err
+		}
+		if attempt >= c.retry.MaxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		delay := c.retry.backoff(attempt)
+		slog.Warn("Retrying CloudWatch request",
+			"attempt", attempt,
+			"delay", delay,
+			"error", err,
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
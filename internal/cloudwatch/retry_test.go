@@ -0,0 +1,254 @@
+package cloudwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var throttlingError = &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// responseError returns an SDK response error with the given HTTP status code
+func responseError(statusCode int) *smithyhttp.ResponseError {
+	return &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: statusCode}},
+		Err:      errors.New("unexpected response"),
+	}
+}
+
+func newRetryTestClient(api CloudWatchLogsAPI) *Client {
+	client := NewClientWithAPI(api)
+	_ = client.SetRetryOptions(RetryOptions{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    5 * time.Millisecond,
+	})
+	return client
+}
+
+func TestClient_RetryResumesFromNextToken(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	client := newRetryTestClient(mockAPI)
+
+	firstPage := mock.MatchedBy(func(input *cloudwatchlogs.FilterLogEventsInput) bool {
+		return input.NextToken == nil
+	})
+	secondPage := mock.MatchedBy(func(input *cloudwatchlogs.FilterLogEventsInput) bool {
+		return input.NextToken != nil && *input.NextToken == "token1"
+	})
+
+	mockAPI.On("FilterLogEvents", mock.Anything, firstPage).Return(&cloudwatchlogs.FilterLogEventsOutput{
+		Events:    []types.FilteredLogEvent{{EventId: stringPtr("event1")}},
+		NextToken: stringPtr("token1"),
+	}, nil).Once()
+	// The second page is throttled twice before it succeeds
+	mockAPI.On("FilterLogEvents", mock.Anything, secondPage).Return((*cloudwatchlogs.FilterLogEventsOutput)(nil), throttlingError).Twice()
+	mockAPI.On("FilterLogEvents", mock.Anything, secondPage).Return(&cloudwatchlogs.FilterLogEventsOutput{
+		Events: []types.FilteredLogEvent{{EventId: stringPtr("event2")}},
+	}, nil).Once()
+
+	events, err := client.FilterLogEventsWithPagination(context.Background(), "test-log-group", time.Now().Add(-time.Hour), time.Now())
+
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "event1", *events[0].EventId)
+	assert.Equal(t, "event2", *events[1].EventId)
+	mockAPI.AssertExpectations(t)
+	mockAPI.AssertNumberOfCalls(t, "FilterLogEvents", 4)
+}
+
+func TestClient_RetryGivesUp(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	client := newRetryTestClient(mockAPI)
+	mockAPI.On("FilterLogEvents", mock.Anything, mock.Anything).Return((*cloudwatchlogs.FilterLogEventsOutput)(nil), throttlingError)
+
+	events, err := client.FilterLogEventsWithPagination(context.Background(), "test-log-group", time.Now().Add(-time.Hour), time.Now())
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "giving up after 3 attempts")
+	assert.True(t, errors.Is(err, throttlingError))
+	assert.Nil(t, events)
+	mockAPI.AssertNumberOfCalls(t, "FilterLogEvents", 3)
+}
+
+func TestClient_NoRetryForPermanentErrors(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	client := newRetryTestClient(mockAPI)
+	accessDenied := &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "not authorized"}
+	mockAPI.On("FilterLogEvents", mock.Anything, mock.Anything).Return((*cloudwatchlogs.FilterLogEventsOutput)(nil), accessDenied)
+
+	_, err := client.FilterLogEvents(context.Background(), "test-log-group", time.Now().Add(-time.Hour), time.Now())
+
+	assert.Error(t, err)
+	assert.Equal(t, accessDenied, err)
+	mockAPI.AssertNumberOfCalls(t, "FilterLogEvents", 1)
+}
+
+func TestClient_RetryStopsOnContextCancel(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	client := NewClientWithAPI(mockAPI)
+	require.NoError(t, client.SetRetryOptions(RetryOptions{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}))
+	mockAPI.On("FilterLogEvents", mock.Anything, mock.Anything).Return((*cloudwatchlogs.FilterLogEventsOutput)(nil), throttlingError)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := client.FilterLogEventsWithPagination(ctx, "test-log-group", time.Now().Add(-time.Hour), time.Now())
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_RetriesInsightsQueries(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	client := newRetryTestClient(mockAPI)
+	client.pollInterval = time.Millisecond
+
+	mockAPI.On("StartQuery", mock.Anything, mock.Anything).Return((*cloudwatchlogs.StartQueryOutput)(nil), throttlingError).Once()
+	mockAPI.On("StartQuery", mock.Anything, mock.Anything).Return(&cloudwatchlogs.StartQueryOutput{QueryId: stringPtr("query1")}, nil).Once()
+	mockAPI.On("GetQueryResults", mock.Anything, mock.Anything).Return((*cloudwatchlogs.GetQueryResultsOutput)(nil), timeoutError{}).Once()
+	mockAPI.On("GetQueryResults", mock.Anything, mock.Anything).Return(&cloudwatchlogs.GetQueryResultsOutput{
+		Status:  types.QueryStatusComplete,
+		Results: [][]types.ResultField{{{Field: stringPtr("path"), Value: stringPtr("/users")}}},
+	}, nil).Once()
+
+	rows, err := client.RunInsightsQuery(context.Background(), []string{"test-log-group"}, time.Now().Add(-time.Hour), time.Now(), "fields @timestamp")
+
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "/users", rows[0]["path"])
+	mockAPI.AssertExpectations(t)
+}
+
+func TestClient_RetriesInsightsQueries_GivesUp(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	client := newRetryTestClient(mockAPI)
+	mockAPI.On("StartQuery", mock.Anything, mock.Anything).Return((*cloudwatchlogs.StartQueryOutput)(nil), throttlingError)
+
+	_, err := client.RunInsightsQuery(context.Background(), []string{"test-log-group"}, time.Now().Add(-time.Hour), time.Now(), "fields @timestamp")
+
+	assert.ErrorIs(t, err, throttlingError)
+	assert.Contains(t, err.Error(), "failed to start Logs Insights query: giving up after 3 attempts")
+	mockAPI.AssertNumberOfCalls(t, "StartQuery", 3)
+}
+
+func TestClient_RetriesDescribeLogGroups(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	client := newRetryTestClient(mockAPI)
+
+	mockAPI.On("DescribeLogGroups", mock.Anything, mock.Anything).Return((*cloudwatchlogs.DescribeLogGroupsOutput)(nil), throttlingError).Once()
+	mockAPI.On("DescribeLogGroups", mock.Anything, mock.Anything).Return(&cloudwatchlogs.DescribeLogGroupsOutput{
+		LogGroups: []types.LogGroup{{LogGroupName: stringPtr("/ecs/app-web")}},
+	}, nil).Once()
+
+	names, err := client.ResolveLogGroups(context.Background(), nil, []string{"/ecs/app-"})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/ecs/app-web"}, names)
+	mockAPI.AssertExpectations(t)
+}
+
+func TestClient_RetriesServerErrorsAndDroppedConnections(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	client := newRetryTestClient(mockAPI)
+
+	mockAPI.On("DescribeLogGroups", mock.Anything, mock.Anything).Return((*cloudwatchlogs.DescribeLogGroupsOutput)(nil), responseError(http.StatusServiceUnavailable)).Once()
+	mockAPI.On("DescribeLogGroups", mock.Anything, mock.Anything).Return((*cloudwatchlogs.DescribeLogGroupsOutput)(nil), io.ErrUnexpectedEOF).Once()
+	mockAPI.On("DescribeLogGroups", mock.Anything, mock.Anything).Return(&cloudwatchlogs.DescribeLogGroupsOutput{
+		LogGroups: []types.LogGroup{{LogGroupName: stringPtr("/ecs/app-web")}},
+	}, nil).Once()
+
+	names, err := client.ResolveLogGroups(context.Background(), nil, []string{"/ecs/app-"})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/ecs/app-web"}, names)
+	mockAPI.AssertExpectations(t)
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "throttling", err: throttlingError, expected: true},
+		{name: "service unavailable", err: &smithy.GenericAPIError{Code: "ServiceUnavailableException"}, expected: true},
+		{name: "wrapped throttling", err: errors.Join(errors.New("operation error"), throttlingError), expected: true},
+		{name: "network timeout", err: timeoutError{}, expected: true},
+		{name: "server error", err: responseError(http.StatusInternalServerError), expected: true},
+		{name: "service unavailable without error code", err: &awshttp.ResponseError{ResponseError: responseError(http.StatusServiceUnavailable)}, expected: true},
+		{name: "client error", err: responseError(http.StatusBadRequest), expected: false},
+		{name: "connection reset", err: &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, expected: true},
+		{name: "unexpected EOF", err: fmt.Errorf("failed to read response body: %w", io.ErrUnexpectedEOF), expected: true},
+		{name: "resource not found", err: &smithy.GenericAPIError{Code: "ResourceNotFoundException"}, expected: false},
+		{name: "plain error", err: errors.New("access denied"), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isRetryable(tt.err))
+		})
+	}
+}
+
+func TestRetryOptions_Backoff(t *testing.T) {
+	opts := RetryOptions{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for i := 0; i < 100; i++ {
+		assert.LessOrEqual(t, opts.backoff(1), 100*time.Millisecond)
+		assert.LessOrEqual(t, opts.backoff(3), 400*time.Millisecond)
+		assert.LessOrEqual(t, opts.backoff(20), time.Second)
+		assert.Greater(t, opts.backoff(20), time.Duration(0))
+	}
+
+	assert.Equal(t, time.Duration(0), RetryOptions{MaxAttempts: 1}.backoff(1))
+}
+
+func TestRetryOptions_Validate(t *testing.T) {
+	assert.NoError(t, DefaultRetryOptions().Validate())
+	assert.Error(t, RetryOptions{MaxAttempts: 0}.Validate())
+	assert.Error(t, RetryOptions{MaxAttempts: 1, BaseDelay: -time.Second}.Validate())
+}
+
+func TestClient_SetRateLimit(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	client := NewClientWithAPI(mockAPI)
+
+	assert.Error(t, client.SetRateLimit(-1))
+	require.NoError(t, client.SetRateLimit(50))
+	assert.NotNil(t, client.limiter)
+
+	mockAPI.On("FilterLogEvents", mock.Anything, mock.Anything).Return(&cloudwatchlogs.FilterLogEventsOutput{}, nil)
+
+	// Five calls at 50 requests per second take at least 80ms
+	begin := time.Now()
+	for i := 0; i < 5; i++ {
+		_, err := client.FilterLogEvents(context.Background(), "test-log-group", time.Now().Add(-time.Hour), time.Now())
+		require.NoError(t, err)
+	}
+	assert.GreaterOrEqual(t, time.Since(begin), 80*time.Millisecond)
+
+	require.NoError(t, client.SetRateLimit(0))
+	assert.Nil(t, client.limiter)
+}
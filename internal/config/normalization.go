@@ -0,0 +1,37 @@
+package config
+
+// NormalizationConfig represents the user rules for normalizing request paths
+type NormalizationConfig struct {
+	Disable  []string         `yaml:"disable,omitempty"`  // Built-in segment detectors to turn off
+	Segments []SegmentRule    `yaml:"segments,omitempty"` // Tried in order before the built-in detectors
+	Rewrites []RewriteRule    `yaml:"rewrites,omitempty"` // Whole-path rewrites; the first matching rule is applied
+	Prefixes []PrefixOverride `yaml:"prefixes,omitempty"` // Rules for the paths under a prefix
+	Query    []QueryRule      `yaml:"query,omitempty"`    // Query parameters kept in the normalized path
+}
+
+// SegmentRule replaces path segments matching a regex with a placeholder
+type SegmentRule struct {
+	Pattern     string `yaml:"pattern"`
+	Placeholder string `yaml:"placeholder"`
+}
+
+// RewriteRule rewrites a whole path matching a regex; the replacement may refer to groups as $1 or ${name}
+type RewriteRule struct {
+	Pattern     string `yaml:"pattern"`
+	Replacement string `yaml:"replacement"`
+}
+
+// PrefixOverride overrides the segment rules for the paths starting with a prefix
+// Its segment rules are tried before the top-level ones, and its disable list replaces the top-level one when set
+type PrefixOverride struct {
+	Prefix   string        `yaml:"prefix"`
+	Disable  []string      `yaml:"disable,omitempty"`
+	Segments []SegmentRule `yaml:"segments,omitempty"`
+}
+
+// QueryRule keeps selected query parameters of the paths starting with a prefix in the normalized path
+type QueryRule struct {
+	Prefix string   `yaml:"prefix"`
+	Params []string `yaml:"params"`           // Names of the parameters to keep
+	Values bool     `yaml:"values,omitempty"` // Keep the parameter values as well as the names
+}
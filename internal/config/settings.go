@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Settings represents the general settings stored next to the exclusion rules in the configuration file
+type Settings struct {
+	Timezone      string              `yaml:"timezone,omitempty"`      // IANA time zone for times given without an offset
+	LogFormats    []LogFormat         `yaml:"log_formats,omitempty"`   // Custom log line formats selectable by --log-format
+	Normalization NormalizationConfig `yaml:"normalization,omitempty"` // User rules for normalizing request paths
+}
+
+// LogFormat represents a custom log line format defined by regular expressions with named capture groups
+// (method, path, status, duration, request_id, timestamp, view, db, controller, action, format)
+type LogFormat struct {
+	Name            string `yaml:"name"`
+	Request         string `yaml:"request,omitempty"`          // Line holding the whole request
+	Started         string `yaml:"started,omitempty"`          // Line starting a request, matched with a completed line
+	Completed       string `yaml:"completed,omitempty"`        // Line completing a request
+	TimestampLayout string `yaml:"timestamp_layout,omitempty"` // Go time layout of the timestamp group
+	FilterPattern   string `yaml:"filter_pattern,omitempty"`   // CloudWatch filter pattern fetching the request lines
+}
+
+// LoadSettings reads the general settings from a config file
+func LoadSettings(configPath string) (*Settings, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", configPath, err)
+	}
+
+	var settings Settings
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	}
+
+	return &settings, nil
+}
+
+// LoadSettingsWithSearch reads the general settings from configPath, or from the config file found in
+// the standard locations when configPath is empty. Returns empty settings if no config file is found
+func LoadSettingsWithSearch(configPath string) (*Settings, error) {
+	if configPath != "" {
+		return LoadSettings(configPath)
+	}
+	if path, found := FindConfigPath(); found {
+		return LoadSettings(path)
+	}
+	return &Settings{}, nil
+}
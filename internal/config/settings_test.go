@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSettings(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "excluded_paths.yml")
+	configContent := `timezone: America/New_York
+excluded_paths:
+  - prefix: "/assets/"
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	settings, err := LoadSettings(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "America/New_York", settings.Timezone)
+
+	// The exclusion rules are still loaded from the same file
+	excluder, err := NewPathExcluder(configPath)
+	require.NoError(t, err)
+	assert.True(t, excluder.ShouldExclude("/assets/app.js"))
+}
+
+func TestLoadSettings_LogFormats(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "excluded_paths.yml")
+	configContent := `log_formats:
+  - name: prefixed
+    started: 'Started (?P<method>\w+) "(?P<path>[^"]+)"'
+    completed: 'Completed (?P<status>\d+) .* in (?P<duration>\d+)ms'
+    timestamp_layout: "2006/01/02 15:04:05"
+  - name: access
+    request: '(?P<method>\w+) (?P<path>\S+) (?P<status>\d+) (?P<duration>\d+)'
+    filter_pattern: "?ACCESS"
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	settings, err := LoadSettings(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, []LogFormat{
+		{
+			Name:            "prefixed",
+			Started:         `Started (?P<method>\w+) "(?P<path>[^"]+)"`,
+			Completed:       `Completed (?P<status>\d+) .* in (?P<duration>\d+)ms`,
+			TimestampLayout: "2006/01/02 15:04:05",
+		},
+		{
+			Name:          "access",
+			Request:       `(?P<method>\w+) (?P<path>\S+) (?P<status>\d+) (?P<duration>\d+)`,
+			FilterPattern: "?ACCESS",
+		},
+	}, settings.LogFormats)
+}
+
+func TestLoadSettings_Normalization(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "excluded_paths.yml")
+	configContent := `normalization:
+  disable: [hex]
+  segments:
+    - pattern: 'ord_[0-9A-Za-z]+'
+      placeholder: ":order_id"
+  rewrites:
+    - pattern: '^/blog/\d{4}/\d{2}/[^/]+$'
+      replacement: "/blog/:year/:month/:slug"
+  prefixes:
+    - prefix: /api/
+      disable: []
+      segments:
+        - pattern: '[a-z]+-[a-z]+'
+          placeholder: ":slug"
+  query:
+    - prefix: /search
+      params: [type]
+    - prefix: /api
+      params: [op]
+      values: true
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	settings, err := LoadSettings(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, NormalizationConfig{
+		Disable:  []string{"hex"},
+		Segments: []SegmentRule{{Pattern: "ord_[0-9A-Za-z]+", Placeholder: ":order_id"}},
+		Rewrites: []RewriteRule{{Pattern: `^/blog/\d{4}/\d{2}/[^/]+$`, Replacement: "/blog/:year/:month/:slug"}},
+		Prefixes: []PrefixOverride{
+			{Prefix: "/api/", Disable: []string{}, Segments: []SegmentRule{{Pattern: "[a-z]+-[a-z]+", Placeholder: ":slug"}}},
+		},
+		Query: []QueryRule{
+			{Prefix: "/search", Params: []string{"type"}},
+			{Prefix: "/api", Params: []string{"op"}, Values: true},
+		},
+	}, settings.Normalization)
+	// An empty disable list is kept apart from a missing one, so a prefix can enable every detector again
+	assert.NotNil(t, settings.Normalization.Prefixes[0].Disable)
+}
+
+func TestLoadSettings_Errors(t *testing.T) {
+	tempDir := t.TempDir()
+
+	_, err := LoadSettings(filepath.Join(tempDir, "missing.yml"))
+	assert.ErrorContains(t, err, "failed to read config file")
+
+	invalidPath := filepath.Join(tempDir, "invalid.yml")
+	require.NoError(t, os.WriteFile(invalidPath, []byte("timezone: [unclosed"), 0644))
+	_, err = LoadSettings(invalidPath)
+	assert.ErrorContains(t, err, "failed to parse config file")
+}
+
+func TestLoadSettingsWithSearch(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tempDir)
+	t.Setenv("HOME", tempDir)
+
+	// No config file found
+	settings, err := LoadSettingsWithSearch("")
+	require.NoError(t, err)
+	assert.Equal(t, &Settings{}, settings)
+
+	configDir := filepath.Join(tempDir, "cw-railspathmetrics")
+	require.NoError(t, os.MkdirAll(configDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "excluded_paths.yml"), []byte("timezone: UTC\n"), 0644))
+
+	settings, err = LoadSettingsWithSearch("")
+	require.NoError(t, err)
+	assert.Equal(t, "UTC", settings.Timezone)
+
+	explicitPath := filepath.Join(tempDir, "explicit.yml")
+	require.NoError(t, os.WriteFile(explicitPath, []byte("timezone: Asia/Tokyo\n"), 0644))
+	settings, err = LoadSettingsWithSearch(explicitPath)
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Tokyo", settings.Timezone)
+}
@@ -26,6 +26,21 @@ func (m *MockCloudWatchLogsAPI) FilterLogEvents(ctx context.Context, params *clo
 	return args.Get(0).(*cloudwatchlogs.FilterLogEventsOutput), args.Error(1)
 }
 
+func (m *MockCloudWatchLogsAPI) StartQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*cloudwatchlogs.StartQueryOutput), args.Error(1)
+}
+
+func (m *MockCloudWatchLogsAPI) GetQueryResults(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*cloudwatchlogs.GetQueryResultsOutput), args.Error(1)
+}
+
+func (m *MockCloudWatchLogsAPI) DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*cloudwatchlogs.DescribeLogGroupsOutput), args.Error(1)
+}
+
 // Helper functions
 func stringPtr(s string) *string {
 	return &s
@@ -167,7 +182,7 @@ func TestFullWorkflowIntegration(t *testing.T) {
 			startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
 			endTime := time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC)
 
-			filterPattern := `?Started ?Completed`
+			filterPattern := `?Started ?Processing ?Completed`
 			expectedInput := &cloudwatchlogs.FilterLogEventsInput{
 				LogGroupName:  &logGroupName,
 				StartTime:     int64Ptr(startTime.UnixMilli()),
@@ -231,7 +246,7 @@ func TestWorkflowWithPaginationIntegration(t *testing.T) {
 	endTime := time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC)
 
 	// Setup pagination scenario
-	filterPattern := `?Started ?Completed`
+	filterPattern := `?Started ?Processing ?Completed`
 
 	// First page
 	firstPageInput := &cloudwatchlogs.FilterLogEventsInput{
@@ -333,7 +348,7 @@ func TestErrorHandlingIntegration(t *testing.T) {
 			startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
 			endTime := time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC)
 
-			filterPattern := `?Started ?Completed`
+			filterPattern := `?Started ?Processing ?Completed`
 			expectedInput := &cloudwatchlogs.FilterLogEventsInput{
 				LogGroupName:  &logGroupName,
 				StartTime:     int64Ptr(startTime.UnixMilli()),
@@ -399,7 +414,7 @@ func TestTimeZoneHandlingIntegration(t *testing.T) {
 	utcEnd := jstEnd.UTC()     // 04:00 UTC
 
 	logGroupName := "test-log-group"
-	filterPattern := `?Started ?Completed`
+	filterPattern := `?Started ?Processing ?Completed`
 
 	expectedInput := &cloudwatchlogs.FilterLogEventsInput{
 		LogGroupName:  &logGroupName,
@@ -461,7 +476,7 @@ func TestSessionBasedMatchingIntegration(t *testing.T) {
 		},
 	}
 
-	filterPattern := `?Started ?Completed`
+	filterPattern := `?Started ?Processing ?Completed`
 	expectedInput := &cloudwatchlogs.FilterLogEventsInput{
 		LogGroupName:  &logGroupName,
 		StartTime:     int64Ptr(startTime.UnixMilli()),
@@ -1,31 +1,48 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // LogEvent represents a CloudWatch log event
 type LogEvent struct {
 	ID        string
 	Message   string
 	Timestamp time.Time
+	LogGroup  string // Log group the event was read from, empty for local files
+	LogStream string // Log stream the event was read from, empty for local files
 }
 
 // LogEntry represents a parsed Rails log entry
 type LogEntry struct {
-	Type         string    // "Started" or "Completed"
+	Type         string    // "Started", "Processing", "Completed", or "Request" for a whole request in one structured line
 	Method       string    // HTTP method (GET, POST, etc.) - only for Started logs
 	Path         string    // Request path - only for Started logs
-	Timestamp    time.Time // Log timestamp - only for Started logs
+	Timestamp    time.Time // Log timestamp - for Started logs, and for all entries from Logs Insights
 	StatusCode   int       // HTTP status code - only for Completed logs
 	StatusText   string    // Status text (OK, Not Found, etc.) - only for Completed logs
 	Duration     int       // Total duration in milliseconds - only for Completed logs
 	ViewDuration float64   // View rendering duration - only for Completed logs
 	DBDuration   float64   // ActiveRecord duration - only for Completed logs
+	Controller   string    // Controller class (UsersController) - from Processing logs, copied onto the matching Started log
+	Action       string    // Controller action (show) - from Processing logs, copied onto the matching Started log
+	Format       string    // Request format (HTML, JSON, */*) - from Processing logs, copied onto the matching Started log
 	SessionID    string    // Session identifier extracted from the log (used for matching Started and Completed logs)
+	ProcessID    string    // PID from the Ruby Logger prefix, used to match logs without a session identifier
+	ThreadID     string    // Thread from a log tag such as tid=..., used with ProcessID to match logs without a session identifier
+	LogGroup     string    // Log group the entry was read from, empty for local files
+	LogStream    string    // Log stream the entry was read from, empty for local files
 }
 
 // PathMetrics represents aggregated metrics for a specific path
 type PathMetrics struct {
+	Method            string         `json:"method,omitempty"` // Set when metrics are grouped by HTTP method
 	Path              string         `json:"path"`
+	LogStream         string         `json:"log_stream,omitempty"` // Set when metrics are grouped by log stream
 	Count             int            `json:"count"`
 	AverageTime       float64        `json:"average_time_ms"`
 	MinTime           int            `json:"min_time_ms"`
@@ -34,27 +51,411 @@ type PathMetrics struct {
 	Methods           map[string]int `json:"methods"`
 	TotalViewDuration float64        `json:"total_view_duration_ms,omitempty"`
 	TotalDBDuration   float64        `json:"total_db_duration_ms,omitempty"`
+	P50Time           int            `json:"p50_time_ms"`
+	P90Time           int            `json:"p90_time_ms"`
+	P95Time           int            `json:"p95_time_ms"`
+	P99Time           int            `json:"p99_time_ms"`
+	LogGroups         map[string]int `json:"log_groups,omitempty"`   // Request count per log group
+	QueryParams       map[string]int `json:"query_params,omitempty"` // Request count per query parameter name, when collected
+	Durations         map[int]int    `json:"-"`                      // Request count per duration bucket used to calculate percentiles
 }
 
 // SimplifiedPathMetrics represents simplified metrics for JSON output
 type SimplifiedPathMetrics struct {
+	Method    string `json:"method,omitempty"`
 	Path      string `json:"path"`
+	LogStream string `json:"log_stream,omitempty"`
 	Count     int    `json:"count"`
 	MaxTimeMs int    `json:"max_time_ms"`
 	MinTimeMs int    `json:"min_time_ms"`
 	AvgTimeMs int    `json:"avg_time_ms"`
+	P50TimeMs int    `json:"p50_time_ms"`
+	P90TimeMs int    `json:"p90_time_ms"`
+	P95TimeMs int    `json:"p95_time_ms"`
+	P99TimeMs int    `json:"p99_time_ms"`
 }
 
+// MethodColumn is the column added before the path when metrics are grouped by HTTP method
+const MethodColumn = "method"
+
+// LogStreamColumn is the column added after the path when metrics are grouped by log stream
+const LogStreamColumn = "log_stream"
+
+// SimplifiedPathMetricsColumns lists the column names of SimplifiedPathMetrics in output order
+var SimplifiedPathMetricsColumns = []string{
+	"path", "count", "max_time_ms", "min_time_ms", "avg_time_ms",
+	"p50_time_ms", "p90_time_ms", "p95_time_ms", "p99_time_ms",
+}
+
+// Values returns the metric values as strings in the order of SimplifiedPathMetricsColumns
+func (m *SimplifiedPathMetrics) Values() []string {
+	return []string{
+		m.Path,
+		strconv.Itoa(m.Count),
+		strconv.Itoa(m.MaxTimeMs),
+		strconv.Itoa(m.MinTimeMs),
+		strconv.Itoa(m.AvgTimeMs),
+		strconv.Itoa(m.P50TimeMs),
+		strconv.Itoa(m.P90TimeMs),
+		strconv.Itoa(m.P95TimeMs),
+		strconv.Itoa(m.P99TimeMs),
+	}
+}
+
+// DetailedPathMetrics represents full metrics including status codes, methods and view/DB time for detailed output
+type DetailedPathMetrics struct {
+	Method      string         `json:"method,omitempty"`
+	Path        string         `json:"path"`
+	LogStream   string         `json:"log_stream,omitempty"`
+	Count       int            `json:"count"`
+	MaxTimeMs   int            `json:"max_time_ms"`
+	MinTimeMs   int            `json:"min_time_ms"`
+	AvgTimeMs   int            `json:"avg_time_ms"`
+	P50TimeMs   int            `json:"p50_time_ms"`
+	P90TimeMs   int            `json:"p90_time_ms"`
+	P95TimeMs   int            `json:"p95_time_ms"`
+	P99TimeMs   int            `json:"p99_time_ms"`
+	AvgViewMs   float64        `json:"avg_view_ms"`
+	AvgDBMs     float64        `json:"avg_db_ms"`
+	Count4xx    int            `json:"count_4xx"`
+	Count5xx    int            `json:"count_5xx"`
+	ErrorRate   float64        `json:"error_rate"` // Ratio of 5xx responses to all requests
+	StatusCodes map[int]int    `json:"status_codes"`
+	Methods     map[string]int `json:"methods"`
+	LogGroups   map[string]int `json:"log_groups,omitempty"` // Request count per log group
+}
+
+// DetailedPathMetricsColumns lists the column names of DetailedPathMetrics in output order
+var DetailedPathMetricsColumns = []string{
+	"path", "count", "max_time_ms", "min_time_ms", "avg_time_ms",
+	"p50_time_ms", "p90_time_ms", "p95_time_ms", "p99_time_ms",
+	"avg_view_ms", "avg_db_ms", "count_4xx", "count_5xx", "error_rate",
+	"status_codes", "methods", "log_groups",
+}
+
+// Values returns the metric values as strings in the order of DetailedPathMetricsColumns
+func (m *DetailedPathMetrics) Values() []string {
+	statusCodes := make([]string, 0, len(m.StatusCodes))
+	for code, count := range m.StatusCodes {
+		statusCodes = append(statusCodes, fmt.Sprintf("%d:%d", code, count))
+	}
+	sort.Strings(statusCodes)
+
+	methods := make([]string, 0, len(m.Methods))
+	for method, count := range m.Methods {
+		methods = append(methods, fmt.Sprintf("%s:%d", method, count))
+	}
+	sort.Strings(methods)
+
+	logGroups := make([]string, 0, len(m.LogGroups))
+	for logGroup, count := range m.LogGroups {
+		logGroups = append(logGroups, fmt.Sprintf("%s:%d", logGroup, count))
+	}
+	sort.Strings(logGroups)
+
+	return []string{
+		m.Path,
+		strconv.Itoa(m.Count),
+		strconv.Itoa(m.MaxTimeMs),
+		strconv.Itoa(m.MinTimeMs),
+		strconv.Itoa(m.AvgTimeMs),
+		strconv.Itoa(m.P50TimeMs),
+		strconv.Itoa(m.P90TimeMs),
+		strconv.Itoa(m.P95TimeMs),
+		strconv.Itoa(m.P99TimeMs),
+		strconv.FormatFloat(m.AvgViewMs, 'f', 1, 64),
+		strconv.FormatFloat(m.AvgDBMs, 'f', 1, 64),
+		strconv.Itoa(m.Count4xx),
+		strconv.Itoa(m.Count5xx),
+		strconv.FormatFloat(m.ErrorRate, 'f', 4, 64),
+		strings.Join(statusCodes, " "),
+		strings.Join(methods, " "),
+		strings.Join(logGroups, " "),
+	}
+}
+
+// Comparison statuses of a path between a baseline and a target result
+const (
+	ComparisonRegression = "regression" // Average or p95 time grew by more than the threshold
+	ComparisonImproved   = "improved"   // Average time shrank by more than the threshold
+	ComparisonUnchanged  = "unchanged"
+	ComparisonNew        = "new"     // Only requested in the target
+	ComparisonRemoved    = "removed" // Only requested in the baseline
+)
+
+// PathComparison represents the change of a path's metrics between a baseline and a target result
+type PathComparison struct {
+	Path          string  `json:"path"`
+	Status        string  `json:"status"`
+	BaselineCount int     `json:"baseline_count"`
+	TargetCount   int     `json:"target_count"`
+	CountDelta    int     `json:"count_delta"`
+	BaselineAvgMs int     `json:"baseline_avg_ms"`
+	TargetAvgMs   int     `json:"target_avg_ms"`
+	AvgDeltaMs    int     `json:"avg_delta_ms"`
+	AvgDeltaPct   float64 `json:"avg_delta_pct"`
+	BaselineMaxMs int     `json:"baseline_max_ms"`
+	TargetMaxMs   int     `json:"target_max_ms"`
+	MaxDeltaMs    int     `json:"max_delta_ms"`
+	BaselineP50Ms int     `json:"baseline_p50_ms"`
+	TargetP50Ms   int     `json:"target_p50_ms"`
+	P50DeltaMs    int     `json:"p50_delta_ms"`
+	BaselineP90Ms int     `json:"baseline_p90_ms"`
+	TargetP90Ms   int     `json:"target_p90_ms"`
+	P90DeltaMs    int     `json:"p90_delta_ms"`
+	BaselineP95Ms int     `json:"baseline_p95_ms"`
+	TargetP95Ms   int     `json:"target_p95_ms"`
+	P95DeltaMs    int     `json:"p95_delta_ms"`
+	P95DeltaPct   float64 `json:"p95_delta_pct"`
+	BaselineP99Ms int     `json:"baseline_p99_ms"`
+	TargetP99Ms   int     `json:"target_p99_ms"`
+	P99DeltaMs    int     `json:"p99_delta_ms"`
+}
+
+// PathComparisonColumns lists the column names of PathComparison in output order
+var PathComparisonColumns = []string{
+	"path", "status", "baseline_count", "target_count", "count_delta",
+	"baseline_avg_ms", "target_avg_ms", "avg_delta_ms", "avg_delta_pct",
+	"baseline_max_ms", "target_max_ms", "max_delta_ms",
+	"baseline_p50_ms", "target_p50_ms", "p50_delta_ms",
+	"baseline_p90_ms", "target_p90_ms", "p90_delta_ms",
+	"baseline_p95_ms", "target_p95_ms", "p95_delta_ms", "p95_delta_pct",
+	"baseline_p99_ms", "target_p99_ms", "p99_delta_ms",
+}
+
+// Values returns the comparison values as strings in the order of PathComparisonColumns
+func (c *PathComparison) Values() []string {
+	return []string{
+		c.Path,
+		c.Status,
+		strconv.Itoa(c.BaselineCount),
+		strconv.Itoa(c.TargetCount),
+		strconv.Itoa(c.CountDelta),
+		strconv.Itoa(c.BaselineAvgMs),
+		strconv.Itoa(c.TargetAvgMs),
+		strconv.Itoa(c.AvgDeltaMs),
+		strconv.FormatFloat(c.AvgDeltaPct, 'f', 1, 64),
+		strconv.Itoa(c.BaselineMaxMs),
+		strconv.Itoa(c.TargetMaxMs),
+		strconv.Itoa(c.MaxDeltaMs),
+		strconv.Itoa(c.BaselineP50Ms),
+		strconv.Itoa(c.TargetP50Ms),
+		strconv.Itoa(c.P50DeltaMs),
+		strconv.Itoa(c.BaselineP90Ms),
+		strconv.Itoa(c.TargetP90Ms),
+		strconv.Itoa(c.P90DeltaMs),
+		strconv.Itoa(c.BaselineP95Ms),
+		strconv.Itoa(c.TargetP95Ms),
+		strconv.Itoa(c.P95DeltaMs),
+		strconv.FormatFloat(c.P95DeltaPct, 'f', 1, 64),
+		strconv.Itoa(c.BaselineP99Ms),
+		strconv.Itoa(c.TargetP99Ms),
+		strconv.Itoa(c.P99DeltaMs),
+	}
+}
+
+// Strategies used to match a Started entry with its Completed entry
+const (
+	MatchByRequestID = "request_id" // Both entries carry the same request ID tag
+	MatchByLogOrder  = "log_order"  // Untagged entries paired by order within a log stream and process
+	MatchByRecord    = "record"     // Structured logs with the whole request in one line, e.g. lograge
+	// Untagged entries paired first in, first out while several requests of the same log stream, process and thread
+	// were pending, e.g. on a threaded server; the pairing may be wrong
+	MatchByLogOrderAmbiguous = "log_order_ambiguous"
+)
+
 // AnalysisResult represents the final analysis output
 type AnalysisResult struct {
-	StartTime   time.Time               `json:"start_time"`
-	EndTime     time.Time               `json:"end_time"`
-	TotalLogs   int                     `json:"total_logs_analyzed"`
-	PathMetrics map[string]*PathMetrics `json:"path_metrics"`
+	StartTime   time.Time                     `json:"start_time"`
+	EndTime     time.Time                     `json:"end_time"`
+	TotalLogs   int                           `json:"total_logs_analyzed"`
+	GroupBy     string                        `json:"group_by,omitempty"`     // Dimensions besides the path the metrics are grouped by, e.g. method,stream
+	AggregateBy string                        `json:"aggregate_by,omitempty"` // Key the metrics are aggregated by when not the path, e.g. action
+	MatchedBy   map[string]int                `json:"matched_by,omitempty"`   // Number of matched requests per match strategy
+	Diagnostics *Diagnostics                  `json:"diagnostics,omitempty"`
+	PathMetrics map[string]*PathMetrics       `json:"path_metrics"`
+	Incomplete  map[string]*IncompleteMetrics `json:"incomplete,omitempty"` // Requests that never completed, keyed like PathMetrics
+	Collapsed   []*CollapsedSegment           `json:"collapsed,omitempty"`  // High-cardinality path positions merged into CollapsedPlaceholder
+}
+
+// IncompleteRequest represents a Started entry without a Completed entry, e.g. a killed worker or a load balancer timeout
+type IncompleteRequest struct {
+	Method    string    `json:"method"`
+	StartedAt time.Time `json:"started_at"`
+	AgeMs     int64     `json:"age_ms"` // Time from the Started entry to the end of the analyzed window
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+// IncompleteMetrics represents the requests of a specific path that never completed
+type IncompleteMetrics struct {
+	Method          string               `json:"method,omitempty"` // Set when metrics are grouped by HTTP method
+	Path            string               `json:"path"`
+	LogStream       string               `json:"log_stream,omitempty"` // Set when metrics are grouped by log stream
+	Count           int                  `json:"count"`
+	OldestStartedAt time.Time            `json:"oldest_started_at"`
+	MaxAgeMs        int64                `json:"max_age_ms"`
+	Requests        []*IncompleteRequest `json:"requests"` // Ordered by Started timestamp
+}
+
+// IncompleteMetricsColumns lists the column names of IncompleteMetrics in output order
+var IncompleteMetricsColumns = []string{"path", "count", "oldest_started_at", "max_age_ms", "requests"}
+
+// Values returns the incomplete request values as strings in the order of IncompleteMetricsColumns
+func (m *IncompleteMetrics) Values() []string {
+	requests := make([]string, 0, len(m.Requests))
+	for _, request := range m.Requests {
+		requests = append(requests, fmt.Sprintf("%s %s (%dms)", request.Method, request.StartedAt.UTC().Format(time.RFC3339), request.AgeMs))
+	}
+
+	return []string{
+		m.Path,
+		strconv.Itoa(m.Count),
+		m.OldestStartedAt.UTC().Format(time.RFC3339),
+		strconv.FormatInt(m.MaxAgeMs, 10),
+		strings.Join(requests, ", "),
+	}
+}
+
+// QueryParamCount is the number of requests with a query parameter
+type QueryParamCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// QueryParamSummary represents the query parameter names of the requests to a specific path
+type QueryParamSummary struct {
+	Method    string             `json:"method,omitempty"`
+	Path      string             `json:"path"`
+	LogStream string             `json:"log_stream,omitempty"`
+	Count     int                `json:"count"`  // Requests to the path
+	Params    []*QueryParamCount `json:"params"` // Ordered by count descending, then by name
+}
+
+// QueryParamSummaryColumns lists the column names of QueryParamSummary in output order
+var QueryParamSummaryColumns = []string{"path", "count", "params"}
+
+// Values returns the query parameter summary values as strings in the order of QueryParamSummaryColumns
+func (q *QueryParamSummary) Values() []string {
+	params := make([]string, 0, len(q.Params))
+	for _, param := range q.Params {
+		params = append(params, fmt.Sprintf("%s (%d)", param.Name, param.Count))
+	}
+
+	return []string{
+		q.Path,
+		strconv.Itoa(q.Count),
+		strings.Join(params, ", "),
+	}
+}
+
+// CollapsedPlaceholder replaces the values of a path position with too many distinct values
+const CollapsedPlaceholder = ":param"
+
+// CollapsedSegment represents a path position whose distinct values were collapsed into CollapsedPlaceholder
+type CollapsedSegment struct {
+	Path     string   `json:"path"`              // Path up to the collapsed position, e.g. /articles/:param
+	Position int      `json:"position"`          // Index of the collapsed segment, starting at 1
+	Distinct int      `json:"distinct"`          // Number of distinct values collapsed
+	Count    int      `json:"count"`             // Number of requests aggregated under the collapsed position
+	Samples  []string `json:"samples,omitempty"` // Some of the collapsed values
+}
+
+// CollapsedSegmentColumns lists the column names of CollapsedSegment in output order
+var CollapsedSegmentColumns = []string{"path", "position", "distinct", "count", "samples"}
+
+// Values returns the collapsed segment values as strings in the order of CollapsedSegmentColumns
+func (c *CollapsedSegment) Values() []string {
+	return []string{
+		c.Path,
+		strconv.Itoa(c.Position),
+		strconv.Itoa(c.Distinct),
+		strconv.Itoa(c.Count),
+		strings.Join(c.Samples, ", "),
+	}
+}
+
+// Kinds of log lines and entries that did not contribute to the metrics
+const (
+	DiagnosticParseFailure       = "parse_failure"        // Request line that could not be parsed
+	DiagnosticOrphanedStarted    = "orphaned_started"     // Started entry never completed, e.g. a timeout or crash
+	DiagnosticOrphanedCompleted  = "orphaned_completed"   // Completed entry without a Started entry
+	DiagnosticDuplicateRequestID = "duplicate_request_id" // Started entry replacing a pending one with the same request ID
+)
+
+// DiagnosticCount is the number of occurrences of a diagnostic with some example log lines
+type DiagnosticCount struct {
+	Count   int      `json:"count"`
+	Samples []string `json:"samples,omitempty"`
+}
+
+// Diagnostics summarizes the log lines and entries that did not contribute to the metrics
+type Diagnostics struct {
+	ParseFailures       map[string]*DiagnosticCount `json:"parse_failures"` // Unparseable request lines per reason
+	OrphanedStarted     DiagnosticCount             `json:"orphaned_started"`
+	OrphanedCompleted   DiagnosticCount             `json:"orphaned_completed"`
+	DuplicateRequestIDs DiagnosticCount             `json:"duplicate_request_ids"`
+}
+
+// DiagnosticRecord represents a single row of the diagnostics output
+type DiagnosticRecord struct {
+	Diagnostic string   `json:"diagnostic"`
+	Reason     string   `json:"reason,omitempty"` // Parse failure reason, empty for other diagnostics
+	Count      int      `json:"count"`
+	Samples    []string `json:"samples,omitempty"`
+}
+
+// DiagnosticRecordColumns lists the column names of DiagnosticRecord in output order
+var DiagnosticRecordColumns = []string{"diagnostic", "reason", "count", "samples"}
+
+// Values returns the diagnostic values as strings in the order of DiagnosticRecordColumns
+func (r *DiagnosticRecord) Values() []string {
+	return []string{
+		r.Diagnostic,
+		r.Reason,
+		strconv.Itoa(r.Count),
+		strings.Join(r.Samples, " | "),
+	}
+}
+
+// Records returns one row per diagnostic, with parse failures sorted by reason
+func (d *Diagnostics) Records() []*DiagnosticRecord {
+	reasons := make([]string, 0, len(d.ParseFailures))
+	for reason := range d.ParseFailures {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	records := make([]*DiagnosticRecord, 0, len(reasons)+3)
+	for _, reason := range reasons {
+		failures := d.ParseFailures[reason]
+		records = append(records, &DiagnosticRecord{
+			Diagnostic: DiagnosticParseFailure,
+			Reason:     reason,
+			Count:      failures.Count,
+			Samples:    failures.Samples,
+		})
+	}
+	for _, diagnostic := range []struct {
+		name  string
+		count DiagnosticCount
+	}{
+		{DiagnosticOrphanedStarted, d.OrphanedStarted},
+		{DiagnosticOrphanedCompleted, d.OrphanedCompleted},
+		{DiagnosticDuplicateRequestID, d.DuplicateRequestIDs},
+	} {
+		records = append(records, &DiagnosticRecord{
+			Diagnostic: diagnostic.name,
+			Count:      diagnostic.count.Count,
+			Samples:    diagnostic.count.Samples,
+		})
+	}
+
+	return records
 }
 
 // RequestPair represents a matched Started and Completed log pair
 type RequestPair struct {
 	Started   *LogEntry
 	Completed *LogEntry
+	MatchedBy string // Match strategy that paired the entries
 }
@@ -0,0 +1,61 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Supported output format names
+const (
+	FormatJSON     = "json"
+	FormatTable    = "table"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+	FormatNDJSON   = "ndjson"
+)
+
+// Record represents a single row of output that can be rendered by every Formatter
+type Record interface {
+	// Values returns the column values in the same order as the table columns
+	Values() []string
+}
+
+// Table represents a set of records with their column headers
+type Table struct {
+	Columns []string
+	Records []Record
+}
+
+// Formatter renders a Table to a writer in a specific format
+type Formatter interface {
+	Format(table *Table, writer io.Writer) error
+}
+
+var formatters = map[string]func() Formatter{
+	FormatJSON:     func() Formatter { return NewJSONFormatter() },
+	FormatTable:    func() Formatter { return NewTableFormatter() },
+	FormatCSV:      func() Formatter { return NewCSVFormatter() },
+	FormatMarkdown: func() Formatter { return NewMarkdownFormatter() },
+	FormatNDJSON:   func() Formatter { return NewNDJSONFormatter() },
+}
+
+// NewFormatter returns the Formatter registered for the given format name
+func NewFormatter(format string) (Formatter, error) {
+	newFunc, exists := formatters[strings.ToLower(format)]
+	if !exists {
+		return nil, fmt.Errorf("unsupported output format %q (supported: %s)", format, strings.Join(SupportedFormats(), ", "))
+	}
+	return newFunc(), nil
+}
+
+// SupportedFormats returns the names of all supported output formats in alphabetical order
+func SupportedFormats() []string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,146 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRecord struct {
+	Path  string `json:"path"`
+	Count string `json:"count"`
+}
+
+func (r *testRecord) Values() []string {
+	return []string{r.Path, r.Count}
+}
+
+func newTestTable() *Table {
+	return &Table{
+		Columns: []string{"path", "count"},
+		Records: []Record{
+			&testRecord{Path: "/users/:id", Count: "120"},
+			&testRecord{Path: "/search|all", Count: "5"},
+		},
+	}
+}
+
+func TestNewFormatter(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		expected    Formatter
+		expectError bool
+	}{
+		{name: "json", format: "json", expected: &JSONFormatter{}},
+		{name: "table", format: "table", expected: &TableFormatter{}},
+		{name: "csv", format: "csv", expected: &CSVFormatter{}},
+		{name: "markdown", format: "markdown", expected: &MarkdownFormatter{}},
+		{name: "ndjson", format: "ndjson", expected: &NDJSONFormatter{}},
+		{name: "case insensitive", format: "CSV", expected: &CSVFormatter{}},
+		{name: "unsupported format", format: "xml", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter, err := NewFormatter(tt.format)
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "unsupported output format")
+				assert.Nil(t, formatter)
+			} else {
+				assert.NoError(t, err)
+				assert.IsType(t, tt.expected, formatter)
+			}
+		})
+	}
+}
+
+func TestSupportedFormats(t *testing.T) {
+	assert.Equal(t, []string{"csv", "json", "markdown", "ndjson", "table"}, SupportedFormats())
+}
+
+func TestFormatters_Format(t *testing.T) {
+	tests := []struct {
+		name      string
+		formatter Formatter
+		table     *Table
+		expected  string
+	}{
+		{
+			name:      "json",
+			formatter: NewJSONFormatter(),
+			table:     newTestTable(),
+			expected: `[
+    {
+        "path": "/users/:id",
+        "count": "120"
+    },
+    {
+        "path": "/search|all",
+        "count": "5"
+    }
+]
+`,
+		},
+		{
+			name:      "json with no records",
+			formatter: NewJSONFormatter(),
+			table:     &Table{Columns: []string{"path", "count"}},
+			expected:  "[]\n",
+		},
+		{
+			name:      "ndjson",
+			formatter: NewNDJSONFormatter(),
+			table:     newTestTable(),
+			expected: `{"path":"/users/:id","count":"120"}
+{"path":"/search|all","count":"5"}
+`,
+		},
+		{
+			name:      "table",
+			formatter: NewTableFormatter(),
+			table:     newTestTable(),
+			expected: `PATH         COUNT
+/users/:id   120
+/search|all  5
+`,
+		},
+		{
+			name:      "csv",
+			formatter: NewCSVFormatter(),
+			table:     newTestTable(),
+			expected: `path,count
+/users/:id,120
+/search|all,5
+`,
+		},
+		{
+			name:      "markdown escapes pipes",
+			formatter: NewMarkdownFormatter(),
+			table:     newTestTable(),
+			expected: `| path | count |
+| --- | --- |
+| /users/:id | 120 |
+| /search\|all | 5 |
+`,
+		},
+		{
+			name:      "csv with no records writes header only",
+			formatter: NewCSVFormatter(),
+			table:     &Table{Columns: []string{"path", "count"}},
+			expected:  "path,count\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := tt.formatter.Format(tt.table, &buf)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONFormatter renders records as an indented JSON array
+type JSONFormatter struct{}
+
+// NewJSONFormatter creates a new JSONFormatter instance
+func NewJSONFormatter() *JSONFormatter {
+	return &JSONFormatter{}
+}
+
+// Format writes the table records as an indented JSON array
+func (f *JSONFormatter) Format(table *Table, writer io.Writer) error {
+	records := table.Records
+	if records == nil {
+		// Always emit an array, even when there is nothing to report
+		records = []Record{}
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(records)
+}
+
+// NDJSONFormatter renders records as newline delimited JSON, one object per line
+type NDJSONFormatter struct{}
+
+// NewNDJSONFormatter creates a new NDJSONFormatter instance
+func NewNDJSONFormatter() *NDJSONFormatter {
+	return &NDJSONFormatter{}
+}
+
+// Format writes each table record as a single line JSON object
+func (f *NDJSONFormatter) Format(table *Table, writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	for _, record := range table.Records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// TableFormatter renders records as an aligned plain text table for terminals
+type TableFormatter struct{}
+
+// NewTableFormatter creates a new TableFormatter instance
+func NewTableFormatter() *TableFormatter {
+	return &TableFormatter{}
+}
+
+// Format writes the table with columns aligned by tabwriter
+func (f *TableFormatter) Format(table *Table, writer io.Writer) error {
+	tw := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+
+	headers := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		headers[i] = strings.ToUpper(column)
+	}
+	if _, err := fmt.Fprintln(tw, strings.Join(headers, "\t")); err != nil {
+		return err
+	}
+
+	for _, record := range table.Records {
+		if _, err := fmt.Fprintln(tw, strings.Join(record.Values(), "\t")); err != nil {
+			return err
+		}
+	}
+
+	return tw.Flush()
+}
+
+// CSVFormatter renders records as RFC 4180 CSV with a header row
+type CSVFormatter struct{}
+
+// NewCSVFormatter creates a new CSVFormatter instance
+func NewCSVFormatter() *CSVFormatter {
+	return &CSVFormatter{}
+}
+
+// Format writes the table as CSV
+func (f *CSVFormatter) Format(table *Table, writer io.Writer) error {
+	csvWriter := csv.NewWriter(writer)
+
+	if err := csvWriter.Write(table.Columns); err != nil {
+		return err
+	}
+	for _, record := range table.Records {
+		if err := csvWriter.Write(record.Values()); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// MarkdownFormatter renders records as a GitHub flavored Markdown table
+type MarkdownFormatter struct{}
+
+// NewMarkdownFormatter creates a new MarkdownFormatter instance
+func NewMarkdownFormatter() *MarkdownFormatter {
+	return &MarkdownFormatter{}
+}
+
+// Format writes the table as Markdown
+func (f *MarkdownFormatter) Format(table *Table, writer io.Writer) error {
+	separators := make([]string, len(table.Columns))
+	for i := range separators {
+		separators[i] = "---"
+	}
+
+	if err := writeMarkdownRow(writer, table.Columns); err != nil {
+		return err
+	}
+	if err := writeMarkdownRow(writer, separators); err != nil {
+		return err
+	}
+	for _, record := range table.Records {
+		if err := writeMarkdownRow(writer, record.Values()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeMarkdownRow writes a single Markdown table row, escaping pipe characters in cells
+func writeMarkdownRow(writer io.Writer, cells []string) error {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = strings.ReplaceAll(cell, "|", `\|`)
+	}
+	_, err := fmt.Fprintf(writer, "| %s |\n", strings.Join(escaped, " | "))
+	return err
+}
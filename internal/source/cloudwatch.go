@@ -0,0 +1,73 @@
+package source
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/cloudwatch"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+// CloudWatchSource reads log events from one or more CloudWatch Logs log groups
+type CloudWatchSource struct {
+	client        *cloudwatch.Client
+	logGroupNames []string
+	parallel      *cloudwatch.ParallelOptions
+}
+
+// NewCloudWatchSource creates a new CloudWatchSource that fetches the whole time range without splitting it into slices
+func NewCloudWatchSource(client *cloudwatch.Client, logGroupNames []string) *CloudWatchSource {
+	return &CloudWatchSource{
+		client:        client,
+		logGroupNames: logGroupNames,
+	}
+}
+
+// NewParallelCloudWatchSource creates a new CloudWatchSource that fetches time slices of all log groups concurrently
+func NewParallelCloudWatchSource(client *cloudwatch.Client, logGroupNames []string, opts cloudwatch.ParallelOptions) *CloudWatchSource {
+	return &CloudWatchSource{
+		client:        client,
+		logGroupNames: logGroupNames,
+		parallel:      &opts,
+	}
+}
+
+// Stream retrieves log events page by page and sends them as LogEvent
+func (s *CloudWatchSource) Stream(ctx context.Context, startTime, endTime time.Time, events chan<- *models.LogEvent) error {
+	defer close(events)
+
+	handlePage := func(page []cloudwatch.LogGroupEvent) error {
+		for _, event := range page {
+			if err := sendFilteredEvent(ctx, events, event.LogGroupName, event.FilteredLogEvent); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Both paths merge the log groups by timestamp, so the stream clock that evicts unmatched requests never jumps back
+	if s.parallel != nil {
+		return s.client.FilterLogEventsParallel(ctx, s.logGroupNames, startTime, endTime, *s.parallel, handlePage)
+	}
+	return s.client.FilterLogEventsMerged(ctx, s.logGroupNames, startTime, endTime, handlePage)
+}
+
+// sendFilteredEvent converts a CloudWatch event to our LogEvent model and sends it
+// Events with missing fields are skipped
+func sendFilteredEvent(ctx context.Context, events chan<- *models.LogEvent, logGroupName string, event types.FilteredLogEvent) error {
+	if event.EventId == nil || event.Message == nil || event.Timestamp == nil {
+		return nil
+	}
+	logEvent := &models.LogEvent{
+		ID:        *event.EventId,
+		Message:   *event.Message,
+		Timestamp: time.UnixMilli(*event.Timestamp),
+		LogGroup:  logGroupName,
+	}
+	if event.LogStreamName != nil {
+		logEvent.LogStream = *event.LogStreamName
+	}
+	return send(ctx, events, logEvent)
+}
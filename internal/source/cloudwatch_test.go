@@ -0,0 +1,197 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/cloudwatch"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+// MockCloudWatchLogsAPI implements cloudwatch.CloudWatchLogsAPI for testing
+type MockCloudWatchLogsAPI struct {
+	mock.Mock
+}
+
+func (m *MockCloudWatchLogsAPI) FilterLogEvents(ctx context.Context, params *cloudwatchlogs.FilterLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*cloudwatchlogs.FilterLogEventsOutput), args.Error(1)
+}
+
+func (m *MockCloudWatchLogsAPI) StartQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*cloudwatchlogs.StartQueryOutput), args.Error(1)
+}
+
+func (m *MockCloudWatchLogsAPI) GetQueryResults(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*cloudwatchlogs.GetQueryResultsOutput), args.Error(1)
+}
+
+func (m *MockCloudWatchLogsAPI) DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*cloudwatchlogs.DescribeLogGroupsOutput), args.Error(1)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func TestCloudWatchSource_Fetch(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	mockAPI.On("FilterLogEvents", mock.Anything, mock.Anything).Return(&cloudwatchlogs.FilterLogEventsOutput{
+		Events: []types.FilteredLogEvent{
+			{
+				EventId:       stringPtr("event1"),
+				LogStreamName: stringPtr("web/app/task-1"),
+				Message:       stringPtr(`Started GET "/users/123" for 127.0.0.1 at 2025-07-10 17:28:13 +0900 [abc123]`),
+				Timestamp:     int64Ptr(1672531200000),
+			},
+			{
+				// Events with missing fields are skipped
+				EventId: stringPtr("event2"),
+			},
+		},
+	}, nil)
+
+	cwSource := NewCloudWatchSource(cloudwatch.NewClientWithAPI(mockAPI), []string{"test-log-group"})
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC)
+
+	events, err := Collect(context.Background(), cwSource, start, end)
+	require.NoError(t, err)
+
+	assert.Equal(t, []*models.LogEvent{
+		{
+			ID:        "event1",
+			Message:   `Started GET "/users/123" for 127.0.0.1 at 2025-07-10 17:28:13 +0900 [abc123]`,
+			Timestamp: time.UnixMilli(1672531200000),
+			LogGroup:  "test-log-group",
+			LogStream: "web/app/task-1",
+		},
+	}, events)
+	mockAPI.AssertExpectations(t)
+}
+
+func TestCloudWatchSource_FetchMultipleLogGroups(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	for _, logGroup := range []string{"app-web", "app-api"} {
+		mockAPI.On("FilterLogEvents", mock.Anything, mock.MatchedBy(func(input *cloudwatchlogs.FilterLogEventsInput) bool {
+			return *input.LogGroupName == logGroup
+		})).Return(&cloudwatchlogs.FilterLogEventsOutput{
+			Events: []types.FilteredLogEvent{
+				{
+					EventId:   stringPtr(logGroup + "-event"),
+					Message:   stringPtr("log"),
+					Timestamp: int64Ptr(1672531200000),
+				},
+			},
+		}, nil)
+	}
+
+	cwSource := NewCloudWatchSource(cloudwatch.NewClientWithAPI(mockAPI), []string{"app-web", "app-api"})
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	events, err := Collect(context.Background(), cwSource, start, start.Add(time.Hour))
+	require.NoError(t, err)
+
+	require.Len(t, events, 2)
+	assert.Equal(t, "app-web-event", events[0].ID)
+	assert.Equal(t, "app-web", events[0].LogGroup)
+	assert.Equal(t, "app-api-event", events[1].ID)
+	assert.Equal(t, "app-api", events[1].LogGroup)
+	mockAPI.AssertExpectations(t)
+}
+
+func TestCloudWatchSource_MergesLogGroupsByTimestamp(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Each log group spans the whole range, so reading them one after another would move the stream clock backwards
+	mockAPI := new(MockCloudWatchLogsAPI)
+	for offset, logGroup := range []string{"app-web", "app-api"} {
+		var groupEvents []types.FilteredLogEvent
+		for i := range 3 {
+			groupEvents = append(groupEvents, types.FilteredLogEvent{
+				EventId:   stringPtr(fmt.Sprintf("%s-%d", logGroup, i)),
+				Message:   stringPtr("log"),
+				Timestamp: int64Ptr(start.Add(time.Duration(2*i+offset) * time.Minute).UnixMilli()),
+			})
+		}
+		mockAPI.On("FilterLogEvents", mock.Anything, mock.MatchedBy(func(input *cloudwatchlogs.FilterLogEventsInput) bool {
+			return *input.LogGroupName == logGroup
+		})).Return(&cloudwatchlogs.FilterLogEventsOutput{Events: groupEvents}, nil)
+	}
+
+	cwSource := NewCloudWatchSource(cloudwatch.NewClientWithAPI(mockAPI), []string{"app-web", "app-api"})
+
+	events, err := Collect(context.Background(), cwSource, start, start.Add(time.Hour))
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	assert.Equal(t, []string{"app-web-0", "app-api-0", "app-web-1", "app-api-1", "app-web-2", "app-api-2"}, ids)
+	mockAPI.AssertExpectations(t)
+}
+
+func TestParallelCloudWatchSource_Fetch(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+
+	mockAPI := new(MockCloudWatchLogsAPI)
+	for i := 0; i < 3; i++ {
+		sliceStart := start.Add(time.Duration(i) * time.Hour).UnixMilli()
+		mockAPI.On("FilterLogEvents", mock.Anything, mock.MatchedBy(func(input *cloudwatchlogs.FilterLogEventsInput) bool {
+			return *input.StartTime == sliceStart
+		})).Return(&cloudwatchlogs.FilterLogEventsOutput{
+			Events: []types.FilteredLogEvent{
+				{
+					EventId:   stringPtr(fmt.Sprintf("event%d", i)),
+					Message:   stringPtr("log"),
+					Timestamp: int64Ptr(sliceStart),
+				},
+			},
+		}, nil)
+	}
+
+	cwSource := NewParallelCloudWatchSource(cloudwatch.NewClientWithAPI(mockAPI), []string{"test-log-group"}, cloudwatch.ParallelOptions{
+		Workers:       2,
+		SliceDuration: time.Hour,
+	})
+
+	events, err := Collect(context.Background(), cwSource, start, end)
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	assert.Equal(t, []string{"event0", "event1", "event2"}, ids)
+	mockAPI.AssertExpectations(t)
+}
+
+func TestCloudWatchSource_FetchError(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	mockAPI.On("FilterLogEvents", mock.Anything, mock.Anything).Return((*cloudwatchlogs.FilterLogEventsOutput)(nil), errors.New("access denied"))
+
+	cwSource := NewCloudWatchSource(cloudwatch.NewClientWithAPI(mockAPI), []string{"test-log-group"})
+
+	events, err := Collect(context.Background(), cwSource, time.Now().Add(-time.Hour), time.Now())
+	assert.Error(t, err)
+	assert.Equal(t, "access denied", err.Error())
+	assert.Nil(t, events)
+}
@@ -0,0 +1,118 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+// StdinPath is the path that selects standard input as a log file
+const StdinPath = "-"
+
+// maxLineSize is the maximum length of a single log line
+const maxLineSize = 1024 * 1024
+
+// gzipMagic is the header that identifies gzip compressed data
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// FileSource reads log events from plain or gzipped Rails log files and stdin
+type FileSource struct {
+	paths []string
+	stdin io.Reader
+}
+
+// NewFileSource creates a new FileSource reading the given paths, where "-" means stdin
+func NewFileSource(paths []string) *FileSource {
+	return &FileSource{
+		paths: paths,
+		stdin: os.Stdin,
+	}
+}
+
+// NewFileSourceWithStdin creates a new FileSource with a custom stdin reader
+// This is primarily used for testing
+func NewFileSourceWithStdin(paths []string, stdin io.Reader) *FileSource {
+	return &FileSource{
+		paths: paths,
+		stdin: stdin,
+	}
+}
+
+// Stream reads every line of the configured files and sends it as a LogEvent
+// Only Started lines carry a timestamp, so the time range is not applied here: the analyzer drops the requests
+// whose Started timestamp is outside it, see analyzer.Analyzer.SetWindowFilter
+func (s *FileSource) Stream(ctx context.Context, startTime, endTime time.Time, events chan<- *models.LogEvent) error {
+	defer close(events)
+
+	for _, path := range s.paths {
+		if err := s.streamPath(ctx, path, events); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// streamPath opens a single path and streams its log events
+func (s *FileSource) streamPath(ctx context.Context, path string, events chan<- *models.LogEvent) error {
+	if path == StdinPath {
+		return streamLogEvents(ctx, "stdin", s.stdin, events)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open log file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	return streamLogEvents(ctx, path, file, events)
+}
+
+// streamLogEvents reads log lines from reader, transparently decompressing gzip input
+func streamLogEvents(ctx context.Context, name string, reader io.Reader, events chan<- *models.LogEvent) error {
+	buffered := bufio.NewReader(reader)
+
+	// Detect gzip by its magic bytes so rotated logs work regardless of file name
+	if header, err := buffered.Peek(len(gzipMagic)); err == nil && bytes.Equal(header, gzipMagic) {
+		gzipReader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return fmt.Errorf("failed to decompress %s: %w", name, err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	} else {
+		reader = buffered
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		logEvent := &models.LogEvent{
+			ID:      fmt.Sprintf("%s:%d", name, lineNumber),
+			Message: line,
+		}
+		if err := send(ctx, events, logEvent); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package source
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRailsLog = `I, [2025-07-10T17:28:13.000000 #1234]  INFO -- : [abc123] Started GET "/users/123" for 127.0.0.1 at 2025-07-10 17:28:13 +0900
+I, [2025-07-10T17:28:13.100000 #1234]  INFO -- : [abc123] Processing by UsersController#show as HTML
+
+I, [2025-07-10T17:28:13.150000 #1234]  INFO -- : [abc123] Completed 200 OK in 150ms (Views: 100.0ms | ActiveRecord: 50.0ms)
+`
+
+func gzipData(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, err := writer.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestFileSource_Fetch(t *testing.T) {
+	tempDir := t.TempDir()
+
+	plainPath := filepath.Join(tempDir, "production.log")
+	require.NoError(t, os.WriteFile(plainPath, []byte(testRailsLog), 0644))
+
+	// Rotated logs are detected by content, not by file extension
+	gzipPath := filepath.Join(tempDir, "production.log.1")
+	require.NoError(t, os.WriteFile(gzipPath, gzipData(t, testRailsLog), 0644))
+
+	tests := []struct {
+		name        string
+		paths       []string
+		stdin       string
+		expectedIDs []string
+	}{
+		{
+			name:        "plain log file skips empty lines",
+			paths:       []string{plainPath},
+			expectedIDs: []string{plainPath + ":1", plainPath + ":2", plainPath + ":4"},
+		},
+		{
+			name:        "gzipped log file",
+			paths:       []string{gzipPath},
+			expectedIDs: []string{gzipPath + ":1", gzipPath + ":2", gzipPath + ":4"},
+		},
+		{
+			name:        "stdin",
+			paths:       []string{StdinPath},
+			stdin:       testRailsLog,
+			expectedIDs: []string{"stdin:1", "stdin:2", "stdin:4"},
+		},
+		{
+			name:  "multiple files are read in order",
+			paths: []string{plainPath, StdinPath},
+			stdin: "Started GET \"/\" for 127.0.0.1 at 2025-07-10 17:28:13 +0900 [x1]\n",
+			expectedIDs: []string{
+				plainPath + ":1", plainPath + ":2", plainPath + ":4", "stdin:1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileSource := NewFileSourceWithStdin(tt.paths, strings.NewReader(tt.stdin))
+
+			events, err := Collect(context.Background(), fileSource, time.Time{}, time.Time{})
+			require.NoError(t, err)
+
+			ids := make([]string, 0, len(events))
+			for _, event := range events {
+				ids = append(ids, event.ID)
+			}
+			assert.Equal(t, tt.expectedIDs, ids)
+		})
+	}
+}
+
+func TestFileSource_FetchMessages(t *testing.T) {
+	fileSource := NewFileSourceWithStdin([]string{StdinPath}, bytes.NewReader(gzipData(t, testRailsLog)))
+
+	events, err := Collect(context.Background(), fileSource, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	assert.Contains(t, events[0].Message, `Started GET "/users/123"`)
+	assert.Contains(t, events[2].Message, "Completed 200 OK in 150ms")
+	assert.True(t, events[0].Timestamp.IsZero())
+}
+
+func TestFileSource_FetchErrors(t *testing.T) {
+	t.Run("missing file", func(t *testing.T) {
+		fileSource := NewFileSource([]string{"/non/existent/production.log"})
+
+		events, err := Collect(context.Background(), fileSource, time.Time{}, time.Time{})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to open log file")
+		assert.Nil(t, events)
+	})
+
+	t.Run("corrupt gzip data", func(t *testing.T) {
+		corrupt := append([]byte{0x1f, 0x8b}, []byte("not really gzip")...)
+		fileSource := NewFileSourceWithStdin([]string{StdinPath}, bytes.NewReader(corrupt))
+
+		events, err := Collect(context.Background(), fileSource, time.Time{}, time.Time{})
+		assert.Error(t, err)
+		assert.Nil(t, events)
+	})
+}
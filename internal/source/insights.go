@@ -0,0 +1,154 @@
+package source
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/cloudwatch"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+// RailsInsightsQuery extracts the fields of Rails Started, Processing and Completed lines on the CloudWatch side
+// Only the extracted fields are returned, so far less data is transferred than with FilterLogEvents
+const RailsInsightsQuery = `fields @timestamp
+| filter @message like /Started / or @message like /Processing by / or @message like /Completed /
+| parse @message /Started (?<method>[A-Z]+) "(?<path>[^"]+)"/
+| parse @message /Processing by (?<controller>[\w:]+)#(?<action>\w+) as (?<format>\S+)/
+| parse @message /Completed (?<status>\d+) (?<status_text>.*) in (?<duration>\d+)ms/
+| parse @message /Views: (?<view>[\d.]+)ms/
+| parse @message /ActiveRecord: (?<db>[\d.]+)ms/
+| parse @message /\[(?<request_id>[a-f0-9\-]+)\]\s+(Started|Processing|Completed)/
+| parse @message /\[(?<trailing_id>[^\]]+)\]$/
+| parse @message /\[\S+ #(?<pid>\d+)\]/
+| parse @message /\[(?<tagged_pid>\d+):(?<thread>[^\]]+)\]/
+| parse @message /\[(tid[=:]\s*|TID-)(?<tid>[\w-]+)\]/
+| sort @timestamp asc
+| display @timestamp, @log, @logStream, method, path, controller, action, format, status, status_text, duration, view, db, request_id, trailing_id, pid, tagged_pid, thread, tid`
+
+// insightsTimestampLayout is the format of @timestamp in Logs Insights results
+const insightsTimestampLayout = "2006-01-02 15:04:05.000"
+
+// EntrySource provides Rails log entries that have already been parsed
+type EntrySource interface {
+	// StreamEntries sends the log entries between startTime and endTime to entries as they are read
+	// StreamEntries closes entries when it returns, whether or not an error occurred
+	StreamEntries(ctx context.Context, startTime, endTime time.Time, entries chan<- *models.LogEntry) error
+}
+
+// InsightsSource reads pre-extracted Rails log entries from log groups using Logs Insights
+type InsightsSource struct {
+	client        *cloudwatch.Client
+	logGroupNames []string
+}
+
+// NewInsightsSource creates a new InsightsSource that queries all log groups at once
+func NewInsightsSource(client *cloudwatch.Client, logGroupNames []string) *InsightsSource {
+	return &InsightsSource{
+		client:        client,
+		logGroupNames: logGroupNames,
+	}
+}
+
+// StreamEntries runs the Rails query and sends each result row as a LogEntry
+func (s *InsightsSource) StreamEntries(ctx context.Context, startTime, endTime time.Time, entries chan<- *models.LogEntry) error {
+	defer close(entries)
+
+	return s.client.InsightsQueryPages(ctx, s.logGroupNames, startTime, endTime, RailsInsightsQuery, func(rows []cloudwatch.InsightsRow) error {
+		for _, row := range rows {
+			entry := toLogEntry(row)
+			if entry == nil {
+				continue
+			}
+			select {
+			case entries <- entry:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	})
+}
+
+// toLogEntry converts a result row of RailsInsightsQuery to a LogEntry
+// Rows that are neither a Started, Processing nor Completed line return nil
+func toLogEntry(row cloudwatch.InsightsRow) *models.LogEntry {
+	timestamp, _ := time.Parse(insightsTimestampLayout, row["@timestamp"])
+
+	sessionID := row["request_id"]
+	if sessionID == "" {
+		sessionID = row["trailing_id"]
+	}
+	processID := row["pid"]
+	if processID == "" {
+		processID = row["tagged_pid"]
+	}
+	threadID := row["thread"]
+	if threadID == "" {
+		threadID = row["tid"]
+	}
+
+	// @log is "<account ID>:<log group name>"
+	logGroup := row["@log"]
+	if _, name, found := strings.Cut(logGroup, ":"); found {
+		logGroup = name
+	}
+
+	if row["method"] != "" && row["path"] != "" {
+		return &models.LogEntry{
+			Type:      "Started",
+			Method:    row["method"],
+			Path:      row["path"],
+			Timestamp: timestamp,
+			SessionID: sessionID,
+			ProcessID: processID,
+			ThreadID:  threadID,
+			LogGroup:  logGroup,
+			LogStream: row["@logStream"],
+		}
+	}
+
+	if row["controller"] != "" && row["action"] != "" {
+		return &models.LogEntry{
+			Type:       "Processing",
+			Controller: row["controller"],
+			Action:     row["action"],
+			Format:     row["format"],
+			Timestamp:  timestamp,
+			SessionID:  sessionID,
+			ProcessID:  processID,
+			ThreadID:   threadID,
+			LogGroup:   logGroup,
+			LogStream:  row["@logStream"],
+		}
+	}
+
+	statusCode, err := strconv.Atoi(row["status"])
+	if err != nil {
+		return nil
+	}
+	duration, err := strconv.Atoi(row["duration"])
+	if err != nil {
+		return nil
+	}
+
+	// View and DB durations are optional, so a missing value is left at zero
+	viewDuration, _ := strconv.ParseFloat(row["view"], 64)
+	dbDuration, _ := strconv.ParseFloat(row["db"], 64)
+
+	return &models.LogEntry{
+		Type:         "Completed",
+		Timestamp:    timestamp,
+		StatusCode:   statusCode,
+		StatusText:   row["status_text"],
+		Duration:     duration,
+		ViewDuration: viewDuration,
+		DBDuration:   dbDuration,
+		SessionID:    sessionID,
+		ProcessID:    processID,
+		ThreadID:     threadID,
+		LogGroup:     logGroup,
+		LogStream:    row["@logStream"],
+	}
+}
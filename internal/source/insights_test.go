@@ -0,0 +1,137 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/cloudwatch"
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+func resultRow(fields map[string]string) []types.ResultField {
+	row := make([]types.ResultField, 0, len(fields))
+	for name, value := range fields {
+		row = append(row, types.ResultField{Field: stringPtr(name), Value: stringPtr(value)})
+	}
+	return row
+}
+
+func collectEntries(ctx context.Context, entrySource EntrySource, start, end time.Time) ([]*models.LogEntry, error) {
+	entries := make(chan *models.LogEntry)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- entrySource.StreamEntries(ctx, start, end, entries)
+	}()
+
+	var logEntries []*models.LogEntry
+	for entry := range entries {
+		logEntries = append(logEntries, entry)
+	}
+	return logEntries, <-errCh
+}
+
+func TestInsightsSource_StreamEntries(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	mockAPI.On("StartQuery", mock.Anything, mock.MatchedBy(func(input *cloudwatchlogs.StartQueryInput) bool {
+		return assert.ObjectsAreEqual([]string{"test-log-group"}, input.LogGroupNames) && *input.QueryString == RailsInsightsQuery
+	})).Return(&cloudwatchlogs.StartQueryOutput{QueryId: stringPtr("query1")}, nil)
+	mockAPI.On("GetQueryResults", mock.Anything, mock.Anything).Return(&cloudwatchlogs.GetQueryResultsOutput{
+		Status: types.QueryStatusComplete,
+		Results: [][]types.ResultField{
+			resultRow(map[string]string{
+				"@timestamp": "2023-01-01 00:00:01.000",
+				"@log":       "123456789012:test-log-group",
+				"@logStream": "web/app/task-1",
+				"method":     "GET",
+				"path":       "/users/123",
+				"request_id": "abc-123",
+				"pid":        "4321",
+			}),
+			resultRow(map[string]string{
+				"@timestamp": "2023-01-01 00:00:01.500",
+				"controller": "Api::UsersController",
+				"action":     "show",
+				"format":     "JSON",
+				"request_id": "abc-123",
+				"tagged_pid": "4321",
+				"thread":     "puma srv tp 001",
+			}),
+			resultRow(map[string]string{
+				"@timestamp":  "2023-01-01 00:00:02.000",
+				"status":      "200",
+				"status_text": "OK",
+				"duration":    "45",
+				"view":        "12.5",
+				"db":          "3.2",
+				"trailing_id": "abc-123",
+				"tid":         "70123",
+			}),
+			// Rows that match neither line type are skipped
+			resultRow(map[string]string{
+				"@timestamp": "2023-01-01 00:00:03.000",
+			}),
+		},
+	}, nil)
+
+	insightsSource := NewInsightsSource(cloudwatch.NewClientWithAPI(mockAPI), []string{"test-log-group"})
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	entries, err := collectEntries(context.Background(), insightsSource, start, end)
+	require.NoError(t, err)
+
+	assert.Equal(t, []*models.LogEntry{
+		{
+			Type:      "Started",
+			Method:    "GET",
+			Path:      "/users/123",
+			Timestamp: time.Date(2023, 1, 1, 0, 0, 1, 0, time.UTC),
+			SessionID: "abc-123",
+			ProcessID: "4321",
+			LogGroup:  "test-log-group",
+			LogStream: "web/app/task-1",
+		},
+		{
+			Type:       "Processing",
+			Controller: "Api::UsersController",
+			Action:     "show",
+			Format:     "JSON",
+			Timestamp:  time.Date(2023, 1, 1, 0, 0, 1, 500000000, time.UTC),
+			SessionID:  "abc-123",
+			ProcessID:  "4321",
+			ThreadID:   "puma srv tp 001",
+		},
+		{
+			Type:         "Completed",
+			Timestamp:    time.Date(2023, 1, 1, 0, 0, 2, 0, time.UTC),
+			StatusCode:   200,
+			StatusText:   "OK",
+			Duration:     45,
+			ViewDuration: 12.5,
+			DBDuration:   3.2,
+			SessionID:    "abc-123",
+			ThreadID:     "70123",
+		},
+	}, entries)
+	mockAPI.AssertExpectations(t)
+}
+
+func TestInsightsSource_StreamEntriesError(t *testing.T) {
+	mockAPI := new(MockCloudWatchLogsAPI)
+	mockAPI.On("StartQuery", mock.Anything, mock.Anything).Return((*cloudwatchlogs.StartQueryOutput)(nil), errors.New("access denied"))
+
+	insightsSource := NewInsightsSource(cloudwatch.NewClientWithAPI(mockAPI), []string{"test-log-group"})
+
+	entries, err := collectEntries(context.Background(), insightsSource, time.Now().Add(-time.Hour), time.Now())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "access denied")
+	assert.Empty(t, entries)
+}
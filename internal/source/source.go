@@ -0,0 +1,46 @@
+package source
+
+import (
+	"context"
+	"time"
+
+	"github.com/kgrsutos/cw-railspathmetrics/internal/models"
+)
+
+// Source provides Rails log events to be analyzed
+type Source interface {
+	// Stream sends the log events between startTime and endTime to events as they are read
+	// Stream closes events when it returns, whether or not an error occurred
+	Stream(ctx context.Context, startTime, endTime time.Time, events chan<- *models.LogEvent) error
+}
+
+// Collect reads every log event of the source into a slice
+// This is convenient for small inputs and tests; large inputs should be consumed via Stream
+func Collect(ctx context.Context, source Source, startTime, endTime time.Time) ([]*models.LogEvent, error) {
+	events := make(chan *models.LogEvent)
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- source.Stream(ctx, startTime, endTime, events)
+	}()
+
+	var logEvents []*models.LogEvent
+	for event := range events {
+		logEvents = append(logEvents, event)
+	}
+
+	if err := <-errCh; err != nil {
+		return nil, err
+	}
+	return logEvents, nil
+}
+
+// send delivers a single event unless the context is cancelled first
+func send(ctx context.Context, events chan<- *models.LogEvent, event *models.LogEvent) error {
+	select {
+	case events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,114 @@
+package timeutil
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// localLayouts are the accepted layouts for times without an offset, interpreted in the given location
+var localLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+var (
+	// relativeTimeRegex matches expressions relative to now such as "now", "now-1d" or "now+30m"
+	relativeTimeRegex = regexp.MustCompile(`^now(?:([+-])(.+))?$`)
+	// durationPartRegex matches a single number and unit of a duration such as "1d" or "1.5h"
+	durationPartRegex = regexp.MustCompile(`(\d+(?:\.\d+)?)([a-zµ]+)`)
+)
+
+// LoadLocation returns the location for an IANA time zone name
+// An empty name or "Local" returns the local time zone
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load time zone %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// ParseTime parses an absolute or relative time expression
+// Supported forms are RFC3339 with an offset, local layouts such as 2006-01-02T15:04:05 interpreted in loc,
+// and "now" optionally followed by a signed duration such as "now-1d"
+func ParseTime(value string, now time.Time, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if matches := relativeTimeRegex.FindStringSubmatch(value); matches != nil {
+		if matches[1] == "" {
+			return now, nil
+		}
+		offset, err := ParseDuration(matches[2])
+		if err != nil {
+			return time.Time{}, err
+		}
+		if matches[1] == "-" {
+			offset = -offset
+		}
+		return now.Add(offset), nil
+	}
+
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+
+	for _, layout := range localLayouts {
+		if parsed, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return parsed, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized time %q (use RFC3339, 2006-01-02T15:04:05 or now-<duration>)", value)
+}
+
+// ParseDuration parses a duration like time.ParseDuration, additionally accepting d (days) and w (weeks)
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "0" {
+		return 0, nil
+	}
+
+	parts := durationPartRegex.FindAllStringSubmatchIndex(value, -1)
+	if len(parts) == 0 || parts[0][0] != 0 || parts[len(parts)-1][1] != len(value) {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	var total time.Duration
+	for i, part := range parts {
+		// Parts must be adjacent, so nothing but numbers and units is accepted
+		if i > 0 && part[0] != parts[i-1][1] {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+
+		number, unit := value[part[2]:part[3]], value[part[4]:part[5]]
+		switch unit {
+		case "d", "w":
+			amount, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q: %w", value, err)
+			}
+			day := 24 * time.Hour
+			if unit == "w" {
+				day *= 7
+			}
+			total += time.Duration(amount * float64(day))
+		default:
+			duration, err := time.ParseDuration(number + unit)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q: %w", value, err)
+			}
+			total += duration
+		}
+	}
+
+	return total, nil
+}
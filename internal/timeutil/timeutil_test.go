@@ -0,0 +1,167 @@
+package timeutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadLocation(t *testing.T) {
+	loc, err := LoadLocation("")
+	require.NoError(t, err)
+	assert.Equal(t, time.Local, loc)
+
+	loc, err = LoadLocation("Local")
+	require.NoError(t, err)
+	assert.Equal(t, time.Local, loc)
+
+	loc, err = LoadLocation("America/New_York")
+	require.NoError(t, err)
+	assert.Equal(t, "America/New_York", loc.String())
+
+	_, err = LoadLocation("Mars/Olympus_Mons")
+	assert.ErrorContains(t, err, `failed to load time zone "Mars/Olympus_Mons"`)
+}
+
+func TestParseTime(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	now := time.Date(2023, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		input    string
+		loc      *time.Location
+		expected time.Time
+		hasError bool
+	}{
+		{
+			name:     "local layout in Tokyo",
+			input:    "2023-01-01T12:00:00",
+			loc:      tokyo,
+			expected: time.Date(2023, 1, 1, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "local layout in UTC",
+			input:    "2023-01-01T12:00:00",
+			loc:      time.UTC,
+			expected: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "space separated layout",
+			input:    "2023-01-01 12:00:00",
+			loc:      time.UTC,
+			expected: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "minutes only",
+			input:    "2023-01-01T12:30",
+			loc:      time.UTC,
+			expected: time.Date(2023, 1, 1, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "date only",
+			input:    "2023-01-01",
+			loc:      tokyo,
+			expected: time.Date(2022, 12, 31, 15, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "RFC3339 offset overrides location",
+			input:    "2023-01-01T12:00:00-05:00",
+			loc:      tokyo,
+			expected: time.Date(2023, 1, 1, 17, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "RFC3339 UTC",
+			input:    "2023-01-01T12:00:00Z",
+			loc:      tokyo,
+			expected: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "now",
+			input:    "now",
+			loc:      tokyo,
+			expected: now,
+		},
+		{
+			name:     "now minus days",
+			input:    "now-1d",
+			loc:      tokyo,
+			expected: now.Add(-24 * time.Hour),
+		},
+		{
+			name:     "now plus minutes",
+			input:    "now+30m",
+			loc:      tokyo,
+			expected: now.Add(30 * time.Minute),
+		},
+		{
+			name:     "invalid relative duration",
+			input:    "now-1x",
+			loc:      tokyo,
+			hasError: true,
+		},
+		{
+			name:     "invalid date",
+			input:    "2023-13-01T12:00:00",
+			loc:      tokyo,
+			hasError: true,
+		},
+		{
+			name:     "unrecognized format",
+			input:    "01/02/2023",
+			loc:      tokyo,
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParseTime(tt.input, now, tt.loc)
+
+			if tt.hasError {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected.UTC(), parsed.UTC())
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		hasError bool
+	}{
+		{input: "0", expected: 0},
+		{input: "30m", expected: 30 * time.Minute},
+		{input: "2h", expected: 2 * time.Hour},
+		{input: "1d", expected: 24 * time.Hour},
+		{input: "1w", expected: 7 * 24 * time.Hour},
+		{input: "1d12h", expected: 36 * time.Hour},
+		{input: "1.5d", expected: 36 * time.Hour},
+		{input: "1h30m15s", expected: time.Hour + 30*time.Minute + 15*time.Second},
+		{input: "", hasError: true},
+		{input: "d", hasError: true},
+		{input: "-1d", hasError: true},
+		{input: "1d 2h", hasError: true},
+		{input: "1y", hasError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			duration, err := ParseDuration(tt.input)
+
+			if tt.hasError {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, duration)
+			}
+		})
+	}
+}